@@ -0,0 +1,47 @@
+// Package atomicfile replaces files so readers never see a partial write.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// TempPattern matches the temporary files Write creates next to its target.
+// A crash between creating and renaming one can leave it behind.
+const TempPattern = ".patchline-*"
+
+// Write replaces path with data through a temporary file in the same
+// directory. An existing file keeps its mode; a new file gets perm.
+func Write(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), TempPattern)
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteKeepsModeAndLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "opencode.json")
+
+	if err := Write(path, []byte("{}\n"), 0o600); err != nil {
+		t.Fatalf("write new: %v", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if err := Write(path, []byte(`{"plugin": []}`), 0o600); err != nil {
+		t.Fatalf("write existing: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(data) != `{"plugin": []}` {
+		t.Fatalf("unexpected content %s", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o644 {
+		t.Fatalf("expected the existing mode to be kept, got %v", info.Mode().Perm())
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, TempPattern))
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("expected no temporary files, got %v %v", matches, err)
+	}
+}
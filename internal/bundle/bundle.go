@@ -0,0 +1,128 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the bundle format this build reads and writes.
+const FormatVersion = 1
+
+// Scopes a plugin declaration or local file is imported into.
+const (
+	ScopeProject = "project"
+	ScopeGlobal  = "global"
+)
+
+// Bundle is a portable description of a plugin environment.
+type Bundle struct {
+	BundleVersion int        `json:"bundleVersion"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	Plugins       []Plugin   `json:"plugins"`
+	LocalFiles    []File     `json:"localFiles,omitempty"`
+	Snapshots     []Snapshot `json:"snapshots,omitempty"`
+}
+
+// Plugin is one declared npm plugin.
+type Plugin struct {
+	Name      string `json:"name"`
+	Spec      string `json:"spec"`
+	Scope     string `json:"scope"`
+	Source    string `json:"source"`
+	Installed string `json:"installed,omitempty"`
+}
+
+// File is a local plugin file. Content is stored base64 encoded.
+type File struct {
+	Name    string `json:"name"`
+	Scope   string `json:"scope"`
+	Content []byte `json:"content"`
+}
+
+// Snapshot summarizes a named snapshot on the exporting machine.
+type Snapshot struct {
+	Name      string            `json:"name"`
+	Timestamp time.Time         `json:"timestamp"`
+	Specs     map[string]string `json:"specs"`
+}
+
+// Load reads and validates a bundle.
+func Load(path string) (Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Bundle{}, fmt.Errorf("%w: %s", ErrBundleNotFound, path)
+		}
+		return Bundle{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Bundle{}, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, path, err)
+	}
+	if b.BundleVersion > FormatVersion {
+		return Bundle{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, b.BundleVersion)
+	}
+	if err := b.validate(); err != nil {
+		return Bundle{}, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, path, err)
+	}
+	return b, nil
+}
+
+// Encode returns the bundle as indented JSON with a stable order.
+func Encode(b Bundle) ([]byte, error) {
+	b.BundleVersion = FormatVersion
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now().UTC()
+	}
+	sort.SliceStable(b.Plugins, func(i, j int) bool {
+		if b.Plugins[i].Scope != b.Plugins[j].Scope {
+			return b.Plugins[i].Scope < b.Plugins[j].Scope
+		}
+		return b.Plugins[i].Name < b.Plugins[j].Name
+	})
+	sort.SliceStable(b.LocalFiles, func(i, j int) bool {
+		if b.LocalFiles[i].Scope != b.LocalFiles[j].Scope {
+			return b.LocalFiles[i].Scope < b.LocalFiles[j].Scope
+		}
+		return b.LocalFiles[i].Name < b.LocalFiles[j].Name
+	})
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal bundle: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func (b Bundle) validate() error {
+	for _, plugin := range b.Plugins {
+		if plugin.Name == "" || plugin.Spec == "" {
+			return fmt.Errorf("plugin entries need a name and spec")
+		}
+		if err := validScope(plugin.Scope); err != nil {
+			return fmt.Errorf("plugin %s: %w", plugin.Name, err)
+		}
+	}
+	for _, file := range b.LocalFiles {
+		if err := validScope(file.Scope); err != nil {
+			return fmt.Errorf("local file %s: %w", file.Name, err)
+		}
+		if file.Name == "" || file.Name != filepath.Base(file.Name) || strings.ContainsAny(file.Name, `/\`) || strings.HasPrefix(file.Name, ".") {
+			return fmt.Errorf("invalid local file name %q", file.Name)
+		}
+	}
+	return nil
+}
+
+func validScope(scope string) error {
+	if scope != ScopeProject && scope != ScopeGlobal {
+		return fmt.Errorf("unknown scope %q", scope)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package bundle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeAndLoad(t *testing.T) {
+	b := Bundle{
+		Plugins: []Plugin{
+			{Name: "beta", Spec: "beta@2.0.0", Scope: ScopeProject, Source: "project", Installed: "2.0.0"},
+			{Name: "alpha", Spec: "alpha", Scope: ScopeGlobal, Source: "global"},
+		},
+		LocalFiles: []File{{Name: "hook.js", Scope: ScopeProject, Content: []byte("export default {}\n")}},
+	}
+	data, err := Encode(b)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.BundleVersion != FormatVersion || loaded.CreatedAt.IsZero() {
+		t.Fatalf("expected version and timestamp, got %#v", loaded)
+	}
+	if loaded.Plugins[0].Name != "alpha" || loaded.Plugins[1].Name != "beta" {
+		t.Fatalf("expected plugins sorted by scope, got %#v", loaded.Plugins)
+	}
+	if string(loaded.LocalFiles[0].Content) != "export default {}\n" {
+		t.Fatalf("unexpected local file content %q", string(loaded.LocalFiles[0].Content))
+	}
+}
+
+func TestLoadRejectsInvalidBundles(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"version": `{"bundleVersion": 99, "plugins": []}`,
+		"scope":   `{"bundleVersion": 1, "plugins": [{"name": "alpha", "spec": "alpha", "scope": "team"}]}`,
+		"path":    `{"bundleVersion": 1, "plugins": [], "localFiles": [{"name": "../evil.js", "scope": "project", "content": ""}]}`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		_, err := Load(path)
+		if name == "version" {
+			if !errors.Is(err, ErrUnsupportedVersion) {
+				t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidBundle) {
+			t.Fatalf("%s: expected ErrInvalidBundle, got %v", name, err)
+		}
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.json")); !errors.Is(err, ErrBundleNotFound) {
+		t.Fatalf("expected ErrBundleNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package bundle
+
+import "errors"
+
+var (
+	// ErrBundleNotFound indicates the bundle file does not exist.
+	ErrBundleNotFound = errors.New("bundle not found")
+	// ErrUnsupportedVersion indicates the bundle was written by a newer format.
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+	// ErrInvalidBundle indicates the bundle could not be parsed.
+	ErrInvalidBundle = errors.New("invalid bundle")
+)
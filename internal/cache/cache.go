@@ -6,18 +6,33 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// Entry is a package found in the plugin cache. Issues lists the reasons the
+// entry looks corrupt or partially installed; it is empty for healthy entries.
 type Entry struct {
-	Name    string
-	Version string
-	Path    string
+	Name         string
+	Version      string
+	Path         string
+	Dependencies []string
+	Issues       []string
+}
+
+// Corrupt reports whether the entry failed any health check.
+func (e Entry) Corrupt() bool {
+	return len(e.Issues) > 0
 }
 
 type packageJSON struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Main                 string            `json:"main"`
+	Module               string            `json:"module"`
+	Exports              json.RawMessage   `json:"exports"`
+	Dependencies         map[string]string `json:"dependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
 }
 
 func Detect(ctx context.Context, cacheDir string) ([]Entry, error) {
@@ -31,6 +46,7 @@ func Detect(ctx context.Context, cacheDir string) ([]Entry, error) {
 	}
 
 	results := make([]Entry, 0, len(entries))
+	hidden := hiddenNames(entries)
 	for _, entry := range entries {
 		if err := ctx.Err(); err != nil {
 			return results, err
@@ -38,7 +54,7 @@ func Detect(ctx context.Context, cacheDir string) ([]Entry, error) {
 		if !entry.IsDir() {
 			continue
 		}
-		if entry.Name() == ".bin" {
+		if strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
@@ -48,55 +64,81 @@ func Detect(ctx context.Context, cacheDir string) ([]Entry, error) {
 			if err != nil {
 				continue
 			}
+			scopedHidden := hiddenNames(scopedEntries)
 			for _, scoped := range scopedEntries {
 				if err := ctx.Err(); err != nil {
 					return results, err
 				}
-				if !scoped.IsDir() {
+				if !scoped.IsDir() || strings.HasPrefix(scoped.Name(), ".") {
 					continue
 				}
-				pkg, ok := readPackageJSON(filepath.Join(entryPath, scoped.Name()))
-				if !ok {
-					continue
-				}
-				results = append(results, Entry{
-					Name:    pkg.Name,
-					Version: pkg.Version,
-					Path:    filepath.Join(entryPath, scoped.Name()),
-				})
+				dirName := entry.Name() + "/" + scoped.Name()
+				results = append(results, loadEntry(cacheDir, filepath.Join(entryPath, scoped.Name()), dirName, scopedHidden))
 			}
 			continue
 		}
 
-		pkg, ok := readPackageJSON(entryPath)
-		if !ok {
-			continue
-		}
-		results = append(results, Entry{
-			Name:    pkg.Name,
-			Version: pkg.Version,
-			Path:    entryPath,
-		})
+		results = append(results, loadEntry(cacheDir, entryPath, entry.Name(), hidden))
 	}
 
 	return results, nil
 }
 
-func readPackageJSON(dir string) (packageJSON, bool) {
+// loadEntry reads the package manifest in dir and runs health checks. The
+// directory name stands in for the package name when the manifest is unusable.
+func loadEntry(cacheDir string, dir string, dirName string, hiddenSiblings []string) Entry {
+	entry := Entry{Name: dirName, Path: dir}
+	entry.Issues = append(entry.Issues, leftoverTempFiles(dir, hiddenSiblings)...)
+
+	pkg, err := readPackageJSON(dir)
+	if err != nil {
+		entry.Issues = append(entry.Issues, err.Error())
+		return entry
+	}
+	if pkg.Name != "" {
+		entry.Name = pkg.Name
+	}
+	entry.Version = pkg.Version
+	entry.Dependencies = pkg.dependencyNames()
+	if pkg.Name == "" {
+		entry.Issues = append(entry.Issues, "package.json missing name")
+	}
+	if pkg.Version == "" {
+		entry.Issues = append(entry.Issues, "package.json missing version")
+	}
+	entry.Issues = append(entry.Issues, missingEntryPoints(dir, pkg)...)
+	entry.Issues = append(entry.Issues, missingDependencies(cacheDir, dir, pkg)...)
+	return entry
+}
+
+func readPackageJSON(dir string) (packageJSON, error) {
 	packagePath := filepath.Join(dir, "package.json")
 	data, err := os.ReadFile(packagePath)
 	if err != nil {
-		return packageJSON{}, false
+		if os.IsNotExist(err) {
+			return packageJSON{}, fmt.Errorf("missing package.json")
+		}
+		return packageJSON{}, fmt.Errorf("unreadable package.json: %v", err)
 	}
 
 	var pkg packageJSON
 	if err := json.Unmarshal(data, &pkg); err != nil {
-		return packageJSON{}, false
+		return packageJSON{}, fmt.Errorf("invalid package.json: %v", err)
+	}
+	return pkg, nil
+}
+
+func (p packageJSON) dependencyNames() []string {
+	names := make([]string, 0, len(p.Dependencies)+len(p.OptionalDependencies))
+	for name := range p.Dependencies {
+		names = append(names, name)
 	}
-	if pkg.Name == "" || pkg.Version == "" {
-		return packageJSON{}, false
+	for name := range p.OptionalDependencies {
+		names = append(names, name)
 	}
-	return pkg, true
+	names = uniqueStrings(names)
+	sort.Strings(names)
+	return names
 }
 
 // Invalidate removes cached plugin directories that match the npm package name.
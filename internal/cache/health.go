@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var tempFileSuffixes = []string{".tmp", ".partial", ".part", ".download"}
+
+var entryPointExtensions = []string{"", ".js", ".cjs", ".mjs", ".json", ".ts"}
+
+func hiddenNames(entries []os.DirEntry) []string {
+	names := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// leftoverTempFiles reports files an interrupted install leaves behind: npm
+// stages packages in hidden ".<name>-<random>" siblings, and downloads can
+// leave partial files inside the package itself.
+func leftoverTempFiles(dir string, hiddenSiblings []string) []string {
+	issues := []string{}
+	prefix := "." + filepath.Base(dir) + "-"
+	for _, sibling := range hiddenSiblings {
+		if strings.HasPrefix(sibling, prefix) {
+			issues = append(issues, fmt.Sprintf("leftover temp directory %s", sibling))
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return issues
+	}
+	for _, entry := range entries {
+		if isTempName(entry.Name()) {
+			issues = append(issues, fmt.Sprintf("leftover temp file %s", entry.Name()))
+		}
+	}
+	return issues
+}
+
+func isTempName(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, ".staging") {
+		return true
+	}
+	for _, suffix := range tempFileSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func missingEntryPoints(dir string, pkg packageJSON) []string {
+	targets := []string{}
+	if pkg.Main != "" {
+		targets = append(targets, pkg.Main)
+	}
+	if pkg.Module != "" {
+		targets = append(targets, pkg.Module)
+	}
+	targets = append(targets, exportTargets(pkg.Exports)...)
+
+	issues := []string{}
+	for _, target := range uniqueStrings(targets) {
+		if strings.Contains(target, "*") {
+			continue
+		}
+		if !entryPointExists(dir, target) {
+			issues = append(issues, fmt.Sprintf("missing entry point %s", target))
+		}
+	}
+	return issues
+}
+
+// exportTargets collects the file paths referenced by an "exports" field,
+// whether it is a string, a conditions object, or a subpath map.
+func exportTargets(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil
+	}
+
+	targets := []string{}
+	var walk func(any)
+	walk = func(node any) {
+		switch typed := node.(type) {
+		case string:
+			targets = append(targets, typed)
+		case []any:
+			for _, item := range typed {
+				walk(item)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(typed[key])
+			}
+		}
+	}
+	walk(value)
+	return targets
+}
+
+func entryPointExists(dir string, target string) bool {
+	base := filepath.Join(dir, filepath.FromSlash(target))
+	if ensureWithin(dir, base) != nil {
+		return false
+	}
+	for _, ext := range entryPointExtensions {
+		if fileExists(base + ext) {
+			return true
+		}
+	}
+	return fileExists(filepath.Join(base, "index.js"))
+}
+
+// missingDependencies checks that every required dependency is installed
+// either nested under the package or hoisted into the cache root.
+func missingDependencies(cacheDir string, dir string, pkg packageJSON) []string {
+	names := make([]string, 0, len(pkg.Dependencies))
+	for name := range pkg.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	issues := []string{}
+	for _, name := range names {
+		nested := filepath.Join(dir, "node_modules", filepath.FromSlash(name))
+		hoisted := filepath.Join(cacheDir, filepath.FromSlash(name))
+		if dirExists(nested) || dirExists(hoisted) {
+			continue
+		}
+		issues = append(issues, fmt.Sprintf("missing dependency %s", name))
+	}
+	return issues
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectFlagsCorruptEntries(t *testing.T) {
+	cacheDir := t.TempDir()
+
+	healthy := filepath.Join(cacheDir, "healthy")
+	writePackageJSON(t, healthy, `{"name":"healthy","version":"1.0.0","main":"lib/index","exports":{".":{"import":"./esm/index.mjs"}},"dependencies":{"dep":"^1.0.0"}}`)
+	writeFile(t, filepath.Join(healthy, "lib", "index.js"), "module.exports = {}")
+	writeFile(t, filepath.Join(healthy, "esm", "index.mjs"), "export default {}")
+	writePackageJSON(t, filepath.Join(cacheDir, "dep"), `{"name":"dep","version":"1.0.0"}`)
+
+	writePackageJSON(t, filepath.Join(cacheDir, "broken"), `{"name":`)
+	writePackageJSON(t, filepath.Join(cacheDir, "noentry"), `{"name":"noentry","version":"1.0.0","main":"dist/index.js"}`)
+	writePackageJSON(t, filepath.Join(cacheDir, "nodeps"), `{"name":"nodeps","version":"1.0.0","dependencies":{"gone":"1.0.0"}}`)
+	writePackageJSON(t, filepath.Join(cacheDir, "staged"), `{"name":"staged","version":"1.0.0"}`)
+	if err := os.MkdirAll(filepath.Join(cacheDir, ".staged-a1b2c3"), 0o755); err != nil {
+		t.Fatalf("mkdir temp: %v", err)
+	}
+	writeFile(t, filepath.Join(cacheDir, "@scope", "partial", "index.js.tmp"), "")
+	writePackageJSON(t, filepath.Join(cacheDir, "@scope", "partial"), `{"name":"@scope/partial","version":"1.0.0"}`)
+
+	entries, err := Detect(context.Background(), cacheDir)
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+
+	byName := map[string]Entry{}
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+	if len(byName) != 7 {
+		t.Fatalf("expected 7 entries, got %#v", byName)
+	}
+	if byName["healthy"].Corrupt() || byName["dep"].Corrupt() {
+		t.Fatalf("expected healthy entries, got %#v / %#v", byName["healthy"].Issues, byName["dep"].Issues)
+	}
+
+	cases := map[string]string{
+		"broken":         "invalid package.json",
+		"noentry":        "missing entry point dist/index.js",
+		"nodeps":         "missing dependency gone",
+		"staged":         "leftover temp directory .staged-a1b2c3",
+		"@scope/partial": "leftover temp file index.js.tmp",
+	}
+	for name, want := range cases {
+		entry, ok := byName[name]
+		if !ok {
+			t.Fatalf("expected entry %s", name)
+		}
+		if !strings.Contains(strings.Join(entry.Issues, "; "), want) {
+			t.Fatalf("expected %s issue %q, got %#v", name, want, entry.Issues)
+		}
+	}
+}
+
+func TestInvalidateRemovesCorruptEntry(t *testing.T) {
+	cacheDir := t.TempDir()
+	brokenDir := filepath.Join(cacheDir, "broken")
+	writePackageJSON(t, brokenDir, `not json`)
+
+	removed, err := Invalidate(context.Background(), cacheDir, "broken")
+	if err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Fatalf("expected corrupt entry removed, got %#v", removed)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Usage describes the on-disk footprint of a cache entry.
+type Usage struct {
+	Entry
+	Size     int64
+	Modified time.Time
+}
+
+// Inventory detects cache entries and measures their size and last-modified time.
+func Inventory(ctx context.Context, cacheDir string) ([]Usage, error) {
+	entries, err := Detect(ctx, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Usage, 0, len(entries))
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		size, modified, err := measureDir(entry.Path)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, Usage{
+			Entry:    entry,
+			Size:     size,
+			Modified: modified,
+		})
+	}
+	return results, nil
+}
+
+// Dependents maps each cache entry name to the root packages whose dependency
+// closure includes it. Roots list themselves as dependents.
+func Dependents(entries []Entry, roots []string) map[string][]string {
+	byName := make(map[string]Entry, len(entries))
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+
+	dependents := map[string][]string{}
+	for _, root := range uniqueStrings(roots) {
+		for _, name := range Closure(byName, root) {
+			dependents[name] = append(dependents[name], root)
+		}
+	}
+	for name := range dependents {
+		sort.Strings(dependents[name])
+	}
+	return dependents
+}
+
+// Closure returns the root and every cached package reachable through its
+// dependencies. Dependencies that are not in the cache are skipped.
+func Closure(byName map[string]Entry, root string) []string {
+	if _, ok := byName[root]; !ok {
+		return nil
+	}
+
+	seen := map[string]struct{}{root: {}}
+	queue := []string{root}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range byName[current].Dependencies {
+			if _, ok := seen[dep]; ok {
+				continue
+			}
+			if _, ok := byName[dep]; !ok {
+				continue
+			}
+			seen[dep] = struct{}{}
+			queue = append(queue, dep)
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func measureDir(root string) (int64, time.Time, error) {
+	var size int64
+	var modified time.Time
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return size, modified, nil
+}
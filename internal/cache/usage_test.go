@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInventoryMeasuresEntries(t *testing.T) {
+	cacheDir := t.TempDir()
+	alphaDir := filepath.Join(cacheDir, "alpha")
+	writePackageJSON(t, alphaDir, `{"name":"alpha","version":"1.0.0","dependencies":{"beta":"^2.0.0"}}`)
+	if err := os.WriteFile(filepath.Join(alphaDir, "index.js"), []byte("0123456789"), 0o600); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), `{"name":"beta","version":"2.0.0"}`)
+
+	usage, err := Inventory(context.Background(), cacheDir)
+	if err != nil {
+		t.Fatalf("inventory: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(usage))
+	}
+
+	byName := map[string]Usage{}
+	for _, item := range usage {
+		byName[item.Name] = item
+	}
+	alpha := byName["alpha"]
+	pkgInfo, err := os.Stat(filepath.Join(alphaDir, "package.json"))
+	if err != nil {
+		t.Fatalf("stat package.json: %v", err)
+	}
+	if want := pkgInfo.Size() + 10; alpha.Size != want {
+		t.Fatalf("expected alpha size %d, got %d", want, alpha.Size)
+	}
+	if alpha.Modified.IsZero() {
+		t.Fatalf("expected alpha modified time")
+	}
+	if !reflect.DeepEqual(alpha.Dependencies, []string{"beta"}) {
+		t.Fatalf("expected alpha dependencies, got %#v", alpha.Dependencies)
+	}
+}
+
+func TestDependentsFollowsTransitiveDependencies(t *testing.T) {
+	entries := []Entry{
+		{Name: "alpha", Dependencies: []string{"shared"}},
+		{Name: "beta", Dependencies: []string{"shared", "absent"}},
+		{Name: "shared", Dependencies: []string{"leaf"}},
+		{Name: "leaf"},
+		{Name: "orphan"},
+	}
+
+	dependents := Dependents(entries, []string{"alpha", "beta"})
+	if got := dependents["leaf"]; !reflect.DeepEqual(got, []string{"alpha", "beta"}) {
+		t.Fatalf("expected leaf dependents alpha,beta, got %#v", got)
+	}
+	if got := dependents["alpha"]; !reflect.DeepEqual(got, []string{"alpha"}) {
+		t.Fatalf("expected alpha to depend on itself, got %#v", got)
+	}
+	if _, ok := dependents["orphan"]; ok {
+		t.Fatalf("expected orphan to have no dependents")
+	}
+	if _, ok := dependents["absent"]; ok {
+		t.Fatalf("expected uncached dependency to be skipped")
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileDiff describes how a cache directory differs from a published package.
+type FileDiff struct {
+	Modified []string `json:"modified"`
+	Added    []string `json:"added"`
+	Missing  []string `json:"missing"`
+}
+
+// Clean reports whether the directory matches the published files exactly.
+func (d FileDiff) Clean() bool {
+	return len(d.Modified) == 0 && len(d.Added) == 0 && len(d.Missing) == 0
+}
+
+// FileHashes maps every regular file under dir to its sha256 hex digest, using
+// slash-separated relative paths. Nested node_modules are skipped because they
+// hold installed dependencies rather than package contents.
+func FileHashes(dir string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		digest, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = digest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// CompareFiles compares published file digests with the contents of dir.
+// When manifest holds the published package.json, it is compared after
+// dropping the "_"-prefixed bookkeeping fields some package managers add.
+func CompareFiles(expected map[string]string, manifest []byte, dir string) (FileDiff, error) {
+	actual, err := FileHashes(dir)
+	if err != nil {
+		return FileDiff{}, err
+	}
+
+	diff := FileDiff{}
+	for name, digest := range expected {
+		got, ok := actual[name]
+		if !ok {
+			diff.Missing = append(diff.Missing, name)
+			continue
+		}
+		if got == digest {
+			continue
+		}
+		if name == "package.json" && manifestMatches(filepath.Join(dir, "package.json"), manifest) {
+			continue
+		}
+		diff.Modified = append(diff.Modified, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			diff.Added = append(diff.Added, name)
+		}
+	}
+
+	sort.Strings(diff.Modified)
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Missing)
+	return diff, nil
+}
+
+// Quarantine moves a cache entry out of the cache into destDir so OpenCode
+// reinstalls it, and returns the new location.
+func Quarantine(ctx context.Context, cacheDir string, entry Entry, destDir string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if destDir == "" {
+		return "", fmt.Errorf("quarantine directory is required")
+	}
+	base, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return "", fmt.Errorf("resolve cache directory: %w", err)
+	}
+	if err := ensureWithin(base, entry.Path); err != nil {
+		return "", fmt.Errorf("validate cache path %q: %w", entry.Path, err)
+	}
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return "", fmt.Errorf("create quarantine dir: %w", err)
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	target := filepath.Join(destDir, fmt.Sprintf("%s@%s-%s", url.PathEscape(entry.Name), entry.Version, stamp))
+	if err := os.Rename(entry.Path, target); err != nil {
+		return "", fmt.Errorf("move %q to quarantine: %w", entry.Path, err)
+	}
+	return target, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func manifestMatches(path string, published []byte) bool {
+	if len(published) == 0 {
+		return false
+	}
+	installed, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	want, ok := normalizedManifest(published)
+	if !ok {
+		return false
+	}
+	got, ok := normalizedManifest(installed)
+	if !ok {
+		return false
+	}
+	return reflect.DeepEqual(want, got)
+}
+
+func normalizedManifest(data []byte) (map[string]any, bool) {
+	var manifest map[string]any
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, false
+	}
+	for key := range manifest {
+		if strings.HasPrefix(key, "_") {
+			delete(manifest, key)
+		}
+	}
+	return manifest, true
+}
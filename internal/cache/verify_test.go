@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareFilesReportsDifferences(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "index.js"), "tampered")
+	writeFile(t, filepath.Join(dir, "extra.js"), "injected")
+	writeFile(t, filepath.Join(dir, "same.js"), "same")
+	writeFile(t, filepath.Join(dir, "node_modules", "dep", "index.js"), "dependency")
+	writeFile(t, filepath.Join(dir, "package.json"), `{"name":"pkg","version":"1.0.0","_resolved":"https://example.test/pkg.tgz"}`)
+
+	manifest := []byte(`{"version":"1.0.0","name":"pkg"}`)
+	expected := map[string]string{
+		"index.js":     digest("original"),
+		"same.js":      digest("same"),
+		"gone.js":      digest("gone"),
+		"package.json": digest(string(manifest)),
+	}
+
+	diff, err := CompareFiles(expected, manifest, dir)
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	want := FileDiff{
+		Modified: []string{"index.js"},
+		Added:    []string{"extra.js"},
+		Missing:  []string{"gone.js"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Fatalf("unexpected diff %#v", diff)
+	}
+	if diff.Clean() {
+		t.Fatalf("expected diff to be dirty")
+	}
+}
+
+func TestQuarantineMovesEntry(t *testing.T) {
+	cacheDir := t.TempDir()
+	pluginDir := filepath.Join(cacheDir, "alpha")
+	writePackageJSON(t, pluginDir, `{"name":"alpha","version":"1.0.0"}`)
+	dest := filepath.Join(t.TempDir(), "quarantine")
+
+	moved, err := Quarantine(context.Background(), cacheDir, Entry{Name: "alpha", Version: "1.0.0", Path: pluginDir}, dest)
+	if err != nil {
+		t.Fatalf("quarantine: %v", err)
+	}
+	if _, err := os.Stat(pluginDir); !os.IsNotExist(err) {
+		t.Fatalf("expected entry removed from cache, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(moved, "package.json")); err != nil {
+		t.Fatalf("expected entry in quarantine: %v", err)
+	}
+
+	if _, err := Quarantine(context.Background(), cacheDir, Entry{Name: "evil", Path: filepath.Join(cacheDir, "..")}, dest); err == nil {
+		t.Fatalf("expected error for path outside cache")
+	}
+}
+
+func digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,7 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
 )
 
 var Version = "dev"
@@ -16,8 +20,12 @@ type CommonOptions struct {
 	GlobalConfig string
 	CacheDir     string
 	SnapshotDir  string
+	Policy       string
+	Registry     string
 	Offline      bool
 	LocalDirs    stringSliceFlag
+	Recursive    bool
+	Flatten      bool
 }
 
 type stringSliceFlag []string
@@ -49,8 +57,40 @@ func Run(args []string, stdout io.Writer, stderr io.Writer) int {
 		return runUpgrade(args[1:], stdout, stderr)
 	case "rollback":
 		return runRollback(args[1:], stdout, stderr)
+	case "link":
+		return runLink(args[1:], stdout, stderr)
+	case "unlink":
+		return runUnlink(args[1:], stdout, stderr)
+	case "disable":
+		return runDisable(args[1:], stdout, stderr)
+	case "enable":
+		return runEnable(args[1:], stdout, stderr)
+	case "history":
+		return runHistory(args[1:], stdout, stderr)
 	case "snapshot":
 		return runSnapshot(args[1:], stdout, stderr)
+	case "restore":
+		return runRestore(args[1:], stdout, stderr)
+	case "cache":
+		return runCache(args[1:], stdout, stderr)
+	case "verify":
+		return runVerify(args[1:], stdout, stderr)
+	case "lock":
+		return runLock(args[1:], stdout, stderr)
+	case "check":
+		return runCheck(args[1:], stdout, stderr)
+	case "export":
+		return runExport(args[1:], stdout, stderr)
+	case "import":
+		return runImport(args[1:], stdout, stderr)
+	case "scan":
+		return runScan(args[1:], stdout, stderr)
+	case "report":
+		return runReport(args[1:], stdout, stderr)
+	case "log":
+		return runLog(args[1:], stdout, stderr)
+	case "undo":
+		return runUndo(args[1:], stdout, stderr)
 	case "version", "--version", "-v":
 		fmt.Fprintf(stdout, "%s %s\n", toolName, Version)
 		return 0
@@ -74,8 +114,24 @@ func printUsage(w io.Writer) {
 		"  outdated   Show plugins with newer versions",
 		"  sync       Refresh cache to match pinned config",
 		"  upgrade    Pin and refresh plugins to a target version",
-		"  rollback   Restore the most recent plugin snapshot",
-		"  snapshot   Save a snapshot of current plugin state",
+		"  rollback   Restore a plugin snapshot (latest, or --to id|time|version)",
+		"  link       Point a plugin at a local checkout (link <plugin> <path>)",
+		"  unlink     Restore the npm spec of a linked plugin",
+		"  disable    Remove a plugin from config, keeping it for enable",
+		"  enable     Restore a disabled plugin at its original position",
+		"  history    List the snapshots recorded for a plugin",
+		"  snapshot   Save a snapshot of current plugin state (--name, list, diff, prune, fsck)",
+		"  restore    Restore every plugin from a named snapshot",
+		"  cache      Inspect the plugin cache (ls, du)",
+		"  verify     Check cached plugins against registry tarballs",
+		"  lock       Write patchline.lock with resolved plugin versions",
+		"  check      Evaluate plugins against the policy file",
+		"  export     Write a portable bundle of the plugin environment",
+		"  import     Apply a plugin bundle to this machine's configs",
+		"  scan       List every project config below a directory",
+		"  report     Plugin version matrix across projects (--root, --format markdown|html|csv)",
+		"  log        Show the operation journal, or one operation's changes",
+		"  undo       Reverse the last (or a given) journaled operation",
 		"  version    Print version information",
 	}
 	fmt.Fprintln(w, strings.Join(lines, "\n"))
@@ -84,31 +140,59 @@ func printUsage(w io.Writer) {
 func runList(args []string, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	opts := bindCommonFlags(fs)
+	bindRecursiveFlag(fs, opts)
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
+	if opts.Recursive {
+		return forEachProject(*opts, stdout, stderr, func(projectOpts CommonOptions) int {
+			return listCommand(projectOpts, stdout, stderr)
+		})
+	}
 	return listCommand(*opts, stdout, stderr)
 }
 
 func runOutdated(args []string, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("outdated", flag.ContinueOnError)
 	opts := bindCommonFlags(fs)
+	bindRecursiveFlag(fs, opts)
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
+	if opts.Recursive {
+		return forEachProject(*opts, stdout, stderr, func(projectOpts CommonOptions) int {
+			return outdatedCommand(projectOpts, stdout, stderr)
+		})
+	}
 	return outdatedCommand(*opts, stdout, stderr)
 }
 
 func runSync(args []string, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	var frozen bool
 	opts := bindCommonFlags(fs)
+	bindRecursiveFlag(fs, opts)
+	fs.BoolVar(&frozen, "frozen", false, "fail when config or cache disagrees with patchline.lock")
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	return syncCommand(*opts, stdout, stderr)
+	run := func(projectOpts CommonOptions) int {
+		if frozen {
+			if code := lockCheckCommand(projectOpts, stdout, stderr); code != 0 {
+				return code
+			}
+		}
+		return syncCommand(projectOpts, stdout, stderr)
+	}
+	return withJournal(*opts, append([]string{"sync"}, args...), stderr, func() int {
+		if opts.Recursive {
+			return forEachProject(*opts, stdout, stderr, run)
+		}
+		return run(*opts)
+	})
 }
 
 func runUpgrade(args []string, stdout io.Writer, stderr io.Writer) int {
@@ -119,11 +203,13 @@ func runUpgrade(args []string, stdout io.Writer, stderr io.Writer) int {
 	var patch bool
 	var all bool
 	opts := bindCommonFlags(fs)
+	bindFlattenFlag(fs, opts)
 	fs.StringVar(&target, "to", "", "explicit target version")
 	fs.BoolVar(&major, "major", false, "upgrade to latest major")
 	fs.BoolVar(&minor, "minor", false, "upgrade to latest minor")
 	fs.BoolVar(&patch, "patch", false, "upgrade to latest patch")
 	fs.BoolVar(&all, "all", false, "upgrade all plugins")
+	bindRecursiveFlag(fs, opts)
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -160,12 +246,28 @@ func runUpgrade(args []string, stdout io.Writer, stderr io.Writer) int {
 	case patch:
 		mode = "patch"
 	}
-	return upgradeCommand(*opts, name, target, mode, all, stdout, stderr)
+	run := func(projectOpts CommonOptions) int {
+		return upgradeCommand(projectOpts, name, target, mode, all, stdout, stderr)
+	}
+	return withJournal(*opts, append([]string{"upgrade"}, args...), stderr, func() int {
+		if opts.Recursive {
+			return recursiveUpgrade(*opts, name, stdout, stderr, run)
+		}
+		return run(*opts)
+	})
 }
 
 func runRollback(args []string, stdout io.Writer, stderr io.Writer) int {
 	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
 	opts := bindCommonFlags(fs)
+	bindFlattenFlag(fs, opts)
+	var ropts rollbackOptions
+	var remaps stringSliceFlag
+	fs.StringVar(&ropts.To, "to", "", "snapshot id, timestamp, or version to restore")
+	fs.BoolVar(&ropts.File, "file", false, "restore the whole config file from the snapshot backup")
+	fs.BoolVar(&ropts.Force, "force", false, "overwrite a config file that changed after the snapshot")
+	fs.BoolVar(&ropts.Exact, "exact", false, "pin to the version that was installed when the snapshot was taken")
+	fs.Var(&remaps, "remap", "rewrite snapshot config paths (old=new, repeatable)")
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
@@ -174,17 +276,412 @@ func runRollback(args []string, stdout io.Writer, stderr io.Writer) int {
 		fmt.Fprintln(stderr, "missing plugin name")
 		return 2
 	}
-	return rollbackCommand(*opts, fs.Arg(0), stdout, stderr)
+	if ropts.Force && !ropts.File {
+		fmt.Fprintln(stderr, "--force requires --file")
+		return 2
+	}
+	if ropts.Exact && ropts.File {
+		fmt.Fprintln(stderr, "cannot use --exact with --file")
+		return 2
+	}
+	parsed, err := parseRemaps(remaps)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	ropts.Remaps = parsed
+	return withJournal(*opts, append([]string{"rollback"}, args...), stderr, func() int {
+		return rollbackCommand(*opts, fs.Arg(0), ropts, stdout, stderr)
+	})
+}
+
+func runLink(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("link", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	bindFlattenFlag(fs, opts)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: patchline link <plugin> <path>")
+		return 2
+	}
+	return withJournal(*opts, append([]string{"link"}, args...), stderr, func() int {
+		return linkCommand(*opts, fs.Arg(0), fs.Arg(1), stdout, stderr)
+	})
+}
+
+func runUnlink(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("unlink", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: patchline unlink <plugin>")
+		return 2
+	}
+	return withJournal(*opts, append([]string{"unlink"}, args...), stderr, func() int {
+		return unlinkCommand(*opts, fs.Arg(0), stdout, stderr)
+	})
+}
+
+func runDisable(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("disable", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: patchline disable <plugin>")
+		return 2
+	}
+	return withJournal(*opts, append([]string{"disable"}, args...), stderr, func() int {
+		return disableCommand(*opts, fs.Arg(0), stdout, stderr)
+	})
+}
+
+func runEnable(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("enable", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: patchline enable <plugin>")
+		return 2
+	}
+	return withJournal(*opts, append([]string{"enable"}, args...), stderr, func() int {
+		return enableCommand(*opts, fs.Arg(0), stdout, stderr)
+	})
+}
+
+func runHistory(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "missing plugin name")
+		return 2
+	}
+	return historyCommand(*opts, fs.Arg(0), stdout, stderr)
 }
 
 func runSnapshot(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "list" {
+		fs := flag.NewFlagSet("snapshot list", flag.ContinueOnError)
+		opts := bindCommonFlags(fs)
+		fs.SetOutput(stderr)
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		return snapshotListCommand(*opts, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "prune" {
+		return runSnapshotPrune(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "diff" {
+		fs := flag.NewFlagSet("snapshot diff", flag.ContinueOnError)
+		var ref string
+		var asJSON bool
+		var remaps stringSliceFlag
+		opts := bindCommonFlags(fs)
+		fs.StringVar(&ref, "to", "", "compare a plugin's snapshot by id, timestamp, or version")
+		fs.Var(&remaps, "remap", "rewrite snapshot config paths (old=new, repeatable)")
+		fs.BoolVar(&asJSON, "json", false, "print JSON output")
+		fs.SetOutput(stderr)
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if ref != "" && fs.NArg() == 0 {
+			fmt.Fprintln(stderr, "--to requires a plugin name")
+			return 2
+		}
+		parsed, err := parseRemaps(remaps)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		return snapshotDiffCommand(*opts, fs.Arg(0), ref, parsed, asJSON, stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "fsck" {
+		fs := flag.NewFlagSet("snapshot fsck", flag.ContinueOnError)
+		var repair bool
+		var asJSON bool
+		opts := bindCommonFlags(fs)
+		fs.BoolVar(&repair, "repair", false, "salvage corrupt history files and remove leftover temp files")
+		fs.BoolVar(&asJSON, "json", false, "print JSON output")
+		fs.SetOutput(stderr)
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if !repair {
+			return snapshotFsckCommand(*opts, repair, asJSON, stdout, stderr)
+		}
+		return withJournal(*opts, append([]string{"snapshot"}, args...), stderr, func() int {
+			return snapshotFsckCommand(*opts, repair, asJSON, stdout, stderr)
+		})
+	}
+
 	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	var name string
+	opts := bindCommonFlags(fs)
+	fs.StringVar(&name, "name", "", "save all plugins as a named snapshot")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return withJournal(*opts, append([]string{"snapshot"}, args...), stderr, func() int {
+		return snapshotCommand(*opts, name, stdout, stderr)
+	})
+}
+
+func runSnapshotPrune(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("snapshot prune", flag.ContinueOnError)
+	var keepLast int
+	var maxAge string
+	var dryRun bool
+	opts := bindCommonFlags(fs)
+	fs.IntVar(&keepLast, "keep-last", 0, "keep the last N entries per plugin")
+	fs.StringVar(&maxAge, "max-age", "", "keep entries newer than this age (e.g. 720h, 30d)")
+	fs.BoolVar(&dryRun, "dry-run", false, "show what would be removed")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	overrides := pruneOverrides{DryRun: dryRun}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "keep-last":
+			overrides.KeepLast = &keepLast
+		case "max-age":
+			overrides.MaxAge = &maxAge
+		}
+	})
+	if keepLast < 0 {
+		fmt.Fprintln(stderr, "--keep-last must not be negative")
+		return 2
+	}
+	if dryRun {
+		return snapshotPruneCommand(*opts, overrides, stdout, stderr)
+	}
+	return withJournal(*opts, append([]string{"snapshot", "prune"}, args...), stderr, func() int {
+		return snapshotPruneCommand(*opts, overrides, stdout, stderr)
+	})
+}
+
+func runExport(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var eopts exportOptions
+	opts := bindCommonFlags(fs)
+	fs.StringVar(&eopts.Output, "output", "", "write the bundle to this file instead of stdout")
+	fs.BoolVar(&eopts.IncludeLocal, "include-local", false, "include local plugin file contents")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return exportCommand(*opts, eopts, stdout, stderr)
+}
+
+func runImport(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	var iopts importOptions
+	opts := bindCommonFlags(fs)
+	bindFlattenFlag(fs, opts)
+	fs.BoolVar(&iopts.Replace, "replace", false, "make declarations match the bundle exactly, removing others")
+	fs.BoolVar(&iopts.DryRun, "dry-run", false, "show the plan without changing files")
+	fs.StringVar(&iopts.Conflict, "conflict", conflictFail, "how to resolve differing specs: fail, bundle, or keep")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "missing bundle path")
+		return 2
+	}
+	switch iopts.Conflict {
+	case conflictFail, conflictBundle, conflictKeep:
+	default:
+		fmt.Fprintf(stderr, "invalid --conflict: %s\n", iopts.Conflict)
+		return 2
+	}
+	if iopts.DryRun {
+		return importCommand(*opts, fs.Arg(0), iopts, stdout, stderr)
+	}
+	return withJournal(*opts, append([]string{"import"}, args...), stderr, func() int {
+		return importCommand(*opts, fs.Arg(0), iopts, stdout, stderr)
+	})
+}
+
+func runRestore(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	var remaps stringSliceFlag
+	opts := bindCommonFlags(fs)
+	bindFlattenFlag(fs, opts)
+	fs.Var(&remaps, "remap", "rewrite snapshot config paths (old=new, repeatable)")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "missing snapshot name")
+		return 2
+	}
+	parsed, err := parseRemaps(remaps)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return withJournal(*opts, append([]string{"restore"}, args...), stderr, func() int {
+		return restoreCommand(*opts, fs.Arg(0), parsed, stdout, stderr)
+	})
+}
+
+func runCache(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "missing cache subcommand (ls or du)")
+		return 2
+	}
+	action := args[0]
+	if action != "ls" && action != "du" {
+		fmt.Fprintf(stderr, "unknown cache subcommand: %s\n", action)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("cache "+action, flag.ContinueOnError)
+	var sortBy string
+	var asJSON bool
+	opts := bindCommonFlags(fs)
+	defaultSort := "name"
+	if action == "du" {
+		defaultSort = "size"
+	}
+	fs.StringVar(&sortBy, "sort", defaultSort, "sort by name, size, or modified")
+	fs.BoolVar(&asJSON, "json", false, "print JSON output")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	return cacheCommand(*opts, action, sortBy, asJSON, stdout, stderr)
+}
+
+func runVerify(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	var vopts verifyOptions
+	opts := bindCommonFlags(fs)
+	fs.BoolVar(&vopts.All, "all", false, "verify every cache entry, including dependencies")
+	fs.BoolVar(&vopts.Quarantine, "quarantine", false, "move tampered entries out of the cache")
+	fs.BoolVar(&vopts.JSON, "json", false, "print JSON output")
+	fs.BoolVar(&vopts.Lockfile, "lockfile", false, "verify against patchline.lock instead of the registry")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !vopts.Quarantine {
+		return verifyCommand(*opts, vopts, stdout, stderr)
+	}
+	return withJournal(*opts, append([]string{"verify"}, args...), stderr, func() int {
+		return verifyCommand(*opts, vopts, stdout, stderr)
+	})
+}
+
+func runCheck(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	var asJSON bool
 	opts := bindCommonFlags(fs)
+	fs.BoolVar(&asJSON, "json", false, "print JSON output")
 	fs.SetOutput(stderr)
 	if err := fs.Parse(args); err != nil {
 		return 2
 	}
-	return snapshotCommand(*opts, stdout, stderr)
+	return checkCommand(*opts, asJSON, stdout, stderr)
+}
+
+func runLock(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lock", flag.ContinueOnError)
+	opts := bindCommonFlags(fs)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return withJournal(*opts, append([]string{"lock"}, args...), stderr, func() int {
+		return lockCommand(*opts, stdout, stderr)
+	})
+}
+
+func runLog(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	var asJSON bool
+	opts := bindCommonFlags(fs)
+	fs.BoolVar(&asJSON, "json", false, "print JSON output")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return logCommand(*opts, fs.Arg(0), asJSON, stdout, stderr)
+}
+
+func runUndo(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	var force bool
+	opts := bindCommonFlags(fs)
+	fs.BoolVar(&force, "force", false, "overwrite files that changed after the operation")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	return undoCommand(*opts, fs.Arg(0), force, stdout, stderr)
+}
+
+func runScan(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	var asJSON bool
+	opts := bindCommonFlags(fs)
+	fs.BoolVar(&asJSON, "json", false, "print JSON output")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		opts.ProjectRoot = fs.Arg(0)
+	}
+	return scanCommand(*opts, asJSON, stdout, stderr)
+}
+
+func runReport(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	var root string
+	var format string
+	var output string
+	opts := bindCommonFlags(fs)
+	fs.StringVar(&root, "root", "", "directory to scan for project configs (default --project or the current directory)")
+	fs.StringVar(&format, "format", reportMarkdown, "output format: markdown, html or csv")
+	fs.StringVar(&output, "output", "", "write the report to a file instead of stdout")
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintln(stderr, "report does not take arguments; use --root")
+		return 2
+	}
+	return reportCommand(*opts, root, format, output, stdout, stderr)
+}
+
+func bindRecursiveFlag(fs *flag.FlagSet, opts *CommonOptions) {
+	fs.BoolVar(&opts.Recursive, "recursive", false, "run for every project config below --project (or the current directory)")
+}
+
+func bindFlattenFlag(fs *flag.FlagSet, opts *CommonOptions) {
+	fs.BoolVar(&opts.Flatten, "flatten", false, "replace plugin entries that use {env:} or {file:} substitution with a literal spec")
 }
 
 func bindCommonFlags(fs *flag.FlagSet) *CommonOptions {
@@ -193,11 +690,65 @@ func bindCommonFlags(fs *flag.FlagSet) *CommonOptions {
 	fs.StringVar(&opts.GlobalConfig, "global-config", "", "override global opencode.json path")
 	fs.StringVar(&opts.CacheDir, "cache-dir", "", "override OpenCode plugin cache directory")
 	fs.StringVar(&opts.SnapshotDir, "snapshot-dir", "", "override snapshot storage directory")
+	fs.StringVar(&opts.Policy, "policy", "", "override the policy file")
+	fs.StringVar(&opts.Registry, "registry", "", "npm registry base URL")
 	fs.BoolVar(&opts.Offline, "offline", false, "disable registry network calls")
 	fs.Var(&opts.LocalDirs, "local-dir", "additional local plugin directory (repeatable)")
 	return opts
 }
 
+func registryFor(opts CommonOptions) npm.Registry {
+	return npm.Registry{BaseURL: opts.Registry}
+}
+
+// updatePluginSpec writes a new spec for a plugin. Templated entries are only
+// replaced when --flatten is set. Plugins declared in OPENCODE_CONFIG_CONTENT
+// go through inlineUpdates instead.
+func updatePluginSpec(opts CommonOptions, path string, name string, spec string) error {
+	if opts.Flatten {
+		return opencode.FlattenPluginSpec(path, name, spec)
+	}
+	return opencode.UpdatePluginSpec(path, name, spec)
+}
+
+// inlineUpdates collects spec changes to plugins declared in
+// OPENCODE_CONFIG_CONTENT. patchline cannot edit the variable, so commands
+// take no snapshot and leave the cache alone for these plugins, print the
+// replacement value once at the end and exit non-zero.
+type inlineUpdates struct {
+	value string
+	names []string
+}
+
+// add applies a spec change on top of the changes collected so far.
+func (u *inlineUpdates) add(opts CommonOptions, name string, spec string) error {
+	base := u.value
+	if len(u.names) == 0 {
+		base = os.Getenv("OPENCODE_CONFIG_CONTENT")
+	}
+	value, err := opencode.UpdateInlinePluginSpec(base, name, spec, opts.Flatten)
+	if err != nil {
+		return err
+	}
+	u.value = value
+	u.names = append(u.names, name)
+	return nil
+}
+
+// report prints the replacement value, if any changes were collected, and
+// reports whether it did.
+func (u *inlineUpdates) report(stdout io.Writer) bool {
+	if len(u.names) == 0 {
+		return false
+	}
+	fmt.Fprintf(stdout, "patchline cannot edit OPENCODE_CONFIG_CONTENT, which declares %s. Set it to:\n%s\n", strings.Join(u.names, ", "), u.value)
+	return true
+}
+
+func printTemplatedRefusal(stderr io.Writer, name string, template string, path string) {
+	fmt.Fprintf(stderr, "refusing to replace %s: it is declared as %q in %s; pass --flatten to replace it with a literal spec\n", name, template, path)
+}
+
 func flagCount(values ...bool) int {
 	count := 0
 	for _, value := range values {
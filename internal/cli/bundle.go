@@ -0,0 +1,450 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+	"github.com/AksharP5/Patchline/internal/bundle"
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+type exportOptions struct {
+	Output       string
+	IncludeLocal bool
+}
+
+type importOptions struct {
+	Replace  bool
+	DryRun   bool
+	Conflict string
+}
+
+// Conflict strategies for import.
+const (
+	conflictFail   = "fail"
+	conflictBundle = "bundle"
+	conflictKeep   = "keep"
+)
+
+type importAction struct {
+	Scope   string
+	Name    string
+	Action  string
+	Current string
+	Bundle  string
+	Path    string
+	Content []byte
+	Local   bool
+}
+
+func exportCommand(opts CommonOptions, eopts exportOptions, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	root, err := projectDir(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to locate project: %v\n", err)
+		return 1
+	}
+
+	installedByName := map[string]cache.Entry{}
+	if cacheDir, _ := cache.ResolveDir(opts.CacheDir); cacheDir != "" {
+		entries, err := cache.Detect(context.Background(), cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+		for _, entry := range entries {
+			installedByName[entry.Name] = entry
+		}
+	}
+
+	b := bundle.Bundle{}
+	chosen := map[string]opencode.PluginSpec{}
+	skippedLocal := 0
+	for _, spec := range result.Plugins {
+		if spec.Source == opencode.SourceDependency {
+			continue
+		}
+		if spec.LinkPath != "" {
+			fmt.Fprintf(stderr, "skipping %s, which is linked to %s; run `patchline unlink %s` to bundle its npm spec\n", spec.Name, spec.LinkPath, spec.Name)
+			continue
+		}
+		if spec.Source == opencode.SourceLocal {
+			if !eopts.IncludeLocal {
+				skippedLocal++
+				continue
+			}
+			if spec.LocalDir != "" {
+				fmt.Fprintf(stderr, "skipping local plugin folder %s; only single-file plugins can be bundled\n", spec.LocalDir)
+				continue
+			}
+			content, err := os.ReadFile(spec.LocalPath)
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to read local plugin %s: %v\n", spec.LocalPath, err)
+				return 1
+			}
+			b.LocalFiles = append(b.LocalFiles, bundle.File{
+				Name:    filepath.Base(spec.LocalPath),
+				Scope:   localScope(root, spec.LocalPath),
+				Content: content,
+			})
+			continue
+		}
+
+		key := bundleScope(spec.Source) + "\x00" + spec.Name
+		if existing, ok := chosen[key]; ok && opencode.Precedence(existing.Source) <= opencode.Precedence(spec.Source) {
+			continue
+		}
+		chosen[key] = spec
+	}
+	for _, spec := range chosen {
+		plugin := bundle.Plugin{
+			Name:   spec.Name,
+			Spec:   spec.DeclaredSpec,
+			Scope:  bundleScope(spec.Source),
+			Source: string(spec.Source),
+		}
+		if entry, ok := installedByName[spec.Name]; ok && !entry.Corrupt() {
+			plugin.Installed = entry.Version
+		}
+		b.Plugins = append(b.Plugins, plugin)
+	}
+
+	if snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir); snapshotDir != "" {
+		sets, err := snapshot.Store{Directory: snapshotDir}.ListSets()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to load snapshots: %v\n", err)
+			return 1
+		}
+		for _, set := range sets {
+			specs := map[string]string{}
+			for _, entry := range set.Entries {
+				specs[entry.PluginName] = entry.PreviousSpec
+			}
+			b.Snapshots = append(b.Snapshots, bundle.Snapshot{Name: set.Name, Timestamp: set.Timestamp, Specs: specs})
+		}
+	}
+
+	data, err := bundle.Encode(b)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to encode bundle: %v\n", err)
+		return 1
+	}
+	if eopts.Output == "" || eopts.Output == "-" {
+		if _, err := stdout.Write(data); err != nil {
+			fmt.Fprintf(stderr, "failed to write bundle: %v\n", err)
+			return 1
+		}
+	} else {
+		if err := os.WriteFile(eopts.Output, data, 0o644); err != nil {
+			fmt.Fprintf(stderr, "failed to write bundle: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Exported %d plugin(s) and %d local file(s) to %s.\n", len(b.Plugins), len(b.LocalFiles), eopts.Output)
+	}
+	if skippedLocal > 0 {
+		fmt.Fprintf(stderr, "%d local plugin file(s) were not included; use --include-local to bundle them.\n", skippedLocal)
+	}
+	return 0
+}
+
+func importCommand(opts CommonOptions, path string, iopts importOptions, stdout io.Writer, stderr io.Writer) int {
+	b, err := bundle.Load(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load bundle: %v\n", err)
+		return 1
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	root, err := projectDir(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to locate project: %v\n", err)
+		return 1
+	}
+
+	configPaths := map[string]string{
+		bundle.ScopeProject: result.ProjectConfig,
+		bundle.ScopeGlobal:  result.GlobalConfig,
+	}
+	if configPaths[bundle.ScopeProject] == "" {
+		configPaths[bundle.ScopeProject] = filepath.Join(root, "opencode.json")
+	}
+	if configPaths[bundle.ScopeGlobal] == "" {
+		configPaths[bundle.ScopeGlobal] = opts.GlobalConfig
+	}
+	if configPaths[bundle.ScopeGlobal] == "" {
+		configPaths[bundle.ScopeGlobal] = opencode.DefaultGlobalConfigPath()
+	}
+	localDirs := map[string]string{
+		bundle.ScopeProject: filepath.Join(root, ".opencode", "plugin"),
+		bundle.ScopeGlobal:  filepath.Join(filepath.Dir(configPaths[bundle.ScopeGlobal]), "plugin"),
+	}
+
+	actions, err := planImport(b, configPaths, localDirs, iopts)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to plan import: %v\n", err)
+		return 1
+	}
+	renderImportPlan(stdout, b, actions)
+
+	conflicts := 0
+	changes := 0
+	for _, action := range actions {
+		switch action.Action {
+		case "conflict":
+			conflicts++
+		case "add", "update", "remove":
+			changes++
+		}
+	}
+	if conflicts > 0 {
+		fmt.Fprintf(stderr, "%d conflict(s); re-run with --conflict bundle or --conflict keep\n", conflicts)
+		return 1
+	}
+
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 1
+	}
+	refused := 0
+	for _, action := range actions {
+		if action.Local || (action.Action != "add" && action.Action != "update") {
+			continue
+		}
+		if violations := specViolations(pol, action.Name, action.Bundle); len(violations) > 0 {
+			fmt.Fprintf(stderr, "refusing to import %s: %s\n", action.Bundle, policyMessages(violations))
+			refused++
+		}
+	}
+	if refused > 0 {
+		fmt.Fprintf(stderr, "%d plugin(s) were blocked by policy; no files were changed.\n", refused)
+		return 1
+	}
+	if iopts.DryRun {
+		fmt.Fprintln(stdout, "Dry run; no files were changed.")
+		return 0
+	}
+	if changes == 0 {
+		fmt.Fprintln(stdout, "Nothing to import; the environment already matches the bundle.")
+		return 0
+	}
+
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	store.Roots = snapshotRoots(opts, nil)
+	ctx := context.Background()
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+
+	for _, action := range actions {
+		if action.Local {
+			if action.Action != "add" && action.Action != "update" {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(action.Path), 0o755); err != nil {
+				fmt.Fprintf(stderr, "failed to write %s: %v\n", action.Path, err)
+				return 1
+			}
+			if err := atomicfile.Write(action.Path, action.Content, 0o644); err != nil {
+				fmt.Fprintf(stderr, "failed to write %s: %v\n", action.Path, err)
+				return 1
+			}
+			continue
+		}
+
+		switch action.Action {
+		case "add", "update", "remove":
+		default:
+			continue
+		}
+		_, err := store.Save(snapshot.Entry{
+			PluginName:   action.Name,
+			PreviousSpec: action.Current,
+			Source:       action.Scope,
+			Reason:       "import",
+			ConfigPath:   action.Path,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", action.Name, err)
+			return 1
+		}
+
+		switch action.Action {
+		case "add":
+			err = opencode.AddPluginSpec(action.Path, action.Bundle)
+		case "update":
+			err = updatePluginSpec(opts, action.Path, action.Name, action.Bundle)
+			if err == nil && cacheDir != "" {
+				_, err = cache.Invalidate(ctx, cacheDir, action.Name)
+			}
+		case "remove":
+			err = opencode.RemovePluginSpec(action.Path, action.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to import %s: %v\n", action.Name, err)
+			if errors.Is(err, opencode.ErrTemplatedSpec) {
+				fmt.Fprintln(stderr, "pass --flatten to replace entries that use config substitution")
+			}
+			return 1
+		}
+	}
+
+	fmt.Fprintf(stdout, "Imported %d change(s). Run OpenCode to install plugins.\n", changes)
+	return 0
+}
+
+// planImport compares the bundle with the target configs and local plugin
+// directories and decides what to do with each plugin and file.
+func planImport(b bundle.Bundle, configPaths map[string]string, localDirs map[string]string, iopts importOptions) ([]importAction, error) {
+	actions := []importAction{}
+	for _, scope := range []string{bundle.ScopeProject, bundle.ScopeGlobal} {
+		path := configPaths[scope]
+		current := map[string]string{}
+		if data, err := os.ReadFile(path); err == nil {
+			specs, err := opencode.ParsePluginSpecs(path, data)
+			if err != nil {
+				return nil, err
+			}
+			for _, spec := range specs {
+				current[spec.Name] = spec.DeclaredSpec
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("read %s: %w", path, err)
+		}
+
+		wanted := map[string]struct{}{}
+		for _, plugin := range b.Plugins {
+			if plugin.Scope != scope {
+				continue
+			}
+			wanted[plugin.Name] = struct{}{}
+			action := importAction{Scope: scope, Name: plugin.Name, Current: current[plugin.Name], Bundle: plugin.Spec, Path: path}
+			switch existing, ok := current[plugin.Name]; {
+			case !ok:
+				action.Action = "add"
+			case existing == plugin.Spec:
+				action.Action = "keep"
+			case iopts.Replace || iopts.Conflict == conflictBundle:
+				action.Action = "update"
+			case iopts.Conflict == conflictKeep:
+				action.Action = "keep"
+			default:
+				action.Action = "conflict"
+			}
+			actions = append(actions, action)
+		}
+
+		if iopts.Replace {
+			names := make([]string, 0, len(current))
+			for name := range current {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if _, ok := wanted[name]; ok {
+					continue
+				}
+				actions = append(actions, importAction{Scope: scope, Name: name, Action: "remove", Current: current[name], Path: path})
+			}
+		}
+
+		for _, file := range b.LocalFiles {
+			if file.Scope != scope {
+				continue
+			}
+			target := filepath.Join(localDirs[scope], file.Name)
+			action := importAction{Scope: scope, Name: file.Name, Bundle: "local file", Path: target, Content: file.Content, Local: true}
+			existing, err := os.ReadFile(target)
+			switch {
+			case os.IsNotExist(err):
+				action.Action = "add"
+			case err != nil:
+				return nil, fmt.Errorf("read %s: %w", target, err)
+			case string(existing) == string(file.Content):
+				action.Action = "keep"
+				action.Current = "local file"
+			case iopts.Replace || iopts.Conflict == conflictBundle:
+				action.Action = "update"
+				action.Current = "local file (differs)"
+			case iopts.Conflict == conflictKeep:
+				action.Action = "keep"
+				action.Current = "local file (differs)"
+			default:
+				action.Action = "conflict"
+				action.Current = "local file (differs)"
+			}
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+func renderImportPlan(w io.Writer, b bundle.Bundle, actions []importAction) {
+	if len(actions) == 0 {
+		fmt.Fprintln(w, "Bundle contains no plugins.")
+	} else {
+		headers := []string{"SCOPE", "PLUGIN", "ACTION", "CURRENT", "BUNDLE"}
+		rows := make([][]string, 0, len(actions))
+		for _, action := range actions {
+			current := action.Current
+			if current == "" {
+				current = "-"
+			}
+			bundled := action.Bundle
+			if bundled == "" {
+				bundled = "-"
+			}
+			rows = append(rows, []string{action.Scope, action.Name, action.Action, current, bundled})
+		}
+		renderTable(w, headers, rows)
+	}
+
+	if len(b.Snapshots) > 0 {
+		names := make([]string, 0, len(b.Snapshots))
+		for _, snap := range b.Snapshots {
+			names = append(names, snap.Name)
+		}
+		fmt.Fprintf(w, "Bundle records %d named snapshot(s): %s\n", len(names), strings.Join(names, ", "))
+	}
+	fmt.Fprintln(w, "")
+}
+
+// bundleScope maps a config source to the scope it is imported into.
+func bundleScope(source opencode.Source) string {
+	if source == opencode.SourceProject {
+		return bundle.ScopeProject
+	}
+	return bundle.ScopeGlobal
+}
+
+func localScope(root string, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return bundle.ScopeProject
+	}
+	return bundle.ScopeGlobal
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type bundleEnv struct {
+	root    string
+	project string
+	global  string
+	opts    CommonOptions
+}
+
+func newBundleEnv(t *testing.T, projectConfig string, globalConfig string) bundleEnv {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("OPENCODE_CONFIG", "")
+	t.Setenv("OPENCODE_CONFIG_DIR", "")
+	env := bundleEnv{
+		root:    root,
+		project: filepath.Join(root, "project", "opencode.json"),
+		global:  filepath.Join(root, "xdg", "opencode", "opencode.json"),
+	}
+	for path, content := range map[string]string{env.project: projectConfig, env.global: globalConfig} {
+		if content == "" {
+			continue
+		}
+		writeTestFile(t, path, content)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(env.project), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	env.opts = CommonOptions{
+		ProjectRoot: filepath.Dir(env.project),
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "snapshots"),
+	}
+	return env
+}
+
+func exportBundle(t *testing.T, opts CommonOptions, includeLocal bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := exportCommand(opts, exportOptions{Output: path, IncludeLocal: includeLocal}, &stdout, &stderr); code != 0 {
+		t.Fatalf("export failed: %d %s", code, stderr.String())
+	}
+	return path
+}
+
+func TestExportImportMergesIntoEmptyMachine(t *testing.T) {
+	source := newBundleEnv(t, `{"plugin": ["alpha@1.0.0"]}`, `{"plugin": ["beta"]}`)
+	writeTestFile(t, filepath.Join(filepath.Dir(source.project), ".opencode", "plugin", "hook.js"), "export default {}\n")
+	bundlePath := exportBundle(t, source.opts, true)
+
+	target := newBundleEnv(t, "", "")
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := importCommand(target.opts, bundlePath, importOptions{Conflict: conflictFail}, &stdout, &stderr); code != 0 {
+		t.Fatalf("import failed: %d %s", code, stderr.String())
+	}
+
+	project, err := os.ReadFile(target.project)
+	if err != nil {
+		t.Fatalf("read project config: %v", err)
+	}
+	if !strings.Contains(string(project), "alpha@1.0.0") {
+		t.Fatalf("expected alpha in project config, got %s", string(project))
+	}
+	global, err := os.ReadFile(target.global)
+	if err != nil {
+		t.Fatalf("read global config: %v", err)
+	}
+	if !strings.Contains(string(global), "beta") {
+		t.Fatalf("expected beta in global config, got %s", string(global))
+	}
+	hook, err := os.ReadFile(filepath.Join(filepath.Dir(target.project), ".opencode", "plugin", "hook.js"))
+	if err != nil || string(hook) != "export default {}\n" {
+		t.Fatalf("expected local plugin file, got %q %v", string(hook), err)
+	}
+}
+
+func TestImportRefusesPolicyViolation(t *testing.T) {
+	source := newBundleEnv(t, `{"plugin": ["alpha@1.0.0", "blocked-plugin"]}`, "")
+	bundlePath := exportBundle(t, source.opts, false)
+
+	target := newBundleEnv(t, "", "")
+	writePolicyFile(t, filepath.Dir(target.project), `{"deny":["blocked-*"]}`)
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := importCommand(target.opts, bundlePath, importOptions{Conflict: conflictFail}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected import to be refused, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "refusing to import blocked-plugin") {
+		t.Fatalf("expected refusal message, got %s", stderr.String())
+	}
+	if _, err := os.Stat(target.project); !os.IsNotExist(err) {
+		t.Fatalf("expected no config to be written, got %v", err)
+	}
+}
+
+func TestImportConflictHandling(t *testing.T) {
+	source := newBundleEnv(t, `{"plugin": ["alpha@2.0.0", "gamma"]}`, "")
+	bundlePath := exportBundle(t, source.opts, false)
+
+	target := newBundleEnv(t, `{"plugin": ["alpha@1.0.0", "delta"]}`, "")
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := importCommand(target.opts, bundlePath, importOptions{Conflict: conflictFail}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected conflict failure, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "conflict") {
+		t.Fatalf("expected conflict in preview, got %s", stdout.String())
+	}
+	data, _ := os.ReadFile(target.project)
+	if strings.Contains(string(data), "gamma") {
+		t.Fatalf("expected no changes on conflict, got %s", string(data))
+	}
+
+	stdout.Reset()
+	if code := importCommand(target.opts, bundlePath, importOptions{Conflict: conflictKeep}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected keep import to succeed, got %d %s", code, stderr.String())
+	}
+	data, _ = os.ReadFile(target.project)
+	if !strings.Contains(string(data), "alpha@1.0.0") || !strings.Contains(string(data), "gamma") || !strings.Contains(string(data), "delta") {
+		t.Fatalf("expected merge keeping local alpha, got %s", string(data))
+	}
+
+	stdout.Reset()
+	if code := importCommand(target.opts, bundlePath, importOptions{Replace: true, Conflict: conflictFail}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected replace import to succeed, got %d %s", code, stderr.String())
+	}
+	data, _ = os.ReadFile(target.project)
+	if !strings.Contains(string(data), "alpha@2.0.0") || strings.Contains(string(data), "delta") {
+		t.Fatalf("expected replace to match bundle, got %s", string(data))
+	}
+}
+
+func TestImportDryRunChangesNothing(t *testing.T) {
+	source := newBundleEnv(t, `{"plugin": ["alpha@2.0.0"]}`, "")
+	bundlePath := exportBundle(t, source.opts, false)
+
+	target := newBundleEnv(t, `{"plugin": ["beta"]}`, "")
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := importCommand(target.opts, bundlePath, importOptions{DryRun: true, Conflict: conflictFail}, &stdout, &stderr); code != 0 {
+		t.Fatalf("dry run failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "add") || !strings.Contains(stdout.String(), "Dry run") {
+		t.Fatalf("expected preview, got %s", stdout.String())
+	}
+	data, _ := os.ReadFile(target.project)
+	if strings.Contains(string(data), "alpha") {
+		t.Fatalf("expected no changes, got %s", string(data))
+	}
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+type cacheRow struct {
+	Name       string    `json:"name"`
+	Version    string    `json:"version"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Modified   time.Time `json:"modified"`
+	Declared   bool      `json:"declared"`
+	Dependents []string  `json:"dependents"`
+}
+
+type cacheReport struct {
+	Directory string     `json:"directory"`
+	Total     int64      `json:"total"`
+	Entries   []cacheRow `json:"entries"`
+}
+
+func cacheCommand(opts CommonOptions, action string, sortBy string, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+
+	cacheDir, candidates := cache.ResolveDir(opts.CacheDir)
+	if cacheDir == "" {
+		if len(candidates) > 0 {
+			fmt.Fprintf(stderr, "cache directory not found. Checked: %s\n", strings.Join(candidates, ", "))
+		} else {
+			fmt.Fprintln(stderr, "cache directory not found")
+		}
+		return 1
+	}
+
+	usage, err := cache.Inventory(context.Background(), cacheDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+		return 1
+	}
+
+	report := buildCacheReport(cacheDir, result.Plugins, usage)
+	if err := sortCacheRows(report.Entries, sortBy); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "failed to encode report: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	switch action {
+	case "du":
+		renderCacheUsage(stdout, report)
+	default:
+		renderCacheInventory(stdout, report)
+	}
+	return 0
+}
+
+func buildCacheReport(cacheDir string, specs []opencode.PluginSpec, usage []cache.Usage) cacheReport {
+	declared := map[string]bool{}
+	roots := []string{}
+	for _, spec := range specs {
+		if spec.Source == opencode.SourceLocal || spec.Source == opencode.SourceDependency || spec.LinkPath != "" {
+			continue
+		}
+		declared[spec.Name] = true
+		roots = append(roots, spec.Name)
+	}
+
+	entries := make([]cache.Entry, 0, len(usage))
+	for _, item := range usage {
+		entries = append(entries, item.Entry)
+	}
+	dependents := cache.Dependents(entries, roots)
+
+	report := cacheReport{Directory: cacheDir, Entries: make([]cacheRow, 0, len(usage))}
+	for _, item := range usage {
+		deps := []string{}
+		for _, dependent := range dependents[item.Name] {
+			if dependent != item.Name {
+				deps = append(deps, dependent)
+			}
+		}
+		report.Entries = append(report.Entries, cacheRow{
+			Name:       item.Name,
+			Version:    item.Version,
+			Path:       item.Path,
+			Size:       item.Size,
+			Modified:   item.Modified,
+			Declared:   declared[item.Name],
+			Dependents: deps,
+		})
+		report.Total += item.Size
+	}
+	return report
+}
+
+func sortCacheRows(rows []cacheRow, sortBy string) error {
+	var less func(a, b cacheRow) bool
+	switch sortBy {
+	case "", "name":
+		less = func(a, b cacheRow) bool { return a.Name < b.Name }
+	case "size":
+		less = func(a, b cacheRow) bool { return a.Size > b.Size }
+	case "modified":
+		less = func(a, b cacheRow) bool { return a.Modified.After(b.Modified) }
+	default:
+		return fmt.Errorf("unknown sort key: %s (use name, size, or modified)", sortBy)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if less(rows[i], rows[j]) {
+			return true
+		}
+		if less(rows[j], rows[i]) {
+			return false
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return nil
+}
+
+func renderCacheInventory(w io.Writer, report cacheReport) {
+	if len(report.Entries) == 0 {
+		fmt.Fprintln(w, "No cache entries found.")
+		return
+	}
+
+	headers := []string{"NAME", "VERSION", "SIZE", "MODIFIED", "DECLARED", "DEPENDENTS"}
+	rows := make([][]string, 0, len(report.Entries))
+	for _, entry := range report.Entries {
+		declared := "no"
+		if entry.Declared {
+			declared = "yes"
+		}
+		dependents := "-"
+		if len(entry.Dependents) > 0 {
+			dependents = strings.Join(entry.Dependents, ",")
+		}
+		rows = append(rows, []string{
+			entry.Name,
+			entry.Version,
+			formatBytes(entry.Size),
+			entry.Modified.Local().Format("2006-01-02 15:04"),
+			declared,
+			dependents,
+		})
+	}
+	renderTable(w, headers, rows)
+}
+
+func renderCacheUsage(w io.Writer, report cacheReport) {
+	if len(report.Entries) == 0 {
+		fmt.Fprintln(w, "No cache entries found.")
+		return
+	}
+
+	headers := []string{"SIZE", "NAME"}
+	rows := make([][]string, 0, len(report.Entries))
+	for _, entry := range report.Entries {
+		rows = append(rows, []string{formatBytes(entry.Size), entry.Name})
+	}
+	renderTable(w, headers, rows)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Total: %s in %d package(s) under %s\n", formatBytes(report.Total), len(report.Entries), report.Directory)
+}
+
+func renderTable(w io.Writer, headers []string, rows [][]string) {
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+
+	writeRow := func(cells []string) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				parts[i] = cell
+				continue
+			}
+			parts[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Fprintln(w, strings.Join(parts, "  "))
+	}
+
+	writeRow(headers)
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
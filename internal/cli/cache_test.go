@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheCommandListsDependencies(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0","dependencies":{"beta":"^2.0.0"}}`)
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), `{"name":"beta","version":"2.0.0"}`)
+	writePackageJSON(t, filepath.Join(cacheDir, "stray"), `{"name":"stray","version":"0.1.0"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := cacheCommand(opts, "ls", "name", true, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected success, got %d: %s", code, stderr.String())
+	}
+
+	var report cacheReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(report.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(report.Entries))
+	}
+	byName := map[string]cacheRow{}
+	for _, row := range report.Entries {
+		byName[row.Name] = row
+	}
+	if !byName["alpha"].Declared || byName["beta"].Declared {
+		t.Fatalf("expected only alpha declared, got %#v", byName)
+	}
+	if got := byName["beta"].Dependents; len(got) != 1 || got[0] != "alpha" {
+		t.Fatalf("expected beta dependents [alpha], got %#v", got)
+	}
+	if len(byName["stray"].Dependents) != 0 {
+		t.Fatalf("expected stray to have no dependents")
+	}
+
+	stdout.Reset()
+	if code := cacheCommand(opts, "du", "size", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected success, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Total:") {
+		t.Fatalf("expected total line, got %q", stdout.String())
+	}
+}
+
+func TestSortCacheRowsRejectsUnknownKey(t *testing.T) {
+	if err := sortCacheRows(nil, "color"); err == nil {
+		t.Fatalf("expected error for unknown sort key")
+	}
+}
+
+func TestRunCacheRequiresSubcommand(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := runCache([]string{}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+	stderr.Reset()
+	if code := runCache([]string{"purge"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "unknown cache subcommand") {
+		t.Fatalf("expected unknown subcommand message, got %q", stderr.String())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:       "0 B",
+		512:     "512 B",
+		1536:    "1.5 KiB",
+		1048576: "1.0 MiB",
+	}
+	for size, want := range cases {
+		if got := formatBytes(size); got != want {
+			t.Fatalf("formatBytes(%d) = %q, want %q", size, got, want)
+		}
+	}
+}
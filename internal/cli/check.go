@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/policy"
+)
+
+type checkReport struct {
+	Sources    []string           `json:"sources"`
+	Violations []policy.Violation `json:"violations"`
+	Unverified []string           `json:"unverified,omitempty"`
+}
+
+func checkCommand(opts CommonOptions, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 2
+	}
+	if pol.Empty() {
+		fmt.Fprintln(stderr, "No policy file found; nothing to check.")
+		return 0
+	}
+
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+	entries := []cache.Entry{}
+	if cacheDir != "" {
+		entries, err = cache.Detect(context.Background(), cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+	}
+	installedByName := map[string]cache.Entry{}
+	for _, entry := range entries {
+		installedByName[entry.Name] = entry
+	}
+
+	report := checkReport{Sources: pol.Sources}
+	plugins := []policy.Plugin{}
+	for _, spec := range effectivePlugins(result.Plugins) {
+		version := effectiveVersion(spec, installedByName)
+		if version == "" && hasConstraint(pol, spec.Name) {
+			report.Unverified = append(report.Unverified, spec.Name)
+		}
+		plugins = append(plugins, policy.Plugin{Name: spec.Name, Version: version})
+	}
+	report.Violations = pol.Evaluate(plugins)
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "failed to encode report: %v\n", err)
+			return 1
+		}
+	} else {
+		renderCheckReport(stdout, report, len(plugins))
+	}
+
+	if len(report.Violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadPolicy merges the global policy with the project policy, or loads only
+// the --policy override when one is given.
+func loadPolicy(opts CommonOptions, result opencode.DiscoveryResult) (policy.Policy, error) {
+	if opts.Policy != "" {
+		if _, err := os.Stat(opts.Policy); err != nil {
+			return policy.Policy{}, fmt.Errorf("%w: %s", policy.ErrPolicyNotFound, opts.Policy)
+		}
+		return policy.Load(opts.Policy)
+	}
+	dir, err := projectDir(opts, result)
+	if err != nil {
+		return policy.Policy{}, err
+	}
+	return policy.Load(policy.ResolveGlobal(), policy.ProjectPath(dir))
+}
+
+// effectiveVersion returns the installed version of a plugin, or its exact
+// pin when it is not installed.
+func effectiveVersion(spec opencode.PluginSpec, installedByName map[string]cache.Entry) string {
+	if entry, ok := installedByName[spec.Name]; ok && !entry.Corrupt() {
+		return entry.Version
+	}
+	if npm.IsExactVersion(spec.Pinned) {
+		return spec.Pinned
+	}
+	return ""
+}
+
+func hasConstraint(pol policy.Policy, name string) bool {
+	for _, constraint := range pol.Constraints {
+		if policy.Match(constraint.Pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// specViolations checks the plugin a spec declares against the policy. Version
+// constraints are only checked when the spec pins an exact version.
+func specViolations(pol policy.Policy, name string, spec string) []policy.Violation {
+	version := ""
+	if at := strings.LastIndex(spec, "@"); at > 0 {
+		if pinned := strings.TrimPrefix(spec[at+1:], "v"); npm.IsExactVersion(pinned) {
+			version = pinned
+		}
+	}
+	return pol.CheckPackage(name, version)
+}
+
+func policyMessages(violations []policy.Violation) string {
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func renderCheckReport(w io.Writer, report checkReport, pluginCount int) {
+	if len(report.Violations) == 0 {
+		fmt.Fprintf(w, "Policy check passed for %d plugin(s).\n", pluginCount)
+	} else {
+		headers := []string{"PLUGIN", "RULE", "MESSAGE"}
+		rows := make([][]string, 0, len(report.Violations))
+		for _, violation := range report.Violations {
+			rows = append(rows, []string{violation.Plugin, violation.Rule, violation.Message})
+		}
+		renderTable(w, headers, rows)
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "%d policy violation(s).\n", len(report.Violations))
+	}
+
+	if len(report.Unverified) > 0 {
+		fmt.Fprintf(w, "Note: could not check version constraints for %s; version is unknown.\n", strings.Join(report.Unverified, ", "))
+	}
+	fmt.Fprintf(w, "Policy: %s\n", strings.Join(report.Sources, ", "))
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/policy"
+)
+
+func writePolicyFile(t *testing.T, root string, content string) string {
+	t.Helper()
+	path := policy.ProjectPath(root)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	return path
+}
+
+func TestCheckCommandReportsViolations(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	config := `{"plugin": ["alpha", "blocked-plugin@1.0.0"]}`
+	if err := os.WriteFile(filepath.Join(root, "opencode.json"), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"3.1.0"}`)
+	writePolicyFile(t, root, `{"deny":["blocked-*"],"require":["@acme/audit"],"constraints":{"alpha":"<3"}}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := checkCommand(opts, true, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d (stderr: %s)", code, stderr.String())
+	}
+
+	var report checkReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	rules := map[string]string{}
+	for _, violation := range report.Violations {
+		rules[violation.Plugin] = violation.Rule
+	}
+	if rules["alpha"] != policy.RuleConstraint {
+		t.Fatalf("expected constraint violation for alpha, got %+v", report.Violations)
+	}
+	if rules["blocked-plugin"] != policy.RuleDenied {
+		t.Fatalf("expected denied violation for blocked-plugin, got %+v", report.Violations)
+	}
+	if rules["@acme/audit"] != policy.RuleRequired {
+		t.Fatalf("expected required violation for @acme/audit, got %+v", report.Violations)
+	}
+}
+
+func TestCheckCommandPasses(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	config := `{"plugin": ["alpha@2.0.0"]}`
+	if err := os.WriteFile(filepath.Join(root, "opencode.json"), []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	policyPath := filepath.Join(root, "org-policy.json")
+	if err := os.WriteFile(policyPath, []byte(`{"allow":["alpha"],"constraints":{"alpha":"^2"}}`), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"2.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, Policy: policyPath}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := checkCommand(opts, false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected exit code 0, got %d (stdout: %s, stderr: %s)", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Policy check passed") {
+		t.Fatalf("expected pass message, got %s", stdout.String())
+	}
+}
+
+func TestCheckCommandMissingExplicitPolicy(t *testing.T) {
+	root := t.TempDir()
+	opts := CommonOptions{ProjectRoot: root, Policy: filepath.Join(root, "missing.json")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := checkCommand(opts, false, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit code 2, got %d", code)
+	}
+}
+
+func TestUpgradeCommandRefusesPolicyViolation(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	config := `{"plugin": ["alpha@1.0.0"]}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	writePolicyFile(t, root, `{"constraints":{"alpha":"<3"}}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{
+		ProjectRoot: root,
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "snapshots"),
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := upgradeCommand(opts, "alpha", "3.0.0", "", false, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "refusing to upgrade alpha to 3.0.0") {
+		t.Fatalf("expected refusal message, got %s", stderr.String())
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alpha@1.0.0") {
+		t.Fatalf("expected config to be unchanged, got %s", string(data))
+	}
+}
+
+func TestUpgradeCommandLeavesDependenciesOutOfPolicy(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	manifest := filepath.Join(root, ".opencode", "package.json")
+	writeTestFile(t, manifest, "{\n  \"dependencies\": {\n    \"@opencode-ai/plugin\": \"0.5.1\"\n  }\n}\n")
+	writePolicyFile(t, root, `{"allow":["alpha"]}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: filepath.Join(root, "cache"), SnapshotDir: filepath.Join(root, "snapshots")}
+	if err := os.MkdirAll(opts.CacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "@opencode-ai/plugin", "0.6.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected the dependency to upgrade despite the allow list, got %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if !strings.Contains(string(data), `"@opencode-ai/plugin": "0.6.0"`) {
+		t.Fatalf("expected updated dependency, got %s", data)
+	}
+}
+
+func TestPolicyBlocksRollbackRestoreAndLink(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	configPath := filepath.Join(root, "opencode.json")
+	writeTestFile(t, configPath, `{"plugin": ["alpha@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	if code := upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+	writePolicyFile(t, root, `{"constraints":{"alpha":">=2"}}`)
+
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected rollback to be refused, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "refusing to roll back alpha to alpha@1.0.0") {
+		t.Fatalf("expected rollback refusal, got %s", stderr.String())
+	}
+
+	stderr.Reset()
+	if code := restoreCommand(opts, "before", nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected restore to be refused, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "refusing to restore alpha to alpha@1.0.0") {
+		t.Fatalf("expected restore refusal, got %s", stderr.String())
+	}
+
+	checkout := filepath.Join(root, "src", "alpha")
+	writePackageJSON(t, checkout, `{"name":"alpha","version":"1.1.0-dev"}`)
+	stderr.Reset()
+	if code := linkCommand(opts, "alpha", checkout, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected link to be refused, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "refusing to link alpha to "+checkout) {
+		t.Fatalf("expected link refusal, got %s", stderr.String())
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), `"alpha@2.0.0"`) {
+		t.Fatalf("expected config to be unchanged, got %s", data)
+	}
+}
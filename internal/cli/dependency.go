@@ -0,0 +1,17 @@
+package cli
+
+import (
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+// dependencyEntry describes the install of a dependency next to the
+// package.json that declares it, in place of a cache entry. It reports false
+// when the dependency is not installed.
+func dependencyEntry(spec opencode.PluginSpec) (cache.Entry, bool) {
+	version, ok := opencode.InstalledDependency(spec)
+	if !ok {
+		return cache.Entry{}, false
+	}
+	return cache.Entry{Name: spec.Name, Version: version, Path: opencode.DependencyDir(spec)}, true
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+func TestUpgradeAndRollbackConfigDirDependency(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	manifest := filepath.Join(root, ".opencode", "package.json")
+	writeTestFile(t, manifest, "{\n  \"dependencies\": {\n    \"@opencode-ai/plugin\": \"0.5.1\"\n  }\n}\n")
+	writePackageJSON(t, filepath.Join(root, ".opencode", "node_modules", "@opencode-ai", "plugin"), `{"name":"@opencode-ai/plugin","version":"0.5.1"}`)
+
+	cacheDir := filepath.Join(root, "cache")
+	cached := filepath.Join(cacheDir, "sdk-cache")
+	writePackageJSON(t, cached, `{"name":"@opencode-ai/plugin","version":"0.4.0"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "@opencode-ai/plugin", "0.6.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if !strings.Contains(string(data), `"@opencode-ai/plugin": "0.6.0"`) {
+		t.Fatalf("expected updated dependency, got %s", data)
+	}
+	if _, err := os.Stat(cached); err != nil {
+		t.Fatalf("expected plugin cache to be left alone: %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := rollbackCommand(opts, "@opencode-ai/plugin", rollbackOptions{}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	data, err = os.ReadFile(manifest)
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if !strings.Contains(string(data), `"@opencode-ai/plugin": "0.5.1"`) {
+		t.Fatalf("expected restored dependency, got %s", data)
+	}
+}
+
+func TestBuildSyncPlanRemovesMismatchedDependencies(t *testing.T) {
+	root := t.TempDir()
+	manifest := filepath.Join(root, "package.json")
+	writePackageJSON(t, filepath.Join(root, "node_modules", "zod"), `{"name":"zod","version":"3.21.0"}`)
+	specs := []opencode.PluginSpec{
+		{Name: "zod", DeclaredSpec: "zod@3.22.0", Pinned: "3.22.0", Source: opencode.SourceDependency, ConfigPath: manifest},
+	}
+
+	plan := buildSyncPlan(specs, nil)
+	if len(plan.RefreshTargets) != 0 || len(plan.DependencyTargets) != 1 {
+		t.Fatalf("expected a dependency target only, got %+v", plan)
+	}
+	row := findSyncRow(plan.Rows, "zod")
+	if row.Installed != "3.21.0" || row.Status != string(model.StatusMismatch) {
+		t.Fatalf("expected zod mismatch, got %+v", row)
+	}
+
+	plugins := buildPluginList(specs, nil)
+	if plugins[0].Status != model.StatusMismatch || plugins[0].Installed != "3.21.0" {
+		t.Fatalf("expected list to show the installed dependency, got %+v", plugins[0])
+	}
+}
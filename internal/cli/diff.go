@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// lineDiff returns a line-based diff from before to after using the longest
+// common subsequence. Ops are ' ', '-' and '+'.
+func lineDiff(before string, after string) []diffLine {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []diffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{Op: ' ', Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{Op: '-', Text: a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{Op: '+', Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{Op: '-', Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{Op: '+', Text: b[j]})
+	}
+	return lines
+}
+
+// renderDiff prints changed lines with a few lines of context around them.
+func renderDiff(w io.Writer, fromLabel string, toLabel string, lines []diffLine) {
+	fmt.Fprintf(w, "--- %s\n", fromLabel)
+	fmt.Fprintf(w, "+++ %s\n", toLabel)
+
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == ' ' {
+			continue
+		}
+		for k := i - diffContext; k <= i+diffContext; k++ {
+			if k >= 0 && k < len(lines) {
+				show[k] = true
+			}
+		}
+	}
+
+	skipped := false
+	for i, line := range lines {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			fmt.Fprintln(w, "...")
+			skipped = false
+		}
+		fmt.Fprintf(w, "%c %s\n", line.Op, line.Text)
+	}
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	lines := lineDiff("a\nb\nc\n", "a\nB\nc\nd\n")
+	got := []string{}
+	for _, line := range lines {
+		got = append(got, string(line.Op)+line.Text)
+	}
+	want := []string{" a", "-b", "+B", " c", "+d"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRenderDiffTrimsContext(t *testing.T) {
+	before := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	after := "1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+	var out bytes.Buffer
+	renderDiff(&out, "old", "new", lineDiff(before, after))
+	text := out.String()
+	if strings.Contains(text, "  1\n") || !strings.Contains(text, "...") {
+		t.Fatalf("expected leading context to be trimmed, got %s", text)
+	}
+	if !strings.Contains(text, "- 10\n") || !strings.Contains(text, "+ ten\n") {
+		t.Fatalf("expected changed lines, got %s", text)
+	}
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+// disableCommand removes a plugin from every config that declares it. The
+// entries are kept in each config's sidecar so enable can put them back.
+func disableCommand(opts CommonOptions, name string, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	warnDiscovery(stderr, result)
+
+	paths := []string{}
+	seen := map[string]struct{}{}
+	local := ""
+	for _, spec := range result.Plugins {
+		if spec.Name != name {
+			continue
+		}
+		if spec.Source == opencode.SourceLocal {
+			local = spec.LocalPath
+			continue
+		}
+		if spec.Source == opencode.SourceDependency {
+			continue
+		}
+		if _, ok := seen[spec.ConfigPath]; ok {
+			continue
+		}
+		seen[spec.ConfigPath] = struct{}{}
+		paths = append(paths, spec.ConfigPath)
+	}
+	if len(paths) == 0 {
+		switch {
+		case local != "":
+			fmt.Fprintf(stderr, "%s is a local plugin loaded from %s; move the file out of the plugin directory to disable it\n", name, local)
+		case hasPlugin(result.Disabled, name):
+			fmt.Fprintf(stderr, "%s is already disabled\n", name)
+		default:
+			fmt.Fprintf(stderr, "plugin not found: %s\n", name)
+		}
+		return 1
+	}
+
+	disabled := 0
+	failed := false
+	for _, path := range paths {
+		if opencode.IsInlineConfig(path) {
+			fmt.Fprintf(stderr, "%s is declared in OPENCODE_CONFIG_CONTENT, which patchline cannot edit; remove it from the variable to disable it\n", name)
+			failed = true
+			continue
+		}
+		entries, err := opencode.DisablePlugin(path, name)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to disable %s in %s: %v\n", name, path, err)
+			return 1
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(stdout, "Disabled %s in %s (was %q at position %d).\n", name, path, entry.Entry, entry.Index+1)
+		}
+		disabled++
+	}
+	if disabled > 0 {
+		fmt.Fprintf(stdout, "Run `patchline enable %s` to restore it.\n", name)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// enableCommand puts the disabled entries of a plugin back into each config.
+func enableCommand(opts CommonOptions, name string, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	warnDiscovery(stderr, result)
+
+	paths := []string{}
+	seen := map[string]struct{}{}
+	for _, spec := range result.Disabled {
+		if spec.Name != name {
+			continue
+		}
+		if _, ok := seen[spec.ConfigPath]; ok {
+			continue
+		}
+		seen[spec.ConfigPath] = struct{}{}
+		paths = append(paths, spec.ConfigPath)
+	}
+	if len(paths) == 0 {
+		if hasPlugin(result.Plugins, name) {
+			fmt.Fprintf(stderr, "%s is not disabled\n", name)
+		} else {
+			fmt.Fprintf(stderr, "plugin not found: %s\n", name)
+		}
+		return 1
+	}
+
+	for _, path := range paths {
+		entries, err := opencode.EnablePlugin(path, name)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to enable %s in %s: %v\n", name, path, err)
+			return 1
+		}
+		for _, entry := range entries {
+			fmt.Fprintf(stdout, "Enabled %s in %s (%q at position %d).\n", name, path, entry.Entry, entry.Index+1)
+		}
+	}
+	return 0
+}
+
+func hasPlugin(specs []opencode.PluginSpec, name string) bool {
+	for _, spec := range specs {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// appendDisabledPlugins adds the disabled plugins to a list built by
+// buildPluginList, keeping it sorted.
+func appendDisabledPlugins(plugins []model.Plugin, specs []opencode.PluginSpec) []model.Plugin {
+	if len(specs) == 0 {
+		return plugins
+	}
+	for _, spec := range specs {
+		plugins = append(plugins, model.Plugin{
+			Name:         spec.Name,
+			DeclaredSpec: spec.DeclaredSpec,
+			Installed:    "-",
+			Status:       model.StatusDisabled,
+			Source:       string(spec.Source),
+			ConfigPath:   spec.ConfigPath,
+			Template:     spec.Template,
+		})
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		if plugins[i].Name == plugins[j].Name {
+			return plugins[i].Source < plugins[j].Source
+		}
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+func printDisabledHint(w io.Writer, plugins []model.Plugin) {
+	names := []string{}
+	for _, plugin := range plugins {
+		if plugin.Status == model.StatusDisabled {
+			names = append(names, plugin.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	names = uniqueNames(names)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Disabled plugins: %s. Run `patchline enable <plugin>` to restore them.\n", strings.Join(names, ", "))
+}
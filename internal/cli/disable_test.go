@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+func TestDisableShowsInListAndEnableRestores(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	globalPath := filepath.Join(root, "xdg", "opencode", "opencode.json")
+	writeTestFile(t, globalPath, `{"plugin": ["alpha@0.9.0"]}`)
+	projectPath := filepath.Join(root, "project", "opencode.json")
+	writeTestFile(t, projectPath, `{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`)
+
+	opts := CommonOptions{ProjectRoot: filepath.Join(root, "project"), SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := disableCommand(opts, "alpha", &stdout, &stderr); code != 0 {
+		t.Fatalf("disable failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(projectPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(data), "alpha") {
+		t.Fatalf("expected alpha removed from project config, got %s", data)
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(result.Plugins) != 1 || len(result.Disabled) != 2 {
+		t.Fatalf("expected beta active and alpha disabled twice, got %+v %+v", result.Plugins, result.Disabled)
+	}
+	plugins := appendDisabledPlugins(buildPluginList(result.Plugins, nil), result.Disabled)
+	if plugins[0].Name != "alpha" || plugins[0].Status != model.StatusDisabled {
+		t.Fatalf("expected disabled alpha first, got %+v", plugins[0])
+	}
+	var hints bytes.Buffer
+	printListHints(&hints, plugins, false)
+	if !strings.Contains(hints.String(), "Disabled plugins: alpha.") {
+		t.Fatalf("expected disabled hint, got %s", hints.String())
+	}
+
+	stderr.Reset()
+	if code := disableCommand(opts, "alpha", &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "alpha is already disabled") {
+		t.Fatalf("expected already disabled error, got %d %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	if code := enableCommand(opts, "alpha", &stdout, &stderr); code != 0 {
+		t.Fatalf("enable failed: %d %s", code, stderr.String())
+	}
+	result, err = opencode.Discover(opts.ProjectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(result.Disabled) != 0 || len(result.Plugins) != 3 || result.Plugins[1].DeclaredSpec != "alpha@1.0.0" {
+		t.Fatalf("expected every declaration restored, got %+v", result.Plugins)
+	}
+}
@@ -33,7 +33,7 @@ func TestSnapshotUpgradeRollbackFlow(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	if code := snapshotCommand(opts, &stdout, &stderr); code != 0 {
+	if code := snapshotCommand(opts, "", &stdout, &stderr); code != 0 {
 		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
 	}
 
@@ -69,7 +69,7 @@ func TestSnapshotUpgradeRollbackFlow(t *testing.T) {
 
 	stdout.Reset()
 	stderr.Reset()
-	if code := rollbackCommand(opts, "alpha", &stdout, &stderr); code != 0 {
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 0 {
 		t.Fatalf("rollback failed: %d %s", code, stderr.String())
 	}
 
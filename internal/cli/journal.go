@@ -0,0 +1,507 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/journal"
+	"github.com/AksharP5/Patchline/internal/lockfile"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+// trackedState is what the journal compares before and after a command.
+type trackedState struct {
+	Files    map[string]string
+	Contents map[string][]byte
+	Cache    map[string]cache.Entry
+}
+
+// withJournal runs a mutating command and appends what it changed to the
+// operation journal. Journal failures are reported but never change the
+// command's exit code.
+func withJournal(opts CommonOptions, command []string, stderr io.Writer, run func() int) int {
+	return journalRun(opts, command, "", stderr, run)
+}
+
+func journalRun(opts CommonOptions, command []string, undoes string, stderr io.Writer, run func() int) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		return run()
+	}
+	j := journal.Journal{Directory: journal.DirFor(snapshotDir)}
+
+	start := time.Now().UTC()
+	before, err := captureState(opts, nil)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: operation will not be journaled: %v\n", err)
+		return run()
+	}
+	snapshotsBefore := snapshotIDs(snapshotDir)
+	code := run()
+	after, err := captureState(opts, before.Files)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: failed to record operation: %v\n", err)
+		return code
+	}
+
+	op := journal.Operation{
+		Timestamp: start,
+		User:      currentUser(),
+		Command:   append([]string{toolName}, command...),
+		ExitCode:  code,
+		Undoes:    undoes,
+	}
+	op.Files = diffFiles(before.Files, after.Files)
+	for _, change := range op.Files {
+		if err := storeContent(j, before, change.Path); err != nil {
+			fmt.Fprintf(stderr, "warning: failed to record operation: %v\n", err)
+			return code
+		}
+		if err := storeContent(j, after, change.Path); err != nil {
+			fmt.Fprintf(stderr, "warning: failed to record operation: %v\n", err)
+			return code
+		}
+	}
+	op.Cache = diffCache(before.Cache, after.Cache)
+
+	snapshotsAfter := snapshotIDs(snapshotDir)
+	for id := range snapshotsAfter {
+		if _, ok := snapshotsBefore[id]; !ok {
+			op.Snapshots = append(op.Snapshots, id)
+		}
+	}
+	for id := range snapshotsBefore {
+		if _, ok := snapshotsAfter[id]; !ok {
+			op.Pruned = append(op.Pruned, id)
+		}
+	}
+	sort.Strings(op.Snapshots)
+	sort.Strings(op.Pruned)
+
+	if op.Empty() {
+		return code
+	}
+	if _, err := j.Append(op); err != nil {
+		fmt.Fprintf(stderr, "warning: failed to record operation: %v\n", err)
+	}
+	return code
+}
+
+// snapshotIDs returns the ids of every snapshot entry in the store, or nil
+// when it cannot be read.
+func snapshotIDs(snapshotDir string) map[string]struct{} {
+	entries, err := snapshot.Store{Directory: snapshotDir}.Entries()
+	if err != nil {
+		return nil
+	}
+	ids := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		ids[entry.ID] = struct{}{}
+	}
+	return ids
+}
+
+// captureState hashes every config, lockfile and local plugin file patchline
+// may touch and lists the cache. Paths in extra are always read so files that
+// disappear are noticed.
+func captureState(opts CommonOptions, extra map[string]string) (trackedState, error) {
+	state := trackedState{Files: map[string]string{}, Contents: map[string][]byte{}, Cache: map[string]cache.Entry{}}
+
+	for _, path := range trackedPaths(opts, extra) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				state.Files[path] = ""
+				continue
+			}
+			return state, fmt.Errorf("read %s: %w", path, err)
+		}
+		state.Files[path] = snapshot.HashBytes(data)
+		state.Contents[path] = data
+	}
+
+	if cacheDir, _ := cache.ResolveDir(opts.CacheDir); cacheDir != "" {
+		entries, err := cache.Detect(context.Background(), cacheDir)
+		if err != nil && !os.IsNotExist(err) {
+			return state, err
+		}
+		for _, entry := range entries {
+			state.Cache[entry.Path] = entry
+		}
+	}
+	return state, nil
+}
+
+// storeContent keeps a copy of a changed file so log and undo can use it.
+func storeContent(j journal.Journal, state trackedState, path string) error {
+	data, ok := state.Contents[path]
+	if !ok {
+		return nil
+	}
+	_, err := j.PutContent(data)
+	return err
+}
+
+func trackedPaths(opts CommonOptions, extra map[string]string) []string {
+	seen := map[string]struct{}{}
+	paths := []string{}
+	add := func(path string) {
+		if path == "" || opencode.IsInlineConfig(path) {
+			return
+		}
+		path = filepath.Clean(path)
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+
+	for path := range extra {
+		add(path)
+	}
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err == nil {
+		add(result.ProjectConfig)
+		add(result.GlobalConfig)
+		add(opencode.DisabledPath(result.ProjectConfig))
+		add(opencode.DisabledPath(result.GlobalConfig))
+		for _, spec := range result.Plugins {
+			add(spec.ConfigPath)
+			add(spec.LocalPath)
+			if spec.Source != opencode.SourceLocal && spec.Source != opencode.SourceDependency {
+				add(opencode.DisabledPath(spec.ConfigPath))
+			}
+		}
+		for _, spec := range result.Disabled {
+			add(spec.ConfigPath)
+			add(opencode.DisabledPath(spec.ConfigPath))
+		}
+	}
+	if root, err := projectDir(opts, result); err == nil {
+		for _, config := range opencode.ProjectConfigCandidates(root) {
+			add(config)
+		}
+		add(lockfile.PathFor(root))
+	}
+	if opts.Recursive {
+		if root, err := scanRoot(opts); err == nil {
+			configs, _ := opencode.FindProjects(root)
+			for _, config := range configs {
+				add(config)
+				add(lockfile.PathFor(projectDirOf(config)))
+			}
+		}
+	}
+	if opts.GlobalConfig != "" {
+		add(opts.GlobalConfig)
+	} else {
+		add(opencode.DefaultGlobalConfigPath())
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func diffFiles(before map[string]string, after map[string]string) []journal.FileChange {
+	changes := []journal.FileChange{}
+	for path, hash := range after {
+		if before[path] != hash {
+			changes = append(changes, journal.FileChange{Path: path, Before: before[path], After: hash})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffCache(before map[string]cache.Entry, after map[string]cache.Entry) []journal.CacheChange {
+	changes := []journal.CacheChange{}
+	for path, entry := range before {
+		next, ok := after[path]
+		switch {
+		case !ok:
+			changes = append(changes, journal.CacheChange{Name: entry.Name, Path: path, Action: journal.CacheRemoved, Before: entry.Version})
+		case next.Version != entry.Version:
+			changes = append(changes, journal.CacheChange{Name: entry.Name, Path: path, Action: journal.CacheChanged, Before: entry.Version, After: next.Version})
+		}
+	}
+	for path, entry := range after {
+		if _, ok := before[path]; !ok {
+			changes = append(changes, journal.CacheChange{Name: entry.Name, Path: path, Action: journal.CacheAdded, After: entry.Version})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return "unknown"
+}
+
+func logCommand(opts CommonOptions, id string, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	j := journal.Journal{Directory: journal.DirFor(snapshotDir)}
+
+	ops, err := j.List()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read journal: %v\n", err)
+		return 1
+	}
+	if id != "" {
+		op, err := j.Find(id)
+		if err != nil {
+			fmt.Fprintf(stderr, "no operation matches %s\n", id)
+			return 1
+		}
+		ops = []journal.Operation{op}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(ops); err != nil {
+			fmt.Fprintf(stderr, "failed to encode journal: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if id != "" {
+		renderOperation(stdout, j, ops[0])
+		return 0
+	}
+	if len(ops) == 0 {
+		fmt.Fprintln(stdout, "No operations recorded.")
+		return 0
+	}
+
+	undone := journal.UndoneIDs(ops)
+	headers := []string{"ID", "TIMESTAMP", "USER", "COMMAND", "FILES", "CACHE", "STATUS"}
+	rows := make([][]string, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		status := "ok"
+		switch {
+		case op.Undoes != "":
+			status = "undo of " + op.Undoes
+		case op.ExitCode != 0:
+			status = "exit " + strconv.Itoa(op.ExitCode)
+		}
+		if _, ok := undone[op.ID]; ok {
+			status += ", undone"
+		}
+		rows = append(rows, []string{
+			op.ID,
+			op.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			op.User,
+			strings.Join(op.Command, " "),
+			strconv.Itoa(len(op.Files)),
+			strconv.Itoa(len(op.Cache)),
+			status,
+		})
+	}
+	renderTable(stdout, headers, rows)
+	return 0
+}
+
+func renderOperation(w io.Writer, j journal.Journal, op journal.Operation) {
+	fmt.Fprintf(w, "Operation %s\n", op.ID)
+	fmt.Fprintf(w, "Date:    %s\n", op.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "User:    %s\n", op.User)
+	fmt.Fprintf(w, "Command: %s\n", strings.Join(op.Command, " "))
+	fmt.Fprintf(w, "Exit:    %d\n", op.ExitCode)
+	if op.Undoes != "" {
+		fmt.Fprintf(w, "Undoes:  %s\n", op.Undoes)
+	}
+	if len(op.Snapshots) > 0 {
+		fmt.Fprintf(w, "Snapshots: %s\n", strings.Join(op.Snapshots, ", "))
+	}
+	if len(op.Pruned) > 0 {
+		fmt.Fprintf(w, "Pruned:  %s\n", strings.Join(op.Pruned, ", "))
+	}
+
+	for _, change := range op.Cache {
+		switch change.Action {
+		case journal.CacheChanged:
+			fmt.Fprintf(w, "Cache %s: %s (%s -> %s)\n", change.Action, change.Path, change.Before, change.After)
+		default:
+			fmt.Fprintf(w, "Cache %s: %s\n", change.Action, change.Path)
+		}
+	}
+
+	for _, change := range op.Files {
+		fmt.Fprintln(w, "")
+		before, _ := fileContent(j, change.Before)
+		after, _ := fileContent(j, change.After)
+		renderDiff(w, "a/"+change.Path, "b/"+change.Path, lineDiff(string(before), string(after)))
+	}
+}
+
+func fileContent(j journal.Journal, hash string) ([]byte, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	return j.Content(hash)
+}
+
+// undoCommand restores every file an operation changed to its earlier
+// content. It refuses when a file changed since the operation unless forced.
+func undoCommand(opts CommonOptions, id string, force bool, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	j := journal.Journal{Directory: journal.DirFor(snapshotDir)}
+
+	var op journal.Operation
+	var err error
+	if id == "" {
+		op, err = j.LastUndoable()
+	} else {
+		op, err = j.Find(id)
+	}
+	if err != nil {
+		if id == "" {
+			fmt.Fprintln(stderr, "no operation to undo")
+		} else {
+			fmt.Fprintf(stderr, "no operation matches %s\n", id)
+		}
+		return 1
+	}
+	ops, err := j.List()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read journal: %v\n", err)
+		return 1
+	}
+	if _, ok := journal.UndoneIDs(ops)[op.ID]; ok {
+		fmt.Fprintf(stderr, "%v: %s\n", journal.ErrAlreadyUndone, op.ID)
+		return 1
+	}
+	if len(op.Files) == 0 {
+		fmt.Fprintf(stderr, "operation %s changed no files; nothing to undo\n", op.ID)
+		return 1
+	}
+
+	conflicts := 0
+	for _, change := range op.Files {
+		current := ""
+		if data, err := os.ReadFile(change.Path); err == nil {
+			current = snapshot.HashBytes(data)
+		} else if !os.IsNotExist(err) {
+			fmt.Fprintf(stderr, "failed to read %s: %v\n", change.Path, err)
+			return 1
+		}
+		if current != change.After {
+			fmt.Fprintf(stderr, "%s changed after operation %s\n", change.Path, op.ID)
+			conflicts++
+		}
+	}
+	if conflicts > 0 && !force {
+		fmt.Fprintln(stderr, "nothing was undone; re-run with --force to overwrite")
+		return 1
+	}
+
+	command := []string{"undo", op.ID}
+	return journalRun(opts, command, op.ID, stderr, func() int {
+		return applyUndo(opts, j, op, stdout, stderr)
+	})
+}
+
+func applyUndo(opts CommonOptions, j journal.Journal, op journal.Operation, stdout io.Writer, stderr io.Writer) int {
+	invalidate := map[string]struct{}{}
+	for _, change := range op.Files {
+		after, _ := fileContent(j, change.After)
+		if change.Before == "" {
+			if err := os.Remove(change.Path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(stderr, "failed to remove %s: %v\n", change.Path, err)
+				return 1
+			}
+			for _, name := range changedPlugins(change.Path, after, nil) {
+				invalidate[name] = struct{}{}
+			}
+			continue
+		}
+
+		before, err := j.Content(change.Before)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to load %s: %v\n", change.Path, err)
+			return 1
+		}
+		if err := os.MkdirAll(filepath.Dir(change.Path), 0o755); err != nil {
+			fmt.Fprintf(stderr, "failed to restore %s: %v\n", change.Path, err)
+			return 1
+		}
+		if err := atomicfile.Write(change.Path, before, 0o600); err != nil {
+			fmt.Fprintf(stderr, "failed to restore %s: %v\n", change.Path, err)
+			return 1
+		}
+		for _, name := range changedPlugins(change.Path, after, before) {
+			invalidate[name] = struct{}{}
+		}
+	}
+
+	if cacheDir, _ := cache.ResolveDir(opts.CacheDir); cacheDir != "" {
+		names := make([]string, 0, len(invalidate))
+		for name := range invalidate {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, err := cache.Invalidate(context.Background(), cacheDir, name); err != nil {
+				fmt.Fprintf(stderr, "failed to invalidate cache for %s: %v\n", name, err)
+				return 1
+			}
+		}
+	}
+
+	// The snapshots the operation saved describe states that were just
+	// undone, so rolling back to them would be misleading.
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	removedSnapshots, err := snapshot.Store{Directory: snapshotDir}.Remove(op.Snapshots)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to remove the operation's snapshots: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Undid operation %s (%s): restored %d file(s).\n", op.ID, strings.Join(op.Command, " "), len(op.Files))
+	if removedSnapshots > 0 {
+		fmt.Fprintf(stdout, "Removed %d snapshot(s) the operation saved.\n", removedSnapshots)
+	}
+	removed := 0
+	for _, change := range op.Cache {
+		if change.Action == journal.CacheRemoved {
+			removed++
+		}
+	}
+	if removed > 0 {
+		fmt.Fprintf(stdout, "%d cache entr(ies) removed by the operation will be reinstalled by OpenCode.\n", removed)
+	}
+	return 0
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/journal"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+func setupJournal(t *testing.T, config string) (CommonOptions, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	project := filepath.Join(root, "project")
+	configPath := filepath.Join(project, "opencode.json")
+	writeTestFile(t, configPath, config)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: project, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "data", "snapshots")}
+	return opts, configPath
+}
+
+func TestJournalRecordsUpgradeAndUndoRestores(t *testing.T) {
+	original := "{\n  // pinned\n  \"plugin\": [\"alpha@1.0.0\"]\n}\n"
+	opts, configPath := setupJournal(t, original)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := withJournal(opts, []string{"upgrade", "alpha", "--to", "2.0.0"}, &stderr, func() int {
+		return upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr)
+	})
+	if code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+
+	j := journal.Journal{Directory: journal.DirFor(opts.SnapshotDir)}
+	ops, err := j.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("expected one operation, got %d", len(ops))
+	}
+	op := ops[0]
+	if len(op.Files) != 1 || op.Files[0].Path != configPath {
+		t.Fatalf("expected config change, got %#v", op.Files)
+	}
+	if len(op.Cache) != 1 || op.Cache[0].Action != journal.CacheRemoved {
+		t.Fatalf("expected cache removal, got %#v", op.Cache)
+	}
+	if len(op.Snapshots) != 1 {
+		t.Fatalf("expected snapshot id, got %#v", op.Snapshots)
+	}
+
+	stdout.Reset()
+	if code := logCommand(opts, "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("log failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), op.ID) || !strings.Contains(stdout.String(), "patchline upgrade alpha") {
+		t.Fatalf("expected operation in log, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := undoCommand(opts, "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("undo failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(data) != original {
+		t.Fatalf("expected original config, got %q", string(data))
+	}
+
+	if !strings.Contains(stdout.String(), "Removed 1 snapshot(s) the operation saved.") {
+		t.Fatalf("expected the upgrade's snapshot to be removed, got %s", stdout.String())
+	}
+	store := snapshot.Store{Directory: opts.SnapshotDir}
+	if _, err := store.Find("alpha", op.Snapshots[0]); !errors.Is(err, snapshot.ErrSnapshotNotFound) {
+		t.Fatalf("expected snapshot %s to be gone, got %v", op.Snapshots[0], err)
+	}
+
+	ops, _ = j.List()
+	if len(ops) != 2 || ops[1].Undoes != op.ID {
+		t.Fatalf("expected undo to be journaled, got %#v", ops)
+	}
+	if len(ops[1].Pruned) != 1 || ops[1].Pruned[0] != op.Snapshots[0] {
+		t.Fatalf("expected the undo to record the removed snapshot, got %#v", ops[1].Pruned)
+	}
+	stderr.Reset()
+	if code := undoCommand(opts, op.ID, false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected second undo to be refused, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "already undone") {
+		t.Fatalf("expected already undone message, got %s", stderr.String())
+	}
+}
+
+func TestUndoRefusesChangedFilesWithoutForce(t *testing.T) {
+	opts, configPath := setupJournal(t, `{"plugin": ["alpha@1.0.0"]}`)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	withJournal(opts, []string{"upgrade", "alpha"}, &stderr, func() int {
+		return upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr)
+	})
+	edited := `{"plugin": ["alpha@3.0.0"]}`
+	writeTestFile(t, configPath, edited)
+
+	if code := undoCommand(opts, "", false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected conflict, got %d", code)
+	}
+	data, _ := os.ReadFile(configPath)
+	if string(data) != edited {
+		t.Fatalf("expected config untouched, got %s", string(data))
+	}
+
+	stderr.Reset()
+	if code := undoCommand(opts, "", true, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected forced undo, got %d %s", code, stderr.String())
+	}
+	data, _ = os.ReadFile(configPath)
+	if string(data) != `{"plugin": ["alpha@1.0.0"]}` {
+		t.Fatalf("expected original config, got %s", string(data))
+	}
+}
+
+func TestJournalRecordsSnapshotCommandsInsideSnapshotDir(t *testing.T) {
+	opts, _ := setupJournal(t, `{"plugin": ["alpha@1.0.0"]}`)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if code := runSnapshot([]string{"--snapshot-dir", opts.SnapshotDir, "--project", opts.ProjectRoot, "--cache-dir", opts.CacheDir}, &stdout, &stderr); code != 0 {
+			t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+		}
+	}
+	if code := runSnapshot([]string{"prune", "--keep-last", "1", "--snapshot-dir", opts.SnapshotDir, "--project", opts.ProjectRoot, "--cache-dir", opts.CacheDir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("prune failed: %d %s", code, stderr.String())
+	}
+
+	j := journal.Journal{Directory: journal.DirFor(opts.SnapshotDir)}
+	if filepath.Dir(j.Directory) != opts.SnapshotDir {
+		t.Fatalf("expected the journal inside the snapshot dir, got %s", j.Directory)
+	}
+	ops, err := j.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ops) != 3 || len(ops[0].Snapshots) != 1 || len(ops[2].Pruned) != 1 || ops[2].Pruned[0] != ops[0].Snapshots[0] {
+		t.Fatalf("expected two snapshots and a prune of the first, got %#v", ops)
+	}
+
+	stderr.Reset()
+	if code := undoCommand(opts, "", false, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "no operation to undo") {
+		t.Fatalf("expected snapshot-only operations to be skipped by undo, got %d %s", code, stderr.String())
+	}
+}
+
+func TestTrackedPathsIncludeEveryProjectConfigName(t *testing.T) {
+	opts, _ := setupJournal(t, `{"plugin": []}`)
+
+	paths := trackedPaths(opts, nil)
+	for _, want := range []string{
+		filepath.Join(opts.ProjectRoot, "opencode.jsonc"),
+		filepath.Join(opts.ProjectRoot, ".opencode.json"),
+		filepath.Join(opts.ProjectRoot, ".opencode", "opencode.jsonc"),
+		filepath.Join(opts.ProjectRoot, ".opencode", "opencode.json"),
+	} {
+		if !slices.Contains(paths, want) {
+			t.Fatalf("expected %s to be tracked, got %v", want, paths)
+		}
+	}
+}
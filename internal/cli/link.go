@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+// linkCommand replaces the declared npm spec of a plugin with a file:// entry
+// for a local checkout. The original spec is saved as a snapshot entry with
+// the reason "link", which unlink restores.
+func linkCommand(opts CommonOptions, name string, path string, stdout io.Writer, stderr io.Writer) int {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve %s: %v\n", path, err)
+		return 1
+	}
+	if !isDir(dir) {
+		fmt.Fprintf(stderr, "link target must be a plugin checkout directory: %s\n", dir)
+		return 1
+	}
+	packageName, version, err := opencode.LinkedPackage(dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read package.json in %s: %v\n", dir, err)
+		return 1
+	}
+	if packageName != name {
+		fmt.Fprintf(stderr, "%s declares package %q, not %s\n", dir, packageName, name)
+		return 1
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	warnDiscovery(stderr, result)
+
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 1
+	}
+	if violations := pol.CheckPackage(name, version); len(violations) > 0 {
+		fmt.Fprintf(stderr, "refusing to link %s to %s: %s\n", name, dir, policyMessages(violations))
+		return 1
+	}
+
+	if linkPath, ok := linkedSpec(result.Plugins, name); ok {
+		fmt.Fprintf(stderr, "%s is already linked to %s; run `patchline unlink %s` first\n", name, linkPath, name)
+		return 1
+	}
+
+	targets := []upgradeTarget{}
+	for _, target := range selectUpgradeTargets(result.Plugins, name, false) {
+		if target.Source != string(opencode.SourceDependency) {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		fmt.Fprintf(stderr, "plugin not found: %s\n", name)
+		return 1
+	}
+	for _, target := range targets {
+		if target.Template != "" && !opts.Flatten {
+			printTemplatedRefusal(stderr, name, target.Template, target.ConfigPath)
+			return 1
+		}
+	}
+
+	store, ok := openSnapshotStore(opts, stderr)
+	if !ok {
+		return 1
+	}
+
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+	installed, err := installedVersion(context.Background(), cacheDir, name)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+		return 1
+	}
+
+	spec := opencode.LinkSpec(dir)
+	var inline inlineUpdates
+	for _, target := range targets {
+		// The link record is saved for inline targets too, so unlink can
+		// restore the spec once the printed value has been applied.
+		_, err := store.Save(snapshot.Entry{
+			PluginName:        name,
+			PreviousSpec:      target.Declared,
+			Template:          target.Template,
+			PreviousInstalled: installed,
+			Source:            target.Source,
+			Reason:            "link",
+			ConfigPath:        target.ConfigPath,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", name, err)
+			return 1
+		}
+		if opencode.IsInlineConfig(target.ConfigPath) {
+			if err := inline.add(opts, name, spec); err != nil {
+				fmt.Fprintf(stderr, "failed to update config for %s: %v\n", name, err)
+				return 1
+			}
+			continue
+		}
+		if err := updatePluginSpec(opts, target.ConfigPath, name, spec); err != nil {
+			fmt.Fprintf(stderr, "failed to update config for %s: %v\n", name, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Linked %s to %s in %s (was %s).\n", name, dir, target.ConfigPath, target.Declared)
+	}
+	if inline.report(stdout) {
+		return 1
+	}
+	fmt.Fprintf(stdout, "Run OpenCode to load the checkout; run `patchline unlink %s` to restore the npm spec.\n", name)
+	return 0
+}
+
+// unlinkCommand restores the npm spec a plugin had before it was linked.
+func unlinkCommand(opts CommonOptions, name string, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	warnDiscovery(stderr, result)
+
+	linked := []opencode.PluginSpec{}
+	declared := false
+	seen := map[string]struct{}{}
+	for _, spec := range result.Plugins {
+		if spec.Name != name {
+			continue
+		}
+		declared = true
+		if spec.LinkPath == "" {
+			continue
+		}
+		if _, ok := seen[spec.ConfigPath]; ok {
+			continue
+		}
+		seen[spec.ConfigPath] = struct{}{}
+		linked = append(linked, spec)
+	}
+	if len(linked) == 0 {
+		if declared {
+			fmt.Fprintf(stderr, "%s is not linked\n", name)
+		} else {
+			fmt.Fprintf(stderr, "plugin not found: %s\n", name)
+		}
+		return 1
+	}
+
+	store, ok := openSnapshotStore(opts, stderr)
+	if !ok {
+		return 1
+	}
+
+	var inline inlineUpdates
+	for _, spec := range linked {
+		record, ok := lastLinkRecord(store, name, spec.ConfigPath)
+		if !ok {
+			fmt.Fprintf(stderr, "no link record for %s in %s; edit the config to restore its npm spec\n", name, spec.ConfigPath)
+			return 1
+		}
+		restored := record.PreviousSpec
+		if record.Template != "" {
+			restored = record.Template
+		}
+		if opencode.IsInlineConfig(spec.ConfigPath) {
+			if err := inline.add(opts, name, restored); err != nil {
+				fmt.Fprintf(stderr, "failed to update config for %s: %v\n", name, err)
+				return 1
+			}
+			continue
+		}
+		_, err := store.Save(snapshot.Entry{
+			PluginName:        name,
+			PreviousSpec:      spec.DeclaredSpec,
+			PreviousInstalled: "local",
+			Source:            string(spec.Source),
+			Reason:            "unlink",
+			ConfigPath:        spec.ConfigPath,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", name, err)
+			return 1
+		}
+		if err := updatePluginSpec(opts, spec.ConfigPath, name, restored); err != nil {
+			fmt.Fprintf(stderr, "failed to update config for %s: %v\n", name, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Unlinked %s in %s; restored %s.\n", name, spec.ConfigPath, restored)
+	}
+	if inline.report(stdout) {
+		return 1
+	}
+	fmt.Fprintln(stdout, "Run OpenCode to reinstall.")
+	return 0
+}
+
+// lastLinkRecord returns the newest link entry for a plugin in one config.
+func lastLinkRecord(store snapshot.Store, name string, configPath string) (snapshot.Entry, bool) {
+	entries, err := store.History(name)
+	if err != nil {
+		return snapshot.Entry{}, false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Reason != "link" {
+			continue
+		}
+		if filepath.Clean(store.Roots.Resolve(entry)) == filepath.Clean(configPath) {
+			return entry, true
+		}
+	}
+	return snapshot.Entry{}, false
+}
+
+func openSnapshotStore(opts CommonOptions, stderr io.Writer) (snapshot.Store, bool) {
+	snapshotDir, candidates := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return snapshot.Store{}, false
+	}
+	if opts.SnapshotDir == "" && len(candidates) > 0 {
+		fmt.Fprintf(stderr, "Using snapshot directory: %s\n", snapshotDir)
+	}
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return snapshot.Store{}, false
+	}
+	store.Roots = snapshotRoots(opts, nil)
+	return store, true
+}
+
+func printLinkedHint(w io.Writer, plugins []model.Plugin) {
+	names := []string{}
+	for _, plugin := range plugins {
+		if plugin.Status == model.StatusLinked {
+			names = append(names, plugin.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	names = uniqueNames(names)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Linked to local checkouts: %s. Run `patchline unlink <plugin>` to restore the npm spec.\n", strings.Join(names, ", "))
+}
+
+// linkedSpec reports whether a plugin with the given name is linked, and to
+// which path.
+func linkedSpec(specs []opencode.PluginSpec, name string) (string, bool) {
+	for _, spec := range specs {
+		if spec.Name == name && spec.LinkPath != "" {
+			return spec.LinkPath, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+func TestLinkAndUnlinkRestoreOriginalSpec(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	configPath := filepath.Join(root, "project", "opencode.json")
+	writeTestFile(t, configPath, `{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`)
+	checkout := filepath.Join(root, "src", "alpha")
+	writePackageJSON(t, checkout, `{"name":"alpha","version":"1.1.0-dev"}`)
+
+	opts := CommonOptions{ProjectRoot: filepath.Join(root, "project"), SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := linkCommand(opts, "alpha", checkout, &stdout, &stderr); code != 0 {
+		t.Fatalf("link failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), opencode.LinkSpec(checkout)) || strings.Contains(string(data), "alpha@1.0.0") {
+		t.Fatalf("expected linked entry, got %s", data)
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	plugins := buildPluginList(result.Plugins, nil)
+	if plugins[0].Name != "alpha" || plugins[0].Status != model.StatusLinked || plugins[0].Installed != "local 1.1.0-dev" {
+		t.Fatalf("expected alpha to be linked, got %+v", plugins[0])
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := upgradeCommand(opts, "alpha", "1.2.0", "", false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected upgrade to refuse a linked plugin, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "run `patchline unlink alpha` before upgrading") {
+		t.Fatalf("expected unlink hint, got %s", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := unlinkCommand(opts, "alpha", &stdout, &stderr); code != 0 {
+		t.Fatalf("unlink failed: %d %s", code, stderr.String())
+	}
+	data, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alpha@1.0.0") || strings.Contains(string(data), "file://") {
+		t.Fatalf("expected original spec restored, got %s", data)
+	}
+
+	stderr.Reset()
+	if code := unlinkCommand(opts, "alpha", &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "alpha is not linked") {
+		t.Fatalf("expected not linked error, got %d %s", code, stderr.String())
+	}
+}
+
+func TestLinkAndUnlinkInlineConfig(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	original := `{"plugin":["alpha@1.0.0"]}`
+	t.Setenv("OPENCODE_CONFIG_CONTENT", original)
+	checkout := filepath.Join(root, "src", "alpha")
+	writePackageJSON(t, checkout, `{"name":"alpha","version":"1.1.0-dev"}`)
+
+	opts := CommonOptions{ProjectRoot: filepath.Join(root, "project"), SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := linkCommand(opts, "alpha", checkout, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1 for an unapplied change, got %d: %s", code, stderr.String())
+	}
+	_, linked, ok := strings.Cut(stdout.String(), "Set it to:\n")
+	if !ok || !strings.Contains(linked, opencode.LinkSpec(checkout)) {
+		t.Fatalf("expected the linked value, got %s", stdout.String())
+	}
+	t.Setenv("OPENCODE_CONFIG_CONTENT", strings.TrimSpace(linked))
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := unlinkCommand(opts, "alpha", &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1 for an unapplied change, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Set it to:\n"+original+"\n") {
+		t.Fatalf("expected the original value, got %s", stdout.String())
+	}
+}
+
+func TestLinkRejectsCheckoutOfAnotherPackage(t *testing.T) {
+	root := t.TempDir()
+	checkout := filepath.Join(root, "beta")
+	writePackageJSON(t, checkout, `{"name":"beta"}`)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := linkCommand(CommonOptions{ProjectRoot: root, SnapshotDir: filepath.Join(root, "snapshots")}, "alpha", checkout, &stdout, &stderr)
+	if code != 1 || !strings.Contains(stderr.String(), `declares package "beta", not alpha`) {
+		t.Fatalf("expected package mismatch, got %d %s", code, stderr.String())
+	}
+}
@@ -18,6 +18,7 @@ func listCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
 		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
 		return 1
 	}
+	warnDiscovery(stderr, result)
 
 	cacheDir, candidates := cache.ResolveDir(opts.CacheDir)
 	cacheEntries := []cache.Entry{}
@@ -35,7 +36,10 @@ func listCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
 	}
 
 	plugins := buildPluginList(result.Plugins, cacheEntries)
+	plugins = appendDisabledPlugins(plugins, result.Disabled)
+	markModifiedLocal(plugins, modifiedLocalPlugins(opts, result.Plugins))
 	renderPluginTable(stdout, plugins)
+	printLocalFolderHint(stdout, result.Plugins)
 	printListHints(stdout, plugins, cacheDir == "")
 	return 0
 }
@@ -54,21 +58,48 @@ func buildPluginList(specs []opencode.PluginSpec, cacheEntries []cache.Entry) []
 			Source:       string(spec.Source),
 			ConfigPath:   spec.ConfigPath,
 			CachePath:    "",
+			Template:     spec.Template,
 		}
 
 		if spec.Source == opencode.SourceLocal {
 			plugin.Status = model.StatusUnmanaged
 			plugin.Installed = "local"
+			if spec.Version != "" {
+				plugin.Installed = "local " + spec.Version
+			}
 			plugin.LocalDirectory = spec.LocalPath
+			if spec.LocalDir != "" {
+				plugin.LocalDirectory = spec.LocalDir
+			}
+			plugins = append(plugins, plugin)
+			continue
+		}
+
+		if spec.LinkPath != "" {
+			plugin.Status = model.StatusLinked
+			plugin.Installed = "local"
+			if spec.Version != "" {
+				plugin.Installed = "local " + spec.Version
+			}
+			plugin.LocalDirectory = spec.LinkPath
 			plugins = append(plugins, plugin)
 			continue
 		}
 
 		entry, ok := cacheByName[spec.Name]
+		if spec.Source == opencode.SourceDependency {
+			entry, ok = dependencyEntry(spec)
+		}
 		if ok {
 			plugin.Installed = entry.Version
 			plugin.CachePath = entry.Path
-			if spec.Pinned != "" && entry.Version != spec.Pinned {
+			if entry.Corrupt() {
+				plugin.Status = model.StatusCorrupt
+				plugin.Issues = entry.Issues
+				if plugin.Installed == "" {
+					plugin.Installed = "unknown"
+				}
+			} else if spec.Pinned != "" && entry.Version != spec.Pinned {
 				plugin.Status = model.StatusMismatch
 			} else {
 				plugin.Status = model.StatusOK
@@ -100,12 +131,16 @@ func renderPluginTable(w io.Writer, plugins []model.Plugin) {
 	headers := []string{"NAME", "DECLARED", "INSTALLED", "STATUS", "SOURCE"}
 	rows := make([][]string, 0, len(plugins))
 	for _, plugin := range plugins {
+		source := plugin.Source
+		if plugin.Template != "" {
+			source += " (templated)"
+		}
 		rows = append(rows, []string{
 			plugin.Name,
 			plugin.DeclaredSpec,
 			plugin.Installed,
 			string(plugin.Status),
-			plugin.Source,
+			source,
 		})
 	}
 
@@ -139,15 +174,43 @@ func renderPluginTable(w io.Writer, plugins []model.Plugin) {
 	}
 }
 
+// warnDiscovery reports directories that hold several config variants, since
+// only the highest precedence one is read and edited, and plugin entries whose
+// substitution failed, which are skipped.
+func warnDiscovery(w io.Writer, result opencode.DiscoveryResult) {
+	for _, variant := range result.Variants {
+		fmt.Fprintf(w, "warning: using %s; also found %s, which is ignored\n", variant.Used, strings.Join(variant.Ignored, ", "))
+	}
+	for _, spec := range result.Unexpanded {
+		fmt.Fprintf(w, "warning: skipping %s in %s: %v\n", spec.Template, spec.ConfigPath, spec.TemplateErr)
+	}
+}
+
 func printListHints(w io.Writer, plugins []model.Plugin, cacheMissing bool) {
 	needsSync := false
+	corrupt := []model.Plugin{}
 	for _, plugin := range plugins {
-		if plugin.Status == model.StatusMissing || plugin.Status == model.StatusMismatch {
+		switch plugin.Status {
+		case model.StatusMissing, model.StatusMismatch:
+			needsSync = true
+		case model.StatusCorrupt:
 			needsSync = true
-			break
+			corrupt = append(corrupt, plugin)
 		}
 	}
 
+	if len(corrupt) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Corrupt cache entries:")
+		for _, plugin := range corrupt {
+			fmt.Fprintf(w, "  %s: %s\n", plugin.Name, strings.Join(plugin.Issues, "; "))
+		}
+	}
+
+	printModifiedHint(w, plugins)
+	printLinkedHint(w, plugins)
+	printDisabledHint(w, plugins)
+
 	if needsSync {
 		fmt.Fprintln(w, "")
 		fmt.Fprintln(w, "Next step: run `patchline sync` to refresh the cache.")
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -32,6 +34,13 @@ func TestBuildPluginListStatuses(t *testing.T) {
 			Source:       opencode.SourceProject,
 			ConfigPath:   "/tmp/opencode.json",
 		},
+		{
+			Name:         "corrupt",
+			DeclaredSpec: "corrupt@1.0.0",
+			Pinned:       "1.0.0",
+			Source:       opencode.SourceProject,
+			ConfigPath:   "/tmp/opencode.json",
+		},
 		{
 			Name:         "ok",
 			DeclaredSpec: "ok@1.0.0",
@@ -44,6 +53,7 @@ func TestBuildPluginListStatuses(t *testing.T) {
 	cacheEntries := []cache.Entry{
 		{Name: "mismatch", Version: "2.0.0", Path: "/cache/mismatch"},
 		{Name: "ok", Version: "1.0.0", Path: "/cache/ok"},
+		{Name: "corrupt", Version: "1.0.0", Path: "/cache/corrupt", Issues: []string{"missing dependency dep"}},
 	}
 
 	plugins := buildPluginList(specs, cacheEntries)
@@ -67,6 +77,11 @@ func TestBuildPluginListStatuses(t *testing.T) {
 		t.Fatalf("expected mismatch plugin, got status=%s installed=%s", mismatch.Status, mismatch.Installed)
 	}
 
+	corrupt := byName["corrupt"]
+	if corrupt.Status != model.StatusCorrupt || len(corrupt.Issues) != 1 {
+		t.Fatalf("expected corrupt plugin, got status=%s issues=%v", corrupt.Status, corrupt.Issues)
+	}
+
 	ok := byName["ok"]
 	if ok.Status != model.StatusOK || ok.Installed != "1.0.0" {
 		t.Fatalf("expected ok plugin, got status=%s installed=%s", ok.Status, ok.Installed)
@@ -85,6 +100,15 @@ func TestPrintListHints(t *testing.T) {
 		t.Fatalf("expected sync hint, got %q", output)
 	}
 
+	out.Reset()
+	printListHints(&out, []model.Plugin{
+		{Name: "broken", Status: model.StatusCorrupt, Issues: []string{"invalid package.json"}},
+	}, false)
+	output = out.String()
+	if !strings.Contains(output, "broken: invalid package.json") {
+		t.Fatalf("expected corrupt details, got %q", output)
+	}
+
 	out.Reset()
 	printListHints(&out, nil, true)
 	output = out.String()
@@ -113,3 +137,42 @@ func TestListCommandNoPlugins(t *testing.T) {
 		t.Fatalf("expected no plugins message, got %q", out.String())
 	}
 }
+
+func TestListCommandWarnsAboutConfigVariants(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	writeTestFile(t, filepath.Join(root, "opencode.jsonc"), `{"plugin": ["alpha@1.0.0"]}`)
+	writeTestFile(t, filepath.Join(root, ".opencode.json"), `{"plugin": ["beta@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := listCommand(CommonOptions{ProjectRoot: root, CacheDir: cacheDir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("list failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "alpha") || strings.Contains(stdout.String(), "beta") {
+		t.Fatalf("expected only opencode.jsonc plugins, got %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), ".opencode.json, which is ignored") {
+		t.Fatalf("expected variant warning, got %s", stderr.String())
+	}
+}
+
+func TestBuildPluginListShowsFolderPluginVersion(t *testing.T) {
+	specs := []opencode.PluginSpec{{
+		Name:         "tool",
+		DeclaredSpec: "/tmp/plugin/tool/index.ts",
+		Source:       opencode.SourceLocal,
+		LocalPath:    "/tmp/plugin/tool/index.ts",
+		LocalDir:     "/tmp/plugin/tool",
+		Version:      "0.3.0",
+	}}
+
+	plugins := buildPluginList(specs, nil)
+	if len(plugins) != 1 || plugins[0].Installed != "local 0.3.0" || plugins[0].LocalDirectory != "/tmp/plugin/tool" {
+		t.Fatalf("unexpected folder plugin: %+v", plugins)
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+// localKey identifies a local plugin: its folder, or its file for single-file
+// plugins. It matches model.Plugin.LocalDirectory.
+func localKey(spec opencode.PluginSpec) string {
+	if spec.LocalDir != "" {
+		return spec.LocalDir
+	}
+	return spec.LocalPath
+}
+
+// localSnapshotEntry builds the snapshot entry that records a single-file
+// local plugin. Saving it stores a copy of the file. Folder plugins are not
+// snapshotted.
+func localSnapshotEntry(spec opencode.PluginSpec, reason string) snapshot.Entry {
+	installed := "local"
+	if spec.Version != "" {
+		installed = spec.Version
+	}
+	return snapshot.Entry{
+		PluginName:        spec.Name,
+		PreviousSpec:      spec.DeclaredSpec,
+		PreviousInstalled: installed,
+		Source:            string(spec.Source),
+		Reason:            reason,
+		LocalPath:         spec.LocalPath,
+	}
+}
+
+// modifiedLocalPlugins returns the keys of local plugins whose file differs
+// from the newest snapshot that holds a copy of it. Plugins that were never
+// snapshotted, including folder plugins, are not reported. A missing snapshot
+// directory is not created.
+func modifiedLocalPlugins(opts CommonOptions, specs []opencode.PluginSpec) map[string]bool {
+	modified := map[string]bool{}
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" || !isDir(snapshotDir) {
+		return modified
+	}
+	store := snapshot.Store{Directory: snapshotDir}
+	for _, spec := range specs {
+		if spec.Source != opencode.SourceLocal || spec.LocalDir != "" {
+			continue
+		}
+		entry, ok := lastLocalSnapshot(store, spec.Name, spec.LocalPath)
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(spec.LocalPath)
+		if err != nil || snapshot.HashBytes(data) != entry.LocalHash {
+			modified[localKey(spec)] = true
+		}
+	}
+	return modified
+}
+
+// lastLocalSnapshot returns the newest entry holding a copy of path. When
+// that entry was saved by a rollback or restore, the file was then set to the
+// entry it restored from, so that entry is returned instead.
+func lastLocalSnapshot(store snapshot.Store, name string, path string) (snapshot.Entry, bool) {
+	entries, err := store.History(name)
+	if err != nil {
+		return snapshot.Entry{}, false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.LocalHash == "" || filepath.Clean(entry.LocalPath) != filepath.Clean(path) {
+			continue
+		}
+		if entry.RestoredFrom == "" {
+			return entry, true
+		}
+		for _, candidate := range entries {
+			if candidate.ID == entry.RestoredFrom && candidate.LocalHash != "" {
+				return candidate, true
+			}
+		}
+		return entry, true
+	}
+	return snapshot.Entry{}, false
+}
+
+// localFolder returns the folder of a local folder plugin. Snapshots do not
+// cover folder plugins, so they cannot be rolled back.
+func localFolder(opts CommonOptions, name string) (string, bool) {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		return "", false
+	}
+	for _, spec := range result.Plugins {
+		if spec.Name == name && spec.Source == opencode.SourceLocal && spec.LocalDir != "" {
+			return spec.LocalDir, true
+		}
+	}
+	return "", false
+}
+
+func printLocalFolderHint(w io.Writer, specs []opencode.PluginSpec) {
+	names := []string{}
+	for _, spec := range specs {
+		if spec.Source == opencode.SourceLocal && spec.LocalDir != "" {
+			names = append(names, spec.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	names = uniqueNames(names)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Local plugin folders are not snapshotted: %s. Changes to them are not shown as modified and cannot be rolled back.\n", strings.Join(names, ", "))
+}
+
+func markModifiedLocal(plugins []model.Plugin, modified map[string]bool) {
+	for i := range plugins {
+		if plugins[i].Status == model.StatusUnmanaged && modified[plugins[i].LocalDirectory] {
+			plugins[i].Status = model.StatusModified
+		}
+	}
+}
+
+func printModifiedHint(w io.Writer, plugins []model.Plugin) {
+	names := []string{}
+	for _, plugin := range plugins {
+		if plugin.Status == model.StatusModified {
+			names = append(names, plugin.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Local plugins modified since last snapshot: %s. Run `patchline snapshot` to record them or `patchline rollback <plugin>` to restore them.\n", strings.Join(names, ", "))
+}
+
+// restoreLocalFile writes the copy of a local plugin file recorded in entry
+// back to its path, first saving the current contents as a new entry with the
+// given reason. It returns the saved entry, or false when the file already
+// matches.
+func restoreLocalFile(store snapshot.Store, entry snapshot.Entry, reason string) (snapshot.Entry, bool, error) {
+	if entry.LocalHash == "" {
+		return snapshot.Entry{}, false, fmt.Errorf("snapshot %s has no copy of %s", entry.ID, entry.LocalPath)
+	}
+	content, err := store.Blob(entry.LocalHash)
+	if err != nil {
+		return snapshot.Entry{}, false, err
+	}
+
+	path := entry.LocalPath
+	mode := os.FileMode(0o644)
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return snapshot.Entry{}, false, err
+	}
+	if err == nil {
+		if snapshot.HashBytes(current) == entry.LocalHash {
+			return snapshot.Entry{}, false, nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	record, err := store.Save(snapshot.Entry{
+		PluginName:        entry.PluginName,
+		PreviousSpec:      entry.PreviousSpec,
+		PreviousInstalled: entry.PreviousInstalled,
+		Source:            entry.Source,
+		Reason:            reason,
+		LocalPath:         path,
+		RestoredFrom:      entry.ID,
+	})
+	if err != nil {
+		return snapshot.Entry{}, false, fmt.Errorf("save snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return snapshot.Entry{}, false, err
+	}
+	if err := atomicfile.Write(path, content, mode); err != nil {
+		return snapshot.Entry{}, false, err
+	}
+	return record, true, nil
+}
+
+// rollbackLocal restores a local plugin file from a snapshot.
+func rollbackLocal(store snapshot.Store, pluginName string, entry snapshot.Entry, stdout io.Writer, stderr io.Writer) int {
+	if entry.LocalHash != "" {
+		if content, err := store.Blob(entry.LocalHash); err == nil {
+			current, _ := os.ReadFile(entry.LocalPath)
+			if snapshot.HashBytes(current) != entry.LocalHash {
+				renderDiff(stdout, "current "+entry.LocalPath, "snapshot "+entry.ID, lineDiff(string(current), string(content)))
+				fmt.Fprintln(stdout, "")
+			}
+		}
+	}
+
+	record, changed, err := restoreLocalFile(store, entry, "rollback")
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to restore %s: %v\n", entry.LocalPath, err)
+		return 1
+	}
+	if !changed {
+		fmt.Fprintf(stdout, "%s already matches snapshot %s.\n", entry.LocalPath, entry.ID)
+		return 0
+	}
+	fmt.Fprintf(stdout, "Restored local plugin %s (%s) from snapshot %s.\n", pluginName, entry.LocalPath, entry.ID)
+	if record.LocalHash != "" {
+		fmt.Fprintf(stdout, "Previous contents saved as %s; run `patchline rollback --to %s %s` to redo.\n", record.ID, record.ID, pluginName)
+	}
+	return 0
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
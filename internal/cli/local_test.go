@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPluginSnapshotListAndRollback(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	project := filepath.Join(root, "project")
+	pluginPath := filepath.Join(project, ".opencode", "plugin", "tool.ts")
+	writeTestFile(t, filepath.Join(project, "opencode.json"), `{"plugin": []}`)
+	writeTestFile(t, pluginPath, "export const version = 1\n")
+	snapshotDir := filepath.Join(root, "snapshots")
+	opts := CommonOptions{ProjectRoot: project, CacheDir: filepath.Join(root, "cache"), SnapshotDir: snapshotDir}
+	if err := os.MkdirAll(opts.CacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir cache: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Saved 1 snapshot(s)") {
+		t.Fatalf("expected local plugin snapshot, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := listCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("list failed: %d %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "modified") {
+		t.Fatalf("expected unchanged plugin, got %s", stdout.String())
+	}
+
+	writeTestFile(t, pluginPath, "export const version = 2\n")
+	stdout.Reset()
+	if code := listCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("list failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "modified") || !strings.Contains(stdout.String(), "Local plugins modified since last snapshot: tool.") {
+		t.Fatalf("expected modified status, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := rollbackCommand(opts, "tool", rollbackOptions{}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "- export const version = 2") || !strings.Contains(stdout.String(), "Restored local plugin tool") {
+		t.Fatalf("expected diff and restore message, got %s", stdout.String())
+	}
+	data, err := os.ReadFile(pluginPath)
+	if err != nil {
+		t.Fatalf("read plugin: %v", err)
+	}
+	if string(data) != "export const version = 1\n" {
+		t.Fatalf("expected restored contents, got %q", string(data))
+	}
+
+	stdout.Reset()
+	if code := listCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("list failed: %d %s", code, stderr.String())
+	}
+	if strings.Contains(stdout.String(), "modified") {
+		t.Fatalf("expected plugin to match its snapshot again, got %s", stdout.String())
+	}
+}
+
+func TestSnapshotSkipsLocalPluginFolders(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	project := filepath.Join(root, "project")
+	pluginDir := filepath.Join(project, ".opencode", "plugin", "suite")
+	writeTestFile(t, filepath.Join(project, "opencode.json"), `{"plugin": []}`)
+	writeTestFile(t, filepath.Join(pluginDir, "index.ts"), "export * from './lib'\n")
+	writeTestFile(t, filepath.Join(pluginDir, "lib.ts"), "export const version = 1\n")
+	opts := CommonOptions{ProjectRoot: project, CacheDir: filepath.Join(root, "cache"), SnapshotDir: filepath.Join(root, "snapshots")}
+	if err := os.MkdirAll(opts.CacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir cache: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "skipping local plugin folder "+pluginDir) {
+		t.Fatalf("expected folder plugin to be skipped, got %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "No plugins found to snapshot.") {
+		t.Fatalf("expected nothing to be saved, got %s", stdout.String())
+	}
+
+	writeTestFile(t, filepath.Join(pluginDir, "lib.ts"), "export const version = 2\n")
+	stdout.Reset()
+	if code := listCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("list failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "unmanaged") || strings.Contains(stdout.String(), "modified since last snapshot") {
+		t.Fatalf("expected folder plugin not to be marked modified, got %s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "Local plugin folders are not snapshotted: suite.") {
+		t.Fatalf("expected a hint about the folder plugin, got %s", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := rollbackCommand(opts, "suite", rollbackOptions{}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected rollback of a folder plugin to fail, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "suite is a local plugin folder ("+pluginDir+")") {
+		t.Fatalf("expected a folder plugin error, got %s", stderr.String())
+	}
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/lockfile"
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+type lockDrift struct {
+	Name    string
+	Problem string
+}
+
+func lockCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
+	if opts.Offline {
+		fmt.Fprintln(stderr, "lock requires registry access; disable --offline")
+		return 2
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	path, err := lockfilePath(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to locate project: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	cacheDir, cacheCandidates := cache.ResolveDir(opts.CacheDir)
+	cacheEntries := []cache.Entry{}
+	if cacheDir != "" {
+		cacheEntries, err = cache.Detect(ctx, cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+	} else if opts.CacheDir != "" {
+		fmt.Fprintf(stderr, "cache directory not found: %s\n", opts.CacheDir)
+	} else if len(cacheCandidates) > 0 {
+		fmt.Fprintf(stderr, "cache directory not found. Checked: %s\n", strings.Join(cacheCandidates, ", "))
+	}
+
+	installedByName := map[string]cache.Entry{}
+	for _, entry := range cacheEntries {
+		installedByName[entry.Name] = entry
+	}
+
+	registry := registryFor(opts)
+	file := lockfile.File{}
+	incomplete := 0
+	for _, spec := range effectivePlugins(result.Plugins) {
+		entry, installed := installedByName[spec.Name]
+		installed = installed && !entry.Corrupt()
+
+		version, err := resolveLockedVersion(ctx, registry, spec, entry, installed)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to resolve %s: %v\n", spec.Name, err)
+			return 1
+		}
+		pkg, err := lockPackage(ctx, registry, spec.Name, version)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to lock %s: %v\n", spec.Name, err)
+			return 1
+		}
+
+		plugin := lockfile.Plugin{
+			Name:    spec.Name,
+			Spec:    spec.DeclaredSpec,
+			Source:  string(spec.Source),
+			Package: pkg,
+		}
+		if installed && entry.Version == version {
+			for _, name := range cache.Closure(installedByName, spec.Name) {
+				if name == spec.Name {
+					continue
+				}
+				dep, err := lockPackage(ctx, registry, name, installedByName[name].Version)
+				if err != nil {
+					fmt.Fprintf(stderr, "failed to lock %s dependency %s: %v\n", spec.Name, name, err)
+					return 1
+				}
+				plugin.Dependencies = append(plugin.Dependencies, dep)
+			}
+		} else {
+			incomplete++
+		}
+		file.Plugins = append(file.Plugins, plugin)
+	}
+
+	if err := lockfile.Save(path, file); err != nil {
+		fmt.Fprintf(stderr, "failed to write lockfile: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Locked %d plugin(s) in %s.\n", len(file.Plugins), path)
+	if incomplete > 0 {
+		fmt.Fprintf(stdout, "%d plugin(s) are not installed at the locked version; run OpenCode and re-run `patchline lock` to record their dependencies.\n", incomplete)
+	}
+	return 0
+}
+
+// lockCheckCommand compares the config and cache with patchline.lock and
+// reports every disagreement. It exits non-zero when anything drifted.
+func lockCheckCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	lock, path, code := loadProjectLockfile(opts, result, stderr)
+	if code != 0 {
+		return code
+	}
+
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+	entries := []cache.Entry{}
+	if cacheDir != "" {
+		entries, err = cache.Detect(context.Background(), cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+	}
+
+	drifts := checkLockfile(lock, result.Plugins, entries)
+	if len(drifts) == 0 {
+		return 0
+	}
+	fmt.Fprintf(stderr, "%s does not match the current state:\n", path)
+	for _, drift := range drifts {
+		fmt.Fprintf(stderr, "  %s: %s\n", drift.Name, drift.Problem)
+	}
+	return 1
+}
+
+func loadProjectLockfile(opts CommonOptions, result opencode.DiscoveryResult, stderr io.Writer) (lockfile.File, string, int) {
+	path, err := lockfilePath(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to locate project: %v\n", err)
+		return lockfile.File{}, "", 1
+	}
+	lock, err := lockfile.Load(path)
+	if err != nil {
+		if errors.Is(err, lockfile.ErrLockfileNotFound) {
+			fmt.Fprintf(stderr, "no lockfile found at %s; run `patchline lock` first\n", path)
+			return lockfile.File{}, path, 1
+		}
+		fmt.Fprintf(stderr, "failed to load lockfile: %v\n", err)
+		return lockfile.File{}, path, 1
+	}
+	return lock, path, 0
+}
+
+func checkLockfile(lock lockfile.File, specs []opencode.PluginSpec, entries []cache.Entry) []lockDrift {
+	installedByName := map[string]cache.Entry{}
+	for _, entry := range entries {
+		installedByName[entry.Name] = entry
+	}
+
+	drifts := []lockDrift{}
+	declared := map[string]struct{}{}
+	for _, spec := range effectivePlugins(specs) {
+		declared[spec.Name] = struct{}{}
+		locked, ok := lock.Plugin(spec.Name)
+		if !ok {
+			drifts = append(drifts, lockDrift{Name: spec.Name, Problem: "declared in config but missing from lockfile"})
+			continue
+		}
+		if locked.Spec != spec.DeclaredSpec {
+			drifts = append(drifts, lockDrift{
+				Name:    spec.Name,
+				Problem: fmt.Sprintf("config declares %s, lockfile has %s", spec.DeclaredSpec, locked.Spec),
+			})
+		}
+	}
+
+	for _, locked := range lock.Plugins {
+		if _, ok := declared[locked.Name]; !ok {
+			drifts = append(drifts, lockDrift{Name: locked.Name, Problem: "locked but no longer declared in config"})
+			continue
+		}
+		packages := append([]lockfile.Package{locked.Package}, locked.Dependencies...)
+		for _, pkg := range packages {
+			entry, ok := installedByName[pkg.Name]
+			switch {
+			case !ok:
+				drifts = append(drifts, lockDrift{Name: pkg.Name, Problem: fmt.Sprintf("not installed, lockfile has %s", pkg.Version)})
+			case entry.Corrupt():
+				drifts = append(drifts, lockDrift{Name: pkg.Name, Problem: "cache entry is corrupt"})
+			case entry.Version != pkg.Version:
+				drifts = append(drifts, lockDrift{
+					Name:    pkg.Name,
+					Problem: fmt.Sprintf("installed %s, lockfile has %s", entry.Version, pkg.Version),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Name < drifts[j].Name
+	})
+	return drifts
+}
+
+// effectivePlugins returns one npm plugin spec per name, preferring the
+// config source OpenCode gives the highest precedence.
+func effectivePlugins(specs []opencode.PluginSpec) []opencode.PluginSpec {
+	byName := map[string][]opencode.PluginSpec{}
+	names := []string{}
+	for _, spec := range specs {
+		if spec.Source == opencode.SourceLocal || spec.Source == opencode.SourceDependency || spec.LinkPath != "" {
+			continue
+		}
+		if _, ok := byName[spec.Name]; !ok {
+			names = append(names, spec.Name)
+		}
+		byName[spec.Name] = append(byName[spec.Name], spec)
+	}
+	sort.Strings(names)
+
+	out := make([]opencode.PluginSpec, 0, len(names))
+	for _, name := range names {
+		candidates := byName[name]
+		chosen := candidates[0]
+		for _, spec := range candidates[1:] {
+			if opencode.Precedence(spec.Source) < opencode.Precedence(chosen.Source) {
+				chosen = spec
+			}
+		}
+		out = append(out, chosen)
+	}
+	return out
+}
+
+// resolveLockedVersion picks the version to lock: an exact pin, the installed
+// version when it satisfies the declared range, or the newest registry version
+// that does, preferring latest as npm would.
+func resolveLockedVersion(ctx context.Context, registry npm.Registry, spec opencode.PluginSpec, entry cache.Entry, installed bool) (string, error) {
+	if npm.IsExactVersion(spec.Pinned) {
+		return spec.Pinned, nil
+	}
+	declared := spec.Pinned
+	if declared == "latest" {
+		declared = ""
+	}
+	versionRange, err := npm.ParseRange(declared)
+	if err != nil {
+		if installed {
+			return entry.Version, nil
+		}
+		return "", fmt.Errorf("cannot resolve %s without an installed version", spec.DeclaredSpec)
+	}
+	if installed && versionRange.Contains(entry.Version) {
+		return entry.Version, nil
+	}
+	info, err := registry.FetchPackageInfo(ctx, spec.Name)
+	if err != nil {
+		return "", err
+	}
+	if info.Latest != "" && versionRange.Contains(info.Latest) {
+		return info.Latest, nil
+	}
+	version, err := versionRange.MaxSatisfying(info.Versions)
+	if err != nil {
+		return "", fmt.Errorf("no published version satisfies %s", spec.DeclaredSpec)
+	}
+	return version, nil
+}
+
+func lockPackage(ctx context.Context, registry npm.Registry, name string, version string) (lockfile.Package, error) {
+	info, err := registry.FetchVersion(ctx, name, version)
+	if err != nil {
+		return lockfile.Package{}, err
+	}
+	return lockfile.Package{
+		Name:      name,
+		Version:   version,
+		Registry:  registry.URL(),
+		Resolved:  info.Tarball,
+		Integrity: info.Integrity,
+		Shasum:    info.Shasum,
+	}, nil
+}
+
+// lockfilePath places patchline.lock next to the project config.
+func lockfilePath(opts CommonOptions, result opencode.DiscoveryResult) (string, error) {
+	dir, err := projectDir(opts, result)
+	if err != nil {
+		return "", err
+	}
+	return lockfile.PathFor(dir), nil
+}
+
+// projectDir returns the project directory that owns the project config,
+// falling back to the project root or working directory when no project
+// config exists.
+func projectDir(opts CommonOptions, result opencode.DiscoveryResult) (string, error) {
+	if result.ProjectConfig != "" {
+		return projectDirOf(result.ProjectConfig), nil
+	}
+	if opts.ProjectRoot != "" {
+		return opts.ProjectRoot, nil
+	}
+	return os.Getwd()
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/lockfile"
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+func TestLockFrozenAndVerifyFlow(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	alphaManifest := `{"name":"alpha","version":"1.0.0","dependencies":{"beta":"^2.0.0"}}`
+	betaManifest := `{"name":"beta","version":"2.0.0"}`
+	server := newTestRegistry(t, map[string]map[string]string{
+		"alpha@1.0.0": {"package/package.json": alphaManifest},
+		"beta@2.0.0":  {"package/package.json": betaManifest},
+	})
+
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), alphaManifest)
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), betaManifest)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, Registry: server.URL}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := lockCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("lock failed: %d %s", code, stderr.String())
+	}
+
+	lock, err := lockfile.Load(filepath.Join(root, lockfile.FileName))
+	if err != nil {
+		t.Fatalf("load lockfile: %v", err)
+	}
+	alpha, ok := lock.Plugin("alpha")
+	if !ok || alpha.Package.Version != "1.0.0" || !strings.HasPrefix(alpha.Package.Integrity, "sha512-") {
+		t.Fatalf("unexpected locked alpha: %#v", alpha)
+	}
+	if alpha.Package.Registry != server.URL {
+		t.Fatalf("expected registry %s, got %s", server.URL, alpha.Package.Registry)
+	}
+	if len(alpha.Dependencies) != 1 || alpha.Dependencies[0].Name != "beta" {
+		t.Fatalf("expected beta in closure, got %#v", alpha.Dependencies)
+	}
+
+	stderr.Reset()
+	if code := runSync([]string{"--frozen", "--project", root, "--cache-dir", cacheDir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected frozen sync to pass, got %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := verifyCommand(opts, verifyOptions{Lockfile: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected lockfile verify to pass, got %d: %s %s", code, stdout.String(), stderr.String())
+	}
+
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), `{"name":"beta","version":"2.1.0"}`)
+	stderr.Reset()
+	if code := runSync([]string{"--frozen", "--project", root, "--cache-dir", cacheDir}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected frozen sync to fail, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "beta: installed 2.1.0, lockfile has 2.0.0") {
+		t.Fatalf("expected beta drift, got %q", stderr.String())
+	}
+}
+
+func TestLockAndCheckUseProjectRootForDotOpencodeConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	configPath := filepath.Join(root, ".opencode", "opencode.json")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	alphaManifest := `{"name":"alpha","version":"1.0.0"}`
+	server := newTestRegistry(t, map[string]map[string]string{
+		"alpha@1.0.0": {"package/package.json": alphaManifest},
+	})
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), alphaManifest)
+	writePolicyFile(t, root, `{"deny":["alpha"]}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, Registry: server.URL}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := lockCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("lock failed: %d %s", code, stderr.String())
+	}
+	if _, err := os.Stat(lockfile.PathFor(root)); err != nil {
+		t.Fatalf("expected lockfile at the project root: %v", err)
+	}
+	if _, err := os.Stat(lockfile.PathFor(filepath.Dir(configPath))); !os.IsNotExist(err) {
+		t.Fatalf("expected no lockfile inside .opencode, got %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := checkCommand(opts, false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected the project policy to deny alpha, got %d: %s", code, stdout.String())
+	}
+}
+
+func TestCheckLockfileReportsConfigDrift(t *testing.T) {
+	lock := lockfile.File{
+		Plugins: []lockfile.Plugin{
+			{Name: "alpha", Spec: "alpha@1.0.0", Package: lockfile.Package{Name: "alpha", Version: "1.0.0"}},
+			{Name: "gone", Spec: "gone@1.0.0", Package: lockfile.Package{Name: "gone", Version: "1.0.0"}},
+		},
+	}
+	specs := []opencode.PluginSpec{
+		{Name: "alpha", DeclaredSpec: "alpha@1.1.0", Pinned: "1.1.0", Source: opencode.SourceProject},
+		{Name: "fresh", DeclaredSpec: "fresh", Source: opencode.SourceProject},
+		{Name: "local", DeclaredSpec: "/tmp/local.js", Source: opencode.SourceLocal},
+	}
+	entries := []cache.Entry{{Name: "alpha", Version: "1.0.0"}}
+
+	drifts := checkLockfile(lock, specs, entries)
+	problems := map[string]string{}
+	for _, drift := range drifts {
+		problems[drift.Name] = drift.Problem
+	}
+	if len(problems) != 3 {
+		t.Fatalf("expected 3 drifts, got %#v", drifts)
+	}
+	if !strings.Contains(problems["alpha"], "config declares alpha@1.1.0") {
+		t.Fatalf("unexpected alpha drift %q", problems["alpha"])
+	}
+	if !strings.Contains(problems["fresh"], "missing from lockfile") {
+		t.Fatalf("unexpected fresh drift %q", problems["fresh"])
+	}
+	if !strings.Contains(problems["gone"], "no longer declared") {
+		t.Fatalf("unexpected gone drift %q", problems["gone"])
+	}
+}
+
+func TestEffectivePluginsPrefersHigherPrecedence(t *testing.T) {
+	specs := []opencode.PluginSpec{
+		{Name: "alpha", DeclaredSpec: "alpha@1.0.0", Source: opencode.SourceGlobal},
+		{Name: "alpha", DeclaredSpec: "alpha@2.0.0", Source: opencode.SourceProject},
+	}
+	got := effectivePlugins(specs)
+	if len(got) != 1 || got[0].DeclaredSpec != "alpha@2.0.0" {
+		t.Fatalf("expected project spec to win, got %#v", got)
+	}
+}
+
+func TestResolveLockedVersionStaysInsideDeclaredRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"dist-tags": map[string]string{"latest": "2.1.0"},
+			"versions":  map[string]any{"1.2.0": map[string]any{}, "1.4.2": map[string]any{}, "2.1.0": map[string]any{}},
+		})
+	}))
+	defer server.Close()
+	registry := npm.Registry{BaseURL: server.URL}
+	ctx := context.Background()
+
+	ranged := opencode.PluginSpec{Name: "alpha", DeclaredSpec: "alpha@^1.2.0", Pinned: "^1.2.0"}
+	version, err := resolveLockedVersion(ctx, registry, ranged, cache.Entry{}, false)
+	if err != nil || version != "1.4.2" {
+		t.Fatalf("expected 1.4.2 inside ^1.2.0, got %q %v", version, err)
+	}
+	version, err = resolveLockedVersion(ctx, registry, ranged, cache.Entry{Version: "1.2.0"}, true)
+	if err != nil || version != "1.2.0" {
+		t.Fatalf("expected installed 1.2.0, got %q %v", version, err)
+	}
+
+	unpinned := opencode.PluginSpec{Name: "alpha", DeclaredSpec: "alpha"}
+	version, err = resolveLockedVersion(ctx, registry, unpinned, cache.Entry{}, false)
+	if err != nil || version != "2.1.0" {
+		t.Fatalf("expected latest 2.1.0, got %q %v", version, err)
+	}
+
+	outside := opencode.PluginSpec{Name: "alpha", DeclaredSpec: "alpha@^3.0.0", Pinned: "^3.0.0"}
+	if _, err := resolveLockedVersion(ctx, registry, outside, cache.Entry{}, false); err == nil {
+		t.Fatalf("expected an error when nothing satisfies the range")
+	}
+}
@@ -28,6 +28,7 @@ func outdatedCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int
 		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
 		return 1
 	}
+	warnDiscovery(stderr, result)
 
 	ctx := context.Background()
 	cacheDir, candidates := cache.ResolveDir(opts.CacheDir)
@@ -51,10 +52,11 @@ func outdatedCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int
 		installedByName[entry.Name] = entry
 	}
 
+	registry := registryFor(opts)
 	latestByName := map[string]string{}
 	localCount := 0
 	for _, spec := range result.Plugins {
-		if spec.Source == opencode.SourceLocal {
+		if spec.Source == opencode.SourceLocal || spec.LinkPath != "" {
 			localCount++
 			continue
 		}
@@ -65,7 +67,7 @@ func outdatedCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int
 			fetchErrors[spec.Name] = fmt.Errorf("offline")
 			continue
 		}
-		info, err := npm.FetchPackageInfo(ctx, spec.Name)
+		info, err := registry.FetchPackageInfo(ctx, spec.Name)
 		if err != nil {
 			fetchErrors[spec.Name] = err
 			continue
@@ -75,20 +77,28 @@ func outdatedCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int
 
 	rows := make([]outdatedRow, 0, len(result.Plugins))
 	for _, spec := range result.Plugins {
-		if spec.Source == opencode.SourceLocal {
+		if spec.Source == opencode.SourceLocal || spec.LinkPath != "" {
 			continue
 		}
 
 		entry, ok := installedByName[spec.Name]
+		if spec.Source == opencode.SourceDependency {
+			entry, ok = dependencyEntry(spec)
+		}
 		installed := "missing"
 		if ok {
 			installed = entry.Version
+			if installed == "" {
+				installed = "unknown"
+			}
 		}
 
 		latest := latestByName[spec.Name]
 		status := string(model.StatusOK)
 		if installed == "missing" {
 			status = string(model.StatusMissing)
+		} else if entry.Corrupt() {
+			status = string(model.StatusCorrupt)
 		} else if fetchErrors[spec.Name] != nil || latest == "" {
 			status = string(model.StatusUnknown)
 			if latest == "" {
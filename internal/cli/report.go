@@ -0,0 +1,337 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+const (
+	reportMarkdown = "markdown"
+	reportHTML     = "html"
+	reportCSV      = "csv"
+)
+
+// fleetReport is the plugin by project matrix for every project under a root.
+type fleetReport struct {
+	Root     string
+	Projects []fleetProject
+	Plugins  []fleetPlugin
+}
+
+type fleetProject struct {
+	Name   string
+	Config string
+	Error  string
+}
+
+// fleetPlugin is one row of the matrix. Cells is keyed by project name.
+type fleetPlugin struct {
+	Name     string
+	Latest   string
+	Versions []string
+	Skew     bool
+	Cells    map[string]fleetCell
+}
+
+type fleetCell struct {
+	Declared string
+	Version  string
+	Outdated bool
+}
+
+// Outdated counts the projects that pin an older version than latest.
+func (p fleetPlugin) Outdated() int {
+	count := 0
+	for _, cell := range p.Cells {
+		if cell.Outdated {
+			count++
+		}
+	}
+	return count
+}
+
+func reportCommand(opts CommonOptions, root string, format string, output string, stdout io.Writer, stderr io.Writer) int {
+	switch format {
+	case reportMarkdown, reportHTML, reportCSV:
+	default:
+		fmt.Fprintf(stderr, "invalid --format %q (expected markdown, html or csv)\n", format)
+		return 2
+	}
+	if root != "" {
+		opts.ProjectRoot = root
+	}
+	root, err := scanRoot(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve scan root: %v\n", err)
+		return 1
+	}
+	configs, err := opencode.FindProjects(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan projects: %v\n", err)
+		return 1
+	}
+	if len(configs) == 0 {
+		fmt.Fprintf(stderr, "no project configs found under %s\n", root)
+		return 1
+	}
+
+	report := buildFleetReport(opts, root, configs, stderr)
+
+	var buf bytes.Buffer
+	switch format {
+	case reportHTML:
+		err = renderReportHTML(&buf, report)
+	case reportCSV:
+		err = renderReportCSV(&buf, report)
+	default:
+		renderReportMarkdown(&buf, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to render report: %v\n", err)
+		return 1
+	}
+
+	if output == "" {
+		_, _ = stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(stderr, "failed to write report: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Wrote report for %d project(s) and %d plugin(s) to %s\n", len(report.Projects), len(report.Plugins), output)
+	return 0
+}
+
+// buildFleetReport discovers the plugins declared by each project config and
+// looks up the latest version of each plugin once. Registry failures leave the
+// latest version unknown rather than failing the report.
+func buildFleetReport(opts CommonOptions, root string, configs []string, stderr io.Writer) fleetReport {
+	report := fleetReport{Root: root}
+	byName := map[string]*fleetPlugin{}
+	for _, config := range configs {
+		dir := projectDirOf(config)
+		project := fleetProject{Name: displayDir(root, dir), Config: config}
+		result, err := opencode.Discover(dir, opts.GlobalConfig, nil)
+		if err != nil {
+			project.Error = err.Error()
+			fmt.Fprintf(stderr, "failed to discover plugins in %s: %v\n", project.Name, err)
+		}
+		report.Projects = append(report.Projects, project)
+		if err != nil {
+			continue
+		}
+
+		for _, spec := range result.Plugins {
+			if spec.Source != opencode.SourceProject || filepath.Clean(spec.ConfigPath) != filepath.Clean(config) {
+				continue
+			}
+			plugin, ok := byName[spec.Name]
+			if !ok {
+				plugin = &fleetPlugin{Name: spec.Name, Cells: map[string]fleetCell{}}
+				byName[spec.Name] = plugin
+			}
+			version := spec.Pinned
+			if version == "" {
+				version = "unpinned"
+			}
+			plugin.Cells[project.Name] = fleetCell{Declared: spec.DeclaredSpec, Version: version}
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx := context.Background()
+	registry := registryFor(opts)
+	for _, name := range names {
+		plugin := byName[name]
+		if !opts.Offline {
+			info, err := registry.FetchPackageInfo(ctx, name)
+			if err != nil {
+				fmt.Fprintf(stderr, "failed to fetch %s: %v\n", name, err)
+			} else {
+				plugin.Latest = info.Latest
+			}
+		}
+
+		seen := map[string]struct{}{}
+		for project, cell := range plugin.Cells {
+			if _, ok := seen[cell.Version]; !ok {
+				seen[cell.Version] = struct{}{}
+				plugin.Versions = append(plugin.Versions, cell.Version)
+			}
+			if plugin.Latest != "" {
+				if cmp, ok := npm.CompareSemver(cell.Version, plugin.Latest); ok && cmp < 0 {
+					cell.Outdated = true
+					plugin.Cells[project] = cell
+				}
+			}
+		}
+		sort.Strings(plugin.Versions)
+		plugin.Skew = len(plugin.Versions) > 1
+		report.Plugins = append(report.Plugins, *plugin)
+	}
+	return report
+}
+
+func reportCellLabel(cell fleetCell, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	if cell.Outdated {
+		return cell.Version + " (outdated)"
+	}
+	return cell.Version
+}
+
+func reportLatestLabel(latest string) string {
+	if latest == "" {
+		return "unknown"
+	}
+	return latest
+}
+
+func reportSummary(report fleetReport) (int, int) {
+	skewed := 0
+	outdated := 0
+	for _, plugin := range report.Plugins {
+		if plugin.Skew {
+			skewed++
+		}
+		outdated += plugin.Outdated()
+	}
+	return skewed, outdated
+}
+
+func renderReportMarkdown(w io.Writer, report fleetReport) {
+	fmt.Fprintf(w, "# Plugin report for %s\n\n", report.Root)
+	skewed, outdated := reportSummary(report)
+	fmt.Fprintf(w, "%d project(s), %d plugin(s), %d with version skew, %d outdated pin(s).\n\n", len(report.Projects), len(report.Plugins), skewed, outdated)
+
+	headers := []string{"Plugin", "Latest", "Skew"}
+	for _, project := range report.Projects {
+		headers = append(headers, markdownCell(project.Name))
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(headers, " | "))
+	separators := make([]string, len(headers))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(separators, " | "))
+	for _, plugin := range report.Plugins {
+		skew := ""
+		if plugin.Skew {
+			skew = "yes"
+		}
+		row := []string{markdownCell(plugin.Name), reportLatestLabel(plugin.Latest), skew}
+		for _, project := range report.Projects {
+			cell, ok := plugin.Cells[project.Name]
+			row = append(row, markdownCell(reportCellLabel(cell, ok)))
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+	}
+
+	var failed []string
+	for _, project := range report.Projects {
+		if project.Error != "" {
+			failed = append(failed, fmt.Sprintf("- %s: %s", project.Name, project.Error))
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "\nProjects that could not be read:\n\n%s\n", strings.Join(failed, "\n"))
+	}
+}
+
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// renderReportCSV writes one row per plugin and project so the output can be
+// filtered and pivoted in a spreadsheet.
+func renderReportCSV(w io.Writer, report fleetReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"plugin", "project", "declared", "version", "latest", "outdated", "skew"}); err != nil {
+		return err
+	}
+	for _, plugin := range report.Plugins {
+		for _, project := range report.Projects {
+			cell, ok := plugin.Cells[project.Name]
+			if !ok {
+				continue
+			}
+			record := []string{
+				plugin.Name,
+				project.Name,
+				cell.Declared,
+				cell.Version,
+				plugin.Latest,
+				strconv.FormatBool(cell.Outdated),
+				strconv.FormatBool(plugin.Skew),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cell": func(plugin fleetPlugin, project string) fleetCell {
+		return plugin.Cells[project]
+	},
+	"latest": reportLatestLabel,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Plugin report for {{.Report.Root}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+tr.skew th { background: #fff4ce; }
+td.outdated { background: #fde2e1; }
+</style>
+</head>
+<body>
+<h1>Plugin report for {{.Report.Root}}</h1>
+<p>{{len .Report.Projects}} project(s), {{len .Report.Plugins}} plugin(s), {{.Skewed}} with version skew, {{.Outdated}} outdated pin(s).</p>
+<table>
+<thead>
+<tr><th>Plugin</th><th>Latest</th>{{range .Report.Projects}}<th title="{{.Config}}">{{.Name}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range $plugin := .Report.Plugins}}<tr{{if $plugin.Skew}} class="skew"{{end}}><th>{{$plugin.Name}}{{if $plugin.Skew}} (skew){{end}}</th><td>{{latest $plugin.Latest}}</td>{{range $.Report.Projects}}{{$cell := cell $plugin .Name}}{{if $cell.Outdated}}<td class="outdated" title="{{$cell.Declared}}">{{$cell.Version}} (outdated)</td>{{else if $cell.Version}}<td title="{{$cell.Declared}}">{{$cell.Version}}</td>{{else}}<td>-</td>{{end}}{{end}}</tr>
+{{end}}</tbody>
+</table>
+{{range .Report.Projects}}{{if .Error}}<p>{{.Name}}: {{.Error}}</p>
+{{end}}{{end}}</body>
+</html>
+`))
+
+func renderReportHTML(w io.Writer, report fleetReport) error {
+	skewed, outdated := reportSummary(report)
+	return reportTemplate.Execute(w, struct {
+		Report   fleetReport
+		Skewed   int
+		Outdated int
+	}{report, skewed, outdated})
+}
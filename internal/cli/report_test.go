@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupFleet(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	writeTestFile(t, filepath.Join(root, "code", "web", "opencode.json"), `{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`)
+	writeTestFile(t, filepath.Join(root, "code", "api", ".opencode", "opencode.jsonc"), `{"plugin": ["alpha@1.2.0"]}`)
+	writeTestFile(t, filepath.Join(root, "code", "cli", "opencode.json"), `{"plugin": ["beta@2.0.0", "gamma"]}`)
+	return filepath.Join(root, "code")
+}
+
+// newLatestRegistry serves packuments that only carry the latest dist-tag.
+func newLatestRegistry(t *testing.T, latest map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, ok := latest[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"dist-tags": map[string]string{"latest": version},
+			"versions":  map[string]any{version: map[string]any{}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReportMarkdownMarksSkewAndOutdated(t *testing.T) {
+	root := setupFleet(t)
+	registry := newLatestRegistry(t, map[string]string{"alpha": "1.2.0", "beta": "2.0.0"})
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := runReport([]string{"--root", root, "--registry", registry.URL}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("report failed: %d %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "| Plugin | Latest | Skew | api | cli | web |") {
+		t.Fatalf("expected project columns, got %s", out)
+	}
+	if !strings.Contains(out, "| alpha | 1.2.0 | yes | 1.2.0 | - | 1.0.0 (outdated) |") {
+		t.Fatalf("expected alpha skew and outdated pin, got %s", out)
+	}
+	if !strings.Contains(out, "| beta | 2.0.0 |  | - | 2.0.0 | 2.0.0 |") {
+		t.Fatalf("expected beta row without skew, got %s", out)
+	}
+	if !strings.Contains(out, "| gamma | unknown |  | - | unpinned | - |") {
+		t.Fatalf("expected unpinned gamma with unknown latest, got %s", out)
+	}
+	if !strings.Contains(out, "3 project(s), 3 plugin(s), 1 with version skew, 1 outdated pin(s).") {
+		t.Fatalf("expected summary, got %s", out)
+	}
+	if !strings.Contains(stderr.String(), "failed to fetch gamma") {
+		t.Fatalf("expected fetch warning for gamma, got %s", stderr.String())
+	}
+}
+
+func TestReportCSVWritesOneRowPerDeclaration(t *testing.T) {
+	root := setupFleet(t)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := reportCommand(CommonOptions{Offline: true}, root, reportCSV, "", &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("report failed: %d %s", code, stderr.String())
+	}
+	records, err := csv.NewReader(&stdout).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("expected header and 5 rows, got %v", records)
+	}
+	if strings.Join(records[1], ",") != "alpha,api,alpha@1.2.0,1.2.0,,false,true" {
+		t.Fatalf("unexpected first row: %v", records[1])
+	}
+}
+
+func TestReportHTMLWritesFile(t *testing.T) {
+	root := setupFleet(t)
+	output := filepath.Join(t.TempDir(), "report.html")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := reportCommand(CommonOptions{Offline: true}, root, reportHTML, output, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("report failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Wrote report for 3 project(s) and 3 plugin(s)") {
+		t.Fatalf("expected confirmation, got %s", stdout.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	html := string(data)
+	if !strings.Contains(html, `<tr class="skew"><th>alpha (skew)</th>`) || !strings.Contains(html, `<td>-</td>`) {
+		t.Fatalf("unexpected html: %s", html)
+	}
+}
+
+func TestReportRejectsUnknownFormat(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := reportCommand(CommonOptions{Offline: true}, t.TempDir(), "pdf", "", &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+}
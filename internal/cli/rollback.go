@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/policy"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+type rollbackOptions struct {
+	To     string
+	File   bool
+	Force  bool
+	Exact  bool
+	Remaps []snapshot.Remap
+}
+
+func rollbackCommand(opts CommonOptions, pluginName string, ropts rollbackOptions, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, candidates := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	if opts.SnapshotDir == "" && len(candidates) > 0 {
+		fmt.Fprintf(stderr, "Using snapshot directory: %s\n", snapshotDir)
+	}
+
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	if dir, ok := localFolder(opts, pluginName); ok {
+		fmt.Fprintf(stderr, "%s is a local plugin folder (%s); only single-file plugins are snapshotted, so it cannot be rolled back\n", pluginName, dir)
+		return 1
+	}
+	entry, err := store.Find(pluginName, ropts.To)
+	if err != nil {
+		if errors.Is(err, snapshot.ErrSnapshotNotFound) {
+			if ropts.To != "" {
+				fmt.Fprintf(stderr, "no snapshot of %s matches %s\n", pluginName, ropts.To)
+				return 1
+			}
+			fmt.Fprintf(stderr, "no snapshot found for %s\n", pluginName)
+			return 1
+		}
+		fmt.Fprintf(stderr, "failed to load snapshot: %v\n", err)
+		return 1
+	}
+	if entry.LocalPath != "" {
+		return rollbackLocal(store, pluginName, entry, stdout, stderr)
+	}
+	store.Roots = snapshotRoots(opts, ropts.Remaps)
+	entry.ConfigPath = store.Roots.Resolve(entry)
+	if entry.ConfigPath == "" {
+		fmt.Fprintln(stderr, "snapshot missing config path")
+		return 1
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 1
+	}
+
+	if ropts.File {
+		return rollbackFile(opts, store, pol, pluginName, entry, ropts.Force, stdout, stderr)
+	}
+
+	// A templated entry flattened since the snapshot gets its {env:} or
+	// {file:} form back; restoredSpec stays the expanded value for messages.
+	restoredSpec := entry.PreviousSpec
+	written := entry.PreviousSpec
+	if entry.Template != "" {
+		written = entry.Template
+	}
+	if ropts.Exact {
+		if !npm.IsExactVersion(entry.PreviousInstalled) {
+			fmt.Fprintf(stderr, "snapshot %s did not record an installed version of %s; cannot pin exactly\n", entry.ID, pluginName)
+			return 1
+		}
+		restoredSpec = fmt.Sprintf("%s@%s", pluginName, entry.PreviousInstalled)
+		written = restoredSpec
+	}
+	if entry.Source != string(opencode.SourceDependency) {
+		if violations := specViolations(pol, pluginName, restoredSpec); len(violations) > 0 {
+			fmt.Fprintf(stderr, "refusing to roll back %s to %s: %s\n", pluginName, restoredSpec, policyMessages(violations))
+			return 1
+		}
+	}
+
+	current, err := opencode.FindPluginSpec(entry.ConfigPath, pluginName)
+	if err != nil {
+		if errors.Is(err, opencode.ErrPluginNotFound) {
+			fmt.Fprintf(stderr, "%s is no longer declared in %s\n", pluginName, entry.ConfigPath)
+			if entry.ConfigHash != "" {
+				fmt.Fprintf(stderr, "run `patchline rollback --file --to %s %s` to restore the whole file\n", entry.ID, pluginName)
+			}
+			return 1
+		}
+		fmt.Fprintf(stderr, "failed to read current config: %v\n", err)
+		return 1
+	}
+	if current.Template != "" && !opts.Flatten {
+		printTemplatedRefusal(stderr, pluginName, current.Template, entry.ConfigPath)
+		return 1
+	}
+
+	if opencode.IsInlineConfig(entry.ConfigPath) {
+		var inline inlineUpdates
+		if err := inline.add(opts, pluginName, written); err != nil {
+			fmt.Fprintf(stderr, "failed to update config: %v\n", err)
+			return 1
+		}
+		inline.report(stdout)
+		return 1
+	}
+
+	ctx := context.Background()
+	cacheDir, cacheCandidates := cache.ResolveDir(opts.CacheDir)
+	dependency := entry.Source == string(opencode.SourceDependency)
+	installed := "missing"
+	if dependency {
+		if installedEntry, ok := dependencyEntry(current); ok {
+			installed = installedEntry.Version
+		}
+	} else {
+		installed, err = installedVersion(ctx, cacheDir, pluginName)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+	}
+
+	record, err := store.Save(snapshot.Entry{
+		PluginName:        pluginName,
+		PreviousSpec:      current.DeclaredSpec,
+		Template:          current.Template,
+		PreviousInstalled: installed,
+		Source:            entry.Source,
+		Reason:            "rollback",
+		ConfigPath:        entry.ConfigPath,
+		RestoredFrom:      entry.ID,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to save snapshot: %v\n", err)
+		return 1
+	}
+
+	if err := updatePluginSpec(opts, entry.ConfigPath, pluginName, written); err != nil {
+		fmt.Fprintf(stderr, "failed to update config: %v\n", err)
+		return 1
+	}
+
+	// Dependencies are reinstalled by OpenCode from the updated package.json.
+	if !dependency {
+		if cacheDir != "" {
+			if _, err := cache.Invalidate(ctx, cacheDir, pluginName); err != nil {
+				fmt.Fprintf(stderr, "failed to invalidate cache: %v\n", err)
+				return 1
+			}
+		} else if opts.CacheDir != "" {
+			fmt.Fprintf(stderr, "cache directory not found: %s\n", opts.CacheDir)
+		} else if len(cacheCandidates) > 0 {
+			fmt.Fprintf(stderr, "cache directory not found. Checked: %s\n", strings.Join(cacheCandidates, ", "))
+		}
+	}
+
+	fmt.Fprintf(stdout, "Restored %s to %s (snapshot %s). Run OpenCode to reinstall.\n", pluginName, written, entry.ID)
+	if !ropts.Exact && !reproducesInstalled(restoredSpec, entry.PreviousInstalled) {
+		fmt.Fprintf(stderr, "warning: %s does not pin %s, the version installed when the snapshot was taken; OpenCode may install a different version. Use --exact to pin it.\n", restoredSpec, entry.PreviousInstalled)
+	}
+	fmt.Fprintf(stdout, "Previous state saved as %s; run `patchline rollback --to %s %s` to redo.\n", record.ID, record.ID, pluginName)
+	return 0
+}
+
+// reproducesInstalled reports whether a spec pins exactly the version that was
+// installed. Unknown installed versions are not warned about.
+func reproducesInstalled(spec string, installed string) bool {
+	if !npm.IsExactVersion(installed) {
+		return true
+	}
+	at := strings.LastIndex(spec, "@")
+	if at <= 0 {
+		return false
+	}
+	return strings.TrimPrefix(spec[at+1:], "v") == installed
+}
+
+// rollbackFile restores the byte-exact config backup recorded with an entry.
+// It refuses when the file changed after the snapshot in ways other than the
+// plugin's own entry, unless force is set.
+func rollbackFile(opts CommonOptions, store snapshot.Store, pol policy.Policy, pluginName string, entry snapshot.Entry, force bool, stdout io.Writer, stderr io.Writer) int {
+	if entry.ConfigHash == "" {
+		fmt.Fprintf(stderr, "snapshot %s has no config backup; it was taken before backups were recorded\n", entry.ID)
+		return 1
+	}
+	backup, err := store.Blob(entry.ConfigHash)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load config backup: %v\n", err)
+		return 1
+	}
+
+	path := entry.ConfigPath
+	mode := os.FileMode(0o600)
+	current, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "failed to read %s: %v\n", path, err)
+		return 1
+	}
+	if exists {
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if snapshot.HashBytes(current) == entry.ConfigHash {
+			fmt.Fprintf(stdout, "%s already matches snapshot %s.\n", path, entry.ID)
+			return 0
+		}
+	}
+
+	renderDiff(stdout, "current "+path, "snapshot "+entry.ID, lineDiff(string(current), string(backup)))
+	fmt.Fprintln(stdout, "")
+
+	if exists && !force {
+		same, err := opencode.SameExceptPlugin(backup, current, pluginName)
+		if err != nil || !same {
+			fmt.Fprintf(stderr, "%s changed after snapshot %s beyond the %s entry; re-run with --force to overwrite\n", path, entry.ID, pluginName)
+			return 1
+		}
+	}
+	if entry.Source != string(opencode.SourceDependency) {
+		specs, _ := opencode.ParsePluginSpecs(path, backup)
+		changed := changedPlugins(path, current, backup)
+		for _, spec := range specs {
+			if !slices.Contains(changed, spec.Name) {
+				continue
+			}
+			if violations := specViolations(pol, spec.Name, spec.DeclaredSpec); len(violations) > 0 {
+				fmt.Fprintf(stderr, "refusing to restore %s: %s\n", path, policyMessages(violations))
+				return 1
+			}
+		}
+	}
+
+	ctx := context.Background()
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+	installed, err := installedVersion(ctx, cacheDir, pluginName)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+		return 1
+	}
+	var previous opencode.PluginSpec
+	if exists {
+		if spec, err := opencode.FindPluginSpec(path, pluginName); err == nil {
+			previous = spec
+		}
+	}
+	record, err := store.Save(snapshot.Entry{
+		PluginName:        pluginName,
+		PreviousSpec:      previous.DeclaredSpec,
+		Template:          previous.Template,
+		PreviousInstalled: installed,
+		Source:            entry.Source,
+		Reason:            "rollback",
+		ConfigPath:        path,
+		RestoredFrom:      entry.ID,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to save snapshot: %v\n", err)
+		return 1
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Fprintf(stderr, "failed to restore %s: %v\n", path, err)
+		return 1
+	}
+	if err := atomicfile.Write(path, backup, mode); err != nil {
+		fmt.Fprintf(stderr, "failed to restore %s: %v\n", path, err)
+		return 1
+	}
+
+	if cacheDir != "" {
+		for _, name := range changedPlugins(path, current, backup) {
+			if _, err := cache.Invalidate(ctx, cacheDir, name); err != nil {
+				fmt.Fprintf(stderr, "failed to invalidate cache for %s: %v\n", name, err)
+				return 1
+			}
+		}
+	}
+
+	fmt.Fprintf(stdout, "Restored %s from snapshot %s. Run OpenCode to reinstall.\n", path, entry.ID)
+	if record.ConfigHash != "" {
+		fmt.Fprintf(stdout, "Previous file saved as %s; run `patchline rollback --file --to %s %s` to redo.\n", record.ID, record.ID, pluginName)
+	}
+	return 0
+}
+
+func installedVersion(ctx context.Context, cacheDir string, pluginName string) (string, error) {
+	if cacheDir == "" {
+		return "missing", nil
+	}
+	entries, err := cache.Detect(ctx, cacheDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Name == pluginName {
+			return entry.Version, nil
+		}
+	}
+	return "missing", nil
+}
+
+// changedPlugins returns the plugins whose declared spec differs between two
+// versions of a config file.
+func changedPlugins(path string, before []byte, after []byte) []string {
+	specsOf := func(data []byte) map[string]string {
+		out := map[string]string{}
+		specs, err := opencode.ParsePluginSpecs(path, data)
+		if err != nil {
+			return out
+		}
+		for _, spec := range specs {
+			out[spec.Name] = spec.DeclaredSpec
+		}
+		return out
+	}
+	old := specsOf(before)
+	updated := specsOf(after)
+
+	names := []string{}
+	for name, spec := range updated {
+		if old[name] != spec {
+			names = append(names, name)
+		}
+	}
+	for name := range old {
+		if _, ok := updated[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+func setupFileRollback(t *testing.T, original string) (CommonOptions, string, snapshot.Store) {
+	t.Helper()
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(original), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+	return opts, configPath, snapshot.Store{Directory: opts.SnapshotDir}
+}
+
+func TestRollbackFileRestoresByteExactConfig(t *testing.T) {
+	original := "{\n  // team plugins\n  \"plugin\": [\"alpha@1.0.0\"],\n  \"theme\": \"dark\",\n}\n"
+	opts, configPath, _ := setupFileRollback(t, original)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{File: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(data) != original {
+		t.Fatalf("expected byte-exact restore, got %q", string(data))
+	}
+	if !strings.Contains(stdout.String(), "+   \"plugin\": [\"alpha@1.0.0\"],") {
+		t.Fatalf("expected diff output, got %s", stdout.String())
+	}
+}
+
+func TestRollbackFileDetectsConflicts(t *testing.T) {
+	original := `{"plugin": ["alpha@1.0.0"], "theme": "dark"}`
+	opts, configPath, _ := setupFileRollback(t, original)
+
+	edited := `{"plugin": ["alpha@2.0.0"], "theme": "light"}`
+	if err := os.WriteFile(configPath, []byte(edited), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{File: true}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected conflict, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "--force") {
+		t.Fatalf("expected force hint, got %s", stderr.String())
+	}
+	data, _ := os.ReadFile(configPath)
+	if string(data) != edited {
+		t.Fatalf("expected config untouched, got %s", string(data))
+	}
+
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{File: true, Force: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected forced restore, got %d %s", code, stderr.String())
+	}
+	data, _ = os.ReadFile(configPath)
+	if string(data) != original {
+		t.Fatalf("expected forced restore, got %s", string(data))
+	}
+}
+
+func TestRollbackFileRecordsReplacedTemplate(t *testing.T) {
+	original := `{"plugin": ["alpha@1.0.0"]}`
+	opts, configPath, store := setupFileRollback(t, original)
+	t.Setenv("PATCHLINE_TEST_ALPHA", "2.0.0")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@{env:PATCHLINE_TEST_ALPHA}"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{File: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	record, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if record.PreviousSpec != "alpha@2.0.0" || record.Template != "alpha@{env:PATCHLINE_TEST_ALPHA}" {
+		t.Fatalf("expected the replaced template to be recorded, got %#v", record)
+	}
+	if !strings.Contains(stdout.String(), "saved as "+record.ID+";") {
+		t.Fatalf("expected the record id to be printed, got %s", stdout.String())
+	}
+}
+
+func TestRollbackRemovedEntrySuggestsFileRestore(t *testing.T) {
+	original := `{"plugin": ["alpha@1.0.0", "beta@1.0.0"]}`
+	opts, configPath, store := setupFileRollback(t, original)
+
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected failure, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "--file") {
+		t.Fatalf("expected file restore hint, got %s", stderr.String())
+	}
+
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{File: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected file restore, got %d %s", code, stderr.String())
+	}
+	data, _ := os.ReadFile(configPath)
+	if string(data) != original {
+		t.Fatalf("expected original config, got %s", string(data))
+	}
+
+	record, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if record.Reason != "rollback" || record.ConfigHash != snapshot.HashBytes([]byte(`{"plugin": ["beta@1.0.0"]}`)) {
+		t.Fatalf("expected rollback record with backup of replaced file, got %#v", record)
+	}
+}
+
+func TestRollbackExactPinsPreviousInstalled(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.4.2"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: alpha does not pin 1.4.2") {
+		t.Fatalf("expected reproducibility warning, got %s", stderr.String())
+	}
+
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{To: "1.4.2", Exact: true}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exact rollback failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alpha@1.4.2") {
+		t.Fatalf("expected exact pin, got %s", string(data))
+	}
+	if strings.Contains(stderr.String(), "warning") {
+		t.Fatalf("expected no warning for exact rollback, got %s", stderr.String())
+	}
+}
+
+func TestReproducesInstalled(t *testing.T) {
+	cases := []struct {
+		spec      string
+		installed string
+		want      bool
+	}{
+		{"alpha@1.4.2", "1.4.2", true},
+		{"alpha", "1.4.2", false},
+		{"alpha@^1.4.0", "1.4.2", false},
+		{"alpha@latest", "1.4.2", false},
+		{"@scope/alpha@1.4.2", "1.4.2", true},
+		{"alpha", "missing", true},
+	}
+	for _, tc := range cases {
+		if got := reproducesInstalled(tc.spec, tc.installed); got != tc.want {
+			t.Fatalf("reproducesInstalled(%q, %q) = %v, want %v", tc.spec, tc.installed, got, tc.want)
+		}
+	}
+}
+
+func TestRollbackFollowsMovedProject(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	oldProject := filepath.Join(root, "old")
+	writeTestFile(t, filepath.Join(oldProject, "opencode.json"), `{"plugin": ["alpha@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: oldProject, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "alpha", "2.0.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("upgrade failed: %d %s", code, stderr.String())
+	}
+
+	newProject := filepath.Join(root, "new")
+	if err := os.Rename(oldProject, newProject); err != nil {
+		t.Fatalf("move project: %v", err)
+	}
+	opts.ProjectRoot = newProject
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 0 {
+		t.Fatalf("rollback failed: %d %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(filepath.Join(newProject, "opencode.json"))
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alpha@1.0.0") {
+		t.Fatalf("expected moved config restored, got %s", string(data))
+	}
+}
+
+func TestRestoreRemapsConfigPaths(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	project := filepath.Join(root, "project")
+	writeTestFile(t, filepath.Join(project, "opencode.json"), `{}`)
+	sharedConfig := filepath.Join(root, "shared", "opencode.json")
+	writeTestFile(t, sharedConfig, `{"plugin": ["alpha@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: project, GlobalConfig: sharedConfig, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+
+	movedConfig := filepath.Join(root, "elsewhere", "opencode.json")
+	writeTestFile(t, movedConfig, `{"plugin": ["alpha@2.0.0"]}`)
+	if err := os.RemoveAll(filepath.Dir(sharedConfig)); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	opts.GlobalConfig = ""
+
+	if code := restoreCommand(opts, "before", nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected restore to fail without remap, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "--remap") {
+		t.Fatalf("expected remap hint, got %s", stderr.String())
+	}
+
+	remap := snapshot.Remap{Old: filepath.Dir(sharedConfig), New: filepath.Dir(movedConfig)}
+	stderr.Reset()
+	if code := restoreCommand(opts, "before", []snapshot.Remap{remap}, &stdout, &stderr); code != 0 {
+		t.Fatalf("restore failed: %d %s", code, stderr.String())
+	}
+	data, _ := os.ReadFile(movedConfig)
+	if !strings.Contains(string(data), "alpha@1.0.0") {
+		t.Fatalf("expected remapped config restored, got %s", string(data))
+	}
+}
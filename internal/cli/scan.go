@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/AksharP5/Patchline/internal/opencode"
+)
+
+type scannedProject struct {
+	Dir     string `json:"dir"`
+	Config  string `json:"config"`
+	Plugins int    `json:"plugins"`
+	Error   string `json:"error,omitempty"`
+}
+
+// scanRoot returns the directory a recursive run starts from.
+func scanRoot(opts CommonOptions) (string, error) {
+	if opts.ProjectRoot != "" {
+		return opts.ProjectRoot, nil
+	}
+	return os.Getwd()
+}
+
+// projectDirOf returns the project directory that owns a config file. Configs
+// inside .opencode belong to the parent directory.
+func projectDirOf(config string) string {
+	dir := filepath.Dir(config)
+	if filepath.Base(dir) == ".opencode" {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+func displayDir(root string, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return dir
+	}
+	return rel
+}
+
+// forEachProject runs a command once per project config under the scan root,
+// grouping the output under a header per project. It returns the highest exit
+// code.
+func forEachProject(opts CommonOptions, stdout io.Writer, stderr io.Writer, run func(CommonOptions) int) int {
+	root, err := scanRoot(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve scan root: %v\n", err)
+		return 1
+	}
+	configs, err := opencode.FindProjects(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan projects: %v\n", err)
+		return 1
+	}
+	if len(configs) == 0 {
+		fmt.Fprintf(stderr, "no project configs found under %s\n", root)
+		return 1
+	}
+
+	code := 0
+	for i, config := range configs {
+		if i > 0 {
+			fmt.Fprintln(stdout, "")
+		}
+		dir := projectDirOf(config)
+		fmt.Fprintf(stdout, "== %s (%s) ==\n", displayDir(root, dir), filepath.Base(config))
+		projectOpts := opts
+		projectOpts.ProjectRoot = dir
+		projectOpts.Recursive = false
+		if result := run(projectOpts); result > code {
+			code = result
+		}
+	}
+	return code
+}
+
+// recursiveUpgrade upgrades a plugin in every project that declares it.
+func recursiveUpgrade(opts CommonOptions, name string, stdout io.Writer, stderr io.Writer, run func(CommonOptions) int) int {
+	found := 0
+	code := forEachProject(opts, stdout, stderr, func(projectOpts CommonOptions) int {
+		if name != "" && !declaresPlugin(projectOpts, name) {
+			fmt.Fprintf(stdout, "%s is not declared here; skipped.\n", name)
+			return 0
+		}
+		found++
+		return run(projectOpts)
+	})
+	if found == 0 && code == 0 {
+		fmt.Fprintf(stderr, "plugin not found in any project: %s\n", name)
+		return 1
+	}
+	return code
+}
+
+func declaresPlugin(opts CommonOptions, name string) bool {
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		return true
+	}
+	for _, spec := range effectivePlugins(result.Plugins) {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scanCommand lists every project config below root.
+func scanCommand(opts CommonOptions, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	root, err := scanRoot(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to resolve scan root: %v\n", err)
+		return 1
+	}
+	configs, err := opencode.FindProjects(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan projects: %v\n", err)
+		return 1
+	}
+
+	projects := make([]scannedProject, 0, len(configs))
+	for _, config := range configs {
+		project := scannedProject{Dir: projectDirOf(config), Config: config}
+		data, err := os.ReadFile(config)
+		if err == nil {
+			var specs []opencode.PluginSpec
+			specs, err = opencode.ParsePluginSpecs(config, data)
+			project.Plugins = len(specs)
+		}
+		if err != nil {
+			project.Error = err.Error()
+		}
+		projects = append(projects, project)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(projects); err != nil {
+			fmt.Fprintf(stderr, "failed to encode projects: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(projects) == 0 {
+		fmt.Fprintf(stdout, "No project configs found under %s.\n", root)
+		return 0
+	}
+
+	headers := []string{"PROJECT", "CONFIG", "PLUGINS"}
+	rows := make([][]string, 0, len(projects))
+	for _, project := range projects {
+		plugins := strconv.Itoa(project.Plugins)
+		if project.Error != "" {
+			plugins = "error: " + project.Error
+		}
+		rows = append(rows, []string{displayDir(root, project.Dir), displayDir(root, project.Config), plugins})
+	}
+	renderTable(stdout, headers, rows)
+	fmt.Fprintln(stdout, "")
+	fmt.Fprintf(stdout, "Found %d project(s). Pass --recursive to list, outdated, sync or upgrade to run across them.\n", len(projects))
+	return 0
+}
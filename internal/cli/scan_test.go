@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupMonorepo(t *testing.T) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	writeTestFile(t, filepath.Join(root, "repo", ".gitignore"), "scratch/\n")
+	writeTestFile(t, filepath.Join(root, "repo", "packages", "web", "opencode.json"), `{"plugin": ["alpha@1.0.0"]}`)
+	writeTestFile(t, filepath.Join(root, "repo", "packages", "api", ".opencode", "opencode.jsonc"), `{"plugin": ["beta@1.0.0"]}`)
+	writeTestFile(t, filepath.Join(root, "repo", "scratch", "opencode.json"), `{"plugin": ["ignored@1.0.0"]}`)
+	writeTestFile(t, filepath.Join(root, "repo", "node_modules", "pkg", "opencode.json"), `{"plugin": ["ignored@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	return filepath.Join(root, "repo"), cacheDir
+}
+
+func TestScanCommandListsProjects(t *testing.T) {
+	repo, _ := setupMonorepo(t)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := scanCommand(CommonOptions{ProjectRoot: repo}, false, &stdout, &stderr); code != 0 {
+		t.Fatalf("scan failed: %d %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, filepath.Join("packages", "web")) || !strings.Contains(out, filepath.Join("packages", "api", ".opencode", "opencode.jsonc")) {
+		t.Fatalf("expected both projects, got %s", out)
+	}
+	if strings.Contains(out, "scratch") || strings.Contains(out, "node_modules") {
+		t.Fatalf("expected ignored directories to be skipped, got %s", out)
+	}
+	if !strings.Contains(out, "Found 2 project(s)") {
+		t.Fatalf("expected project count, got %s", out)
+	}
+}
+
+func TestRecursiveListGroupsByProject(t *testing.T) {
+	repo, cacheDir := setupMonorepo(t)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := runList([]string{"--recursive", "--project", repo, "--cache-dir", cacheDir}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("recursive list failed: %d %s", code, stderr.String())
+	}
+	out := stdout.String()
+	api := strings.Index(out, "== "+filepath.Join("packages", "api")+" (opencode.jsonc) ==")
+	web := strings.Index(out, "== "+filepath.Join("packages", "web")+" (opencode.json) ==")
+	if api < 0 || web < 0 || api > web {
+		t.Fatalf("expected grouped output, got %s", out)
+	}
+	if beta := strings.Index(out, "beta"); beta < api || beta > web {
+		t.Fatalf("expected beta under the api project, got %s", out)
+	}
+	if alpha := strings.LastIndex(out, "alpha"); alpha < web {
+		t.Fatalf("expected alpha under the web project, got %s", out)
+	}
+}
+
+func TestRecursiveUpgradeSkipsProjectsWithoutPlugin(t *testing.T) {
+	repo, cacheDir := setupMonorepo(t)
+	snapshotDir := filepath.Join(filepath.Dir(repo), "data", "snapshots")
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	args := []string{"--recursive", "--project", repo, "--cache-dir", cacheDir, "--snapshot-dir", snapshotDir, "--to", "2.0.0", "alpha"}
+	if code := runUpgrade(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("recursive upgrade failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "alpha is not declared here; skipped.") {
+		t.Fatalf("expected api project to be skipped, got %s", stdout.String())
+	}
+	data, err := os.ReadFile(filepath.Join(repo, "packages", "web", "opencode.json"))
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), "alpha@2.0.0") {
+		t.Fatalf("expected web project upgraded, got %s", string(data))
+	}
+
+	stderr.Reset()
+	args = []string{"--recursive", "--project", repo, "--cache-dir", cacheDir, "--snapshot-dir", snapshotDir, "--to", "2.0.0", "missing"}
+	if code := runUpgrade(args, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected unknown plugin to fail, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "plugin not found in any project") {
+		t.Fatalf("expected not found message, got %s", stderr.String())
+	}
+}
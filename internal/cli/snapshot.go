@@ -2,17 +2,25 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/AksharP5/Patchline/internal/atomicfile"
 	"github.com/AksharP5/Patchline/internal/cache"
 	"github.com/AksharP5/Patchline/internal/opencode"
 	"github.com/AksharP5/Patchline/internal/snapshot"
 )
 
-func snapshotCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
+func snapshotCommand(opts CommonOptions, name string, stdout io.Writer, stderr io.Writer) int {
 	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
 	if err != nil {
 		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
@@ -48,46 +56,224 @@ func snapshotCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int
 		installedByName[entry.Name] = entry
 	}
 
-	store := snapshot.Store{Directory: snapshotDir}
-	saved := 0
-	localCount := 0
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	store.Roots = snapshotRoots(opts, nil)
+	entries := []snapshot.Entry{}
 	for _, spec := range result.Plugins {
 		if spec.Source == opencode.SourceLocal {
-			localCount++
+			// A copy of the entry file alone cannot bring a folder back.
+			if spec.LocalDir != "" {
+				fmt.Fprintf(stderr, "skipping local plugin folder %s; only single-file plugins can be snapshotted\n", spec.LocalDir)
+				continue
+			}
+			entries = append(entries, localSnapshotEntry(spec, "snapshot"))
 			continue
 		}
 		installed := "missing"
-		if entry, ok := installedByName[spec.Name]; ok {
+		entry, ok := installedByName[spec.Name]
+		if spec.Source == opencode.SourceDependency {
+			entry, ok = dependencyEntry(spec)
+		}
+		if ok {
 			installed = entry.Version
 		}
-		err := store.Save(snapshot.Entry{
+		entries = append(entries, snapshot.Entry{
 			PluginName:        spec.Name,
 			PreviousSpec:      spec.DeclaredSpec,
+			Template:          spec.Template,
 			PreviousInstalled: installed,
 			Source:            string(spec.Source),
 			Reason:            "snapshot",
 			ConfigPath:        spec.ConfigPath,
 		})
-		if err != nil {
-			fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", spec.Name, err)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No plugins found to snapshot.")
+		return 0
+	}
+
+	if name != "" {
+		if err := store.SaveSet(snapshot.Set{Name: name, Entries: entries}); err != nil {
+			fmt.Fprintf(stderr, "failed to save snapshot %s: %v\n", name, err)
 			return 1
 		}
-		saved++
+		fmt.Fprintf(stdout, "Saved snapshot %q with %d plugin(s) to %s.\n", name, len(entries), snapshotDir)
+	} else {
+		for _, entry := range entries {
+			if _, err := store.Save(entry); err != nil {
+				fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", entry.PluginName, err)
+				return 1
+			}
+		}
+		fmt.Fprintf(stdout, "Saved %d snapshot(s) to %s.\n", len(entries), snapshotDir)
+	}
+	return 0
+}
+
+func snapshotListCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	sets, err := store.ListSets()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshots: %v\n", err)
+		return 1
+	}
+	if len(sets) == 0 {
+		fmt.Fprintln(stdout, "No named snapshots found.")
+		return 0
+	}
+
+	headers := []string{"NAME", "CREATED", "PLUGINS"}
+	rows := make([][]string, 0, len(sets))
+	for i := len(sets) - 1; i >= 0; i-- {
+		rows = append(rows, []string{
+			sets[i].Name,
+			sets[i].Timestamp.Local().Format("2006-01-02 15:04:05"),
+			strconv.Itoa(len(sets[i].Entries)),
+		})
+	}
+	renderTable(stdout, headers, rows)
+	return 0
+}
+
+type pruneOverrides struct {
+	KeepLast *int
+	MaxAge   *string
+	DryRun   bool
+}
+
+func snapshotPruneCommand(opts CommonOptions, overrides pruneOverrides, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+
+	retention := store.Retention
+	if overrides.KeepLast != nil || overrides.MaxAge != nil {
+		retention = snapshot.Retention{}
+	}
+	if overrides.KeepLast != nil {
+		retention.KeepLast = *overrides.KeepLast
+	}
+	if overrides.MaxAge != nil {
+		age, err := snapshot.ParseAge(*overrides.MaxAge)
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid --max-age: %v\n", err)
+			return 2
+		}
+		retention.MaxAge = age
+	}
+	if !retention.Enabled() {
+		fmt.Fprintln(stdout, "Retention keeps every snapshot; nothing to prune.")
+		return 0
 	}
 
-	if saved == 0 {
-		fmt.Fprintln(stdout, "No npm plugins found to snapshot.")
+	result, err := store.Prune(retention, time.Now(), overrides.DryRun)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to prune snapshots: %v\n", err)
+		return 1
+	}
+	if result.Total() == 0 {
+		fmt.Fprintln(stdout, "No snapshots to prune.")
 		return 0
 	}
 
-	fmt.Fprintf(stdout, "Saved %d snapshot(s) to %s.\n", saved, snapshotDir)
-	if localCount > 0 {
-		fmt.Fprintln(stdout, "Local plugins are unmanaged and were skipped.")
+	names := make([]string, 0, len(result.Removed))
+	for name := range result.Removed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	verb := "Removed"
+	if overrides.DryRun {
+		verb = "Would remove"
+	}
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s %d snapshot(s) of %s\n", verb, len(result.Removed[name]), name)
+	}
+	fmt.Fprintln(stdout, "")
+	fmt.Fprintf(stdout, "%s %d snapshot(s). Named snapshots are always kept.\n", verb, result.Total())
+	return 0
+}
+
+// snapshotFsckCommand validates the snapshot store and exits non-zero while
+// problems remain.
+func snapshotFsckCommand(opts CommonOptions, repair bool, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+
+	result, err := snapshot.Store{Directory: snapshotDir}.Fsck(repair)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to check snapshots: %v\n", err)
+		return 1
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintf(stderr, "failed to encode result: %v\n", err)
+			return 1
+		}
+	} else {
+		if len(result.Problems) > 0 {
+			headers := []string{"FILE", "STATUS", "PROBLEM"}
+			rows := make([][]string, 0, len(result.Problems))
+			for _, problem := range result.Problems {
+				status := "error"
+				if problem.Repaired {
+					status = "repaired"
+				}
+				path := problem.Path
+				if rel, err := filepath.Rel(snapshotDir, path); err == nil {
+					path = rel
+				}
+				rows = append(rows, []string{path, status, problem.Message})
+			}
+			renderTable(stdout, headers, rows)
+			fmt.Fprintln(stdout, "")
+		}
+		fmt.Fprintf(stdout, "Checked %d file(s) with %d entr(ies): %d problem(s).\n", result.Files, result.Entries, len(result.Problems))
+		if result.Unrepaired() > 0 && !repair {
+			fmt.Fprintln(stdout, "Run `patchline snapshot fsck --repair` to salvage corrupt history files.")
+		}
+	}
+
+	if result.Unrepaired() > 0 {
+		return 1
 	}
 	return 0
 }
 
-func rollbackCommand(opts CommonOptions, pluginName string, stdout io.Writer, stderr io.Writer) int {
+// restoreCommand puts the plugin lists of every config in a named snapshot
+// back the way they were: changed specs are restored, plugins added since the
+// snapshot are removed and plugins removed since are declared again. Every
+// change is checked before the first write, and files already written are
+// put back if a later write fails, so the snapshot is restored whole or not
+// at all.
+func restoreCommand(opts CommonOptions, name string, remaps []snapshot.Remap, stdout io.Writer, stderr io.Writer) int {
 	snapshotDir, candidates := snapshot.ResolveDir(opts.SnapshotDir)
 	if snapshotDir == "" {
 		fmt.Fprintln(stderr, "snapshot directory not found")
@@ -97,39 +283,859 @@ func rollbackCommand(opts CommonOptions, pluginName string, stdout io.Writer, st
 		fmt.Fprintf(stderr, "Using snapshot directory: %s\n", snapshotDir)
 	}
 
-	store := snapshot.Store{Directory: snapshotDir}
-	entry, err := store.Latest(pluginName)
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	store.Roots = snapshotRoots(opts, remaps)
+	set, err := store.LoadSet(name)
 	if err != nil {
 		if errors.Is(err, snapshot.ErrSnapshotNotFound) {
-			fmt.Fprintf(stderr, "no snapshot found for %s\n", pluginName)
+			fmt.Fprintf(stderr, "no snapshot named %s\n", name)
 			return 1
 		}
 		fmt.Fprintf(stderr, "failed to load snapshot: %v\n", err)
 		return 1
 	}
-	if entry.ConfigPath == "" {
-		fmt.Fprintln(stderr, "snapshot missing config path")
+	for i := range set.Entries {
+		set.Entries[i].ConfigPath = store.Roots.Resolve(set.Entries[i])
+	}
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
 		return 1
 	}
 
-	if err := opencode.UpdatePluginSpec(entry.ConfigPath, pluginName, entry.PreviousSpec); err != nil {
-		fmt.Fprintf(stderr, "failed to update config: %v\n", err)
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 1
+	}
+
+	plan, problems := planRestore(opts, store, set, stderr)
+	for _, change := range plan.changes() {
+		if change.Restored == "" || change.Source == string(opencode.SourceDependency) {
+			continue
+		}
+		if violations := specViolations(pol, change.Name, change.Restored); len(violations) > 0 {
+			fmt.Fprintf(stderr, "refusing to restore %s to %s: %s\n", change.Name, change.Restored, policyMessages(violations))
+			problems++
+		}
+	}
+	if problems > 0 {
+		fmt.Fprintln(stderr, "snapshot not restored; no files were changed")
+		if len(remaps) == 0 {
+			fmt.Fprintln(stderr, "if the configs moved, pass --remap old=new to point at their new location")
+		}
 		return 1
 	}
 
 	ctx := context.Background()
-	cacheDir, cacheCandidates := cache.ResolveDir(opts.CacheDir)
+	cacheDir, _ := cache.ResolveDir(opts.CacheDir)
+	installedByName := map[string]cache.Entry{}
 	if cacheDir != "" {
-		if _, err := cache.Invalidate(ctx, cacheDir, pluginName); err != nil {
-			fmt.Fprintf(stderr, "failed to invalidate cache: %v\n", err)
+		entries, err := cache.Detect(ctx, cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
 			return 1
 		}
-	} else if opts.CacheDir != "" {
-		fmt.Fprintf(stderr, "cache directory not found: %s\n", opts.CacheDir)
-	} else if len(cacheCandidates) > 0 {
-		fmt.Fprintf(stderr, "cache directory not found. Checked: %s\n", strings.Join(cacheCandidates, ", "))
+		for _, entry := range entries {
+			installedByName[entry.Name] = entry
+		}
+	}
+
+	backups, err := backupFiles(plan.paths())
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to back up configs: %v\n", err)
+		return 1
+	}
+	changes := plan.changes()
+	for _, change := range changes {
+		if change.Current == "" {
+			continue
+		}
+		installed := "missing"
+		if cached, ok := installedByName[change.Name]; ok {
+			installed = cached.Version
+		}
+		_, err := store.Save(snapshot.Entry{
+			PluginName:        change.Name,
+			PreviousSpec:      change.Current,
+			Template:          change.Template,
+			PreviousInstalled: installed,
+			Source:            change.Source,
+			Reason:            "restore",
+			ConfigPath:        change.Path,
+			RestoredFrom:      change.EntryID,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to save snapshot for %s: %v\n", change.Name, err)
+			return 1
+		}
+	}
+
+	locals, err := applyRestore(opts, store, plan)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to restore snapshot %q: %v\n", name, err)
+		if err := revertFiles(backups); err != nil {
+			fmt.Fprintf(stderr, "failed to revert the files already written: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stderr, "reverted the files already written; no files were changed")
+		return 1
+	}
+
+	for _, change := range changes {
+		if change.Source == string(opencode.SourceDependency) || cacheDir == "" {
+			continue
+		}
+		if _, err := cache.Invalidate(ctx, cacheDir, change.Name); err != nil {
+			fmt.Fprintf(stderr, "failed to invalidate cache for %s: %v\n", change.Name, err)
+			return 1
+		}
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Restored == "":
+			fmt.Fprintf(stdout, "Removed %s from %s; it was added after the snapshot\n", change.Name, change.Path)
+		case change.Current == "":
+			fmt.Fprintf(stdout, "Declared %s again in %s -> %s\n", change.Name, change.Path, change.Restored)
+		default:
+			fmt.Fprintf(stdout, "Restored %s -> %s\n", change.Name, change.Restored)
+		}
+	}
+	for _, entry := range locals {
+		fmt.Fprintf(stdout, "Restored local plugin %s (%s)\n", entry.PluginName, entry.LocalPath)
+	}
+
+	pending := plan.Inline.report(stdout)
+	restored := len(changes) + len(locals)
+	if restored == 0 {
+		if pending {
+			return 1
+		}
+		fmt.Fprintf(stdout, "All plugins already match snapshot %q.\n", name)
+		return 0
+	}
+	fmt.Fprintln(stdout, "")
+	fmt.Fprintf(stdout, "Restored %d plugin(s) from snapshot %q. Run OpenCode to reinstall.\n", restored, name)
+	if pending {
+		return 1
+	}
+	return 0
+}
+
+// restorePlan is everything a restore changes. Configs have their plugin
+// lists replaced whole; Specs are package.json dependencies, which are moved
+// back one by one.
+type restorePlan struct {
+	Configs []restoreConfig
+	Specs   []restoreChange
+	Locals  []snapshot.Entry
+	Inline  inlineUpdates
+}
+
+// restoreConfig is a config file whose plugin lists are set to those of
+// Snapshot.
+type restoreConfig struct {
+	Path     string
+	Snapshot []byte
+	Changes  []restoreChange
+}
+
+// restoreChange is one plugin a restore changes. Current is empty for a
+// plugin removed since the snapshot and Restored is empty for one added since.
+type restoreChange struct {
+	Name     string
+	Path     string
+	Source   string
+	Current  string
+	Template string
+	Restored string
+	EntryID  string
+}
+
+func (p restorePlan) changes() []restoreChange {
+	changes := []restoreChange{}
+	for _, config := range p.Configs {
+		changes = append(changes, config.Changes...)
+	}
+	return append(changes, p.Specs...)
+}
+
+func (p restorePlan) paths() []string {
+	paths := []string{}
+	for _, config := range p.Configs {
+		paths = append(paths, config.Path)
+	}
+	for _, change := range p.Specs {
+		paths = append(paths, change.Path)
+	}
+	for _, entry := range p.Locals {
+		paths = append(paths, entry.LocalPath)
+	}
+	return paths
+}
+
+// planRestore works out the changes a restore makes without writing
+// anything. Problems are printed and counted. Only configs the snapshot
+// captured are planned; a config that held no plugins then is left alone.
+func planRestore(opts CommonOptions, store snapshot.Store, set snapshot.Set, stderr io.Writer) (restorePlan, int) {
+	plan := restorePlan{}
+	problems := 0
+	paths := []string{}
+	byPath := map[string][]snapshot.Entry{}
+	sources := map[string]string{}
+	for _, entry := range set.Entries {
+		switch {
+		case entry.LocalPath != "":
+			if entry.LocalHash == "" {
+				fmt.Fprintf(stderr, "cannot restore %s: snapshot has no copy of %s\n", entry.PluginName, entry.LocalPath)
+				problems++
+				continue
+			}
+			plan.Locals = append(plan.Locals, entry)
+		case entry.Source == string(opencode.SourceDependency) || opencode.IsInlineConfig(entry.ConfigPath):
+			if !planSpecRestore(opts, &plan, entry, stderr) {
+				problems++
+			}
+		default:
+			if _, ok := byPath[entry.ConfigPath]; !ok {
+				paths = append(paths, entry.ConfigPath)
+				sources[entry.ConfigPath] = entry.Source
+			}
+			byPath[entry.ConfigPath] = append(byPath[entry.ConfigPath], entry)
+		}
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		config, ok := planConfigRestore(opts, store, path, sources[path], byPath[path], stderr)
+		if !ok {
+			problems++
+			continue
+		}
+		if len(config.Changes) > 0 {
+			plan.Configs = append(plan.Configs, config)
+		}
+	}
+	return plan, problems
+}
+
+// planConfigRestore compares a config file with its copy in the snapshot. A
+// missing file is only written back when its directory still exists;
+// otherwise the config most likely moved and needs --remap.
+func planConfigRestore(opts CommonOptions, store snapshot.Store, path string, source string, entries []snapshot.Entry, stderr io.Writer) (restoreConfig, bool) {
+	content, err := snapshotConfigContent(store, entries)
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot restore %s: %v\n", path, err)
+		return restoreConfig{}, false
+	}
+	restored, err := opencode.ParsePluginSpecs(path, content)
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot restore %s: %v\n", path, err)
+		return restoreConfig{}, false
+	}
+
+	declared := []opencode.PluginSpec{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		declared, err = opencode.ParsePluginSpecs(path, data)
+		if err != nil {
+			fmt.Fprintf(stderr, "cannot restore %s: %v\n", path, err)
+			return restoreConfig{}, false
+		}
+	case os.IsNotExist(err) && isDir(filepath.Dir(path)):
+	default:
+		fmt.Fprintf(stderr, "cannot restore %s: %v\n", path, err)
+		return restoreConfig{}, false
+	}
+
+	entryIDs := map[string]string{}
+	for _, entry := range entries {
+		entryIDs[entry.PluginName] = entry.ID
+	}
+	currentByName := map[string]opencode.PluginSpec{}
+	for _, spec := range declared {
+		currentByName[spec.Name] = spec
+	}
+	config := restoreConfig{Path: path, Snapshot: content}
+	ok := true
+	for _, spec := range restored {
+		change := restoreChange{Name: spec.Name, Path: path, Source: source, Restored: spec.DeclaredSpec, EntryID: entryIDs[spec.Name]}
+		if spec.Template != "" {
+			change.Restored = spec.Template
+		}
+		if current, found := currentByName[spec.Name]; found {
+			delete(currentByName, spec.Name)
+			if current.DeclaredSpec == spec.DeclaredSpec && current.Template == spec.Template {
+				continue
+			}
+			if current.Template != "" && !opts.Flatten {
+				printTemplatedRefusal(stderr, spec.Name, current.Template, path)
+				ok = false
+				continue
+			}
+			change.Current = current.DeclaredSpec
+			change.Template = current.Template
+		}
+		config.Changes = append(config.Changes, change)
+	}
+	for _, spec := range declared {
+		if _, found := currentByName[spec.Name]; !found {
+			continue
+		}
+		config.Changes = append(config.Changes, restoreChange{Name: spec.Name, Path: path, Source: source, Current: spec.DeclaredSpec, Template: spec.Template})
+	}
+	return config, ok
+}
+
+// snapshotConfigContent returns the copy of a config taken with the snapshot.
+// Snapshots taken before config copies were recorded get a plugin list built
+// from their entries.
+func snapshotConfigContent(store snapshot.Store, entries []snapshot.Entry) ([]byte, error) {
+	specs := []string{}
+	for _, entry := range entries {
+		if entry.ConfigHash != "" {
+			return store.Blob(entry.ConfigHash)
+		}
+		spec := entry.PreviousSpec
+		if entry.Template != "" {
+			spec = entry.Template
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string][]string{"plugin": specs})
+}
+
+// planSpecRestore plans the restore of a package.json dependency or a plugin
+// declared in OPENCODE_CONFIG_CONTENT, which are moved back one by one.
+func planSpecRestore(opts CommonOptions, plan *restorePlan, entry snapshot.Entry, stderr io.Writer) bool {
+	current, err := opencode.FindPluginSpec(entry.ConfigPath, entry.PluginName)
+	if err != nil {
+		fmt.Fprintf(stderr, "cannot restore %s in %s: %v\n", entry.PluginName, entry.ConfigPath, err)
+		return false
+	}
+	if current.DeclaredSpec == entry.PreviousSpec {
+		return true
+	}
+	if current.Template != "" && !opts.Flatten {
+		printTemplatedRefusal(stderr, entry.PluginName, current.Template, entry.ConfigPath)
+		return false
+	}
+	if opencode.IsInlineConfig(entry.ConfigPath) {
+		if err := plan.Inline.add(opts, entry.PluginName, entry.PreviousSpec); err != nil {
+			fmt.Fprintf(stderr, "cannot restore %s in %s: %v\n", entry.PluginName, entry.ConfigPath, err)
+			return false
+		}
+		return true
+	}
+	plan.Specs = append(plan.Specs, restoreChange{
+		Name:     entry.PluginName,
+		Path:     entry.ConfigPath,
+		Source:   entry.Source,
+		Current:  current.DeclaredSpec,
+		Template: current.Template,
+		Restored: entry.PreviousSpec,
+		EntryID:  entry.ID,
+	})
+	return true
+}
+
+// applyRestore writes a planned restore and returns the local plugin files
+// it changed. It stops at the first failure.
+func applyRestore(opts CommonOptions, store snapshot.Store, plan restorePlan) ([]snapshot.Entry, error) {
+	for _, config := range plan.Configs {
+		if err := opencode.RestorePluginLists(config.Path, config.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+	for _, change := range plan.Specs {
+		if err := updatePluginSpec(opts, change.Path, change.Name, change.Restored); err != nil {
+			return nil, fmt.Errorf("update %s: %w", change.Name, err)
+		}
+	}
+	locals := []snapshot.Entry{}
+	for _, entry := range plan.Locals {
+		_, changed, err := restoreLocalFile(store, entry, "restore")
+		if err != nil {
+			return nil, fmt.Errorf("restore %s: %w", entry.LocalPath, err)
+		}
+		if changed {
+			locals = append(locals, entry)
+		}
+	}
+	return locals, nil
+}
+
+// fileBackup is a file's content before a change that spans several files.
+type fileBackup struct {
+	Path   string
+	Data   []byte
+	Exists bool
+	Mode   os.FileMode
+}
+
+func backupFiles(paths []string) ([]fileBackup, error) {
+	backups := []fileBackup{}
+	seen := map[string]struct{}{}
+	for _, path := range paths {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				backups = append(backups, fileBackup{Path: path})
+				continue
+			}
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, fileBackup{Path: path, Data: data, Exists: true, Mode: info.Mode().Perm()})
+	}
+	return backups, nil
+}
+
+// revertFiles puts backed-up files back and removes those that did not exist.
+func revertFiles(backups []fileBackup) error {
+	var errs []error
+	for _, backup := range backups {
+		if !backup.Exists {
+			if err := os.Remove(backup.Path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := atomicfile.Write(backup.Path, backup.Data, backup.Mode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func historyCommand(opts CommonOptions, pluginName string, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	entries, err := store.History(pluginName)
+	if err != nil {
+		if errors.Is(err, snapshot.ErrSnapshotNotFound) {
+			fmt.Fprintf(stderr, "no snapshot found for %s\n", pluginName)
+			return 1
+		}
+		fmt.Fprintf(stderr, "failed to load snapshots: %v\n", err)
+		return 1
+	}
+
+	headers := []string{"ID", "TIMESTAMP", "REASON", "SPEC", "INSTALLED"}
+	rows := make([][]string, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		reason := entry.Reason
+		if entry.RestoredFrom != "" {
+			reason = fmt.Sprintf("%s to %s", reason, entry.RestoredFrom)
+		}
+		if entry.Set != "" {
+			reason = fmt.Sprintf("%s (%s)", reason, entry.Set)
+		}
+		rows = append(rows, []string{
+			entry.ID,
+			entry.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			reason,
+			entry.PreviousSpec,
+			entry.PreviousInstalled,
+		})
+	}
+	renderTable(stdout, headers, rows)
+	return 0
+}
+
+// Snapshot diff change kinds.
+const (
+	changeAdded     = "added"
+	changeRemoved   = "removed"
+	changeChanged   = "changed"
+	changeUnchanged = "unchanged"
+)
+
+type valueChange struct {
+	Snapshot string `json:"snapshot"`
+	Current  string `json:"current"`
+}
+
+func (v valueChange) changed() bool {
+	return v.Snapshot != v.Current
+}
+
+type pluginChange struct {
+	Plugin    string      `json:"plugin"`
+	Change    string      `json:"change"`
+	Snapshot  string      `json:"snapshot,omitempty"`
+	Spec      valueChange `json:"spec"`
+	Installed valueChange `json:"installed"`
+	Config    valueChange `json:"config"`
+}
+
+type snapshotDiffReport struct {
+	Target  string         `json:"target"`
+	Plugins []pluginChange `json:"plugins"`
+}
+
+// snapshotDiffCommand compares a named snapshot, a plugin's snapshot, or the
+// latest snapshot of every plugin with the currently discovered state.
+func snapshotDiffCommand(opts CommonOptions, target string, ref string, remaps []snapshot.Remap, asJSON bool, stdout io.Writer, stderr io.Writer) int {
+	snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+	if snapshotDir == "" {
+		fmt.Fprintln(stderr, "snapshot directory not found")
+		return 1
+	}
+	store := snapshot.Store{Directory: snapshotDir}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+	installedByName := map[string]cache.Entry{}
+	if cacheDir, _ := cache.ResolveDir(opts.CacheDir); cacheDir != "" {
+		entries, err := cache.Detect(context.Background(), cacheDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+			return 1
+		}
+		for _, entry := range entries {
+			installedByName[entry.Name] = entry
+		}
+	}
+
+	if target == "" {
+		sets, err := store.ListSets()
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to load snapshots: %v\n", err)
+			return 1
+		}
+		if len(sets) > 0 {
+			target = sets[len(sets)-1].Name
+		}
+	}
+	// Local plugins, dependencies and linked checkouts are snapshotted too,
+	// so every discovered spec takes part in the diff.
+	current := result.Plugins
+	declared := map[string]bool{}
+	for _, spec := range current {
+		declared[spec.Name] = true
 	}
 
-	fmt.Fprintf(stdout, "Restored %s to %s. Run OpenCode to reinstall.\n", pluginName, entry.PreviousSpec)
+	entries, whole, err := snapshotDiffEntries(store, target, ref, declared)
+	if err != nil {
+		switch {
+		case errors.Is(err, snapshot.ErrSnapshotNotFound) && target != "":
+			fmt.Fprintf(stderr, "no snapshot named or recorded for %s\n", target)
+		case errors.Is(err, snapshot.ErrSnapshotNotFound) || errors.Is(err, snapshot.ErrAmbiguousSnapshot):
+			fmt.Fprintf(stderr, "%v\n", err)
+		default:
+			fmt.Fprintf(stderr, "failed to load snapshots: %v\n", err)
+		}
+		return 1
+	}
+
+	roots := snapshotRoots(opts, remaps)
+	for i := range entries {
+		entries[i].ConfigPath = roots.Resolve(entries[i])
+	}
+
+	if !whole {
+		current = slices.DeleteFunc(slices.Clone(current), func(spec opencode.PluginSpec) bool {
+			return spec.Name != target
+		})
+	}
+	report := snapshotDiffReport{Target: target, Plugins: diffSnapshot(entries, current, installedByName)}
+	if report.Target == "" {
+		report.Target = "latest"
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "failed to encode diff: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	renderSnapshotDiff(stdout, report)
 	return 0
 }
+
+// snapshotDiffEntries returns the snapshot side of a diff and whether it
+// covers every plugin, so plugins declared since then count as added. With no
+// target and no named snapshot it takes the latest entry of each plugin that
+// is still declared, so plugins dropped long ago are not reported every time.
+func snapshotDiffEntries(store snapshot.Store, target string, ref string, declared map[string]bool) ([]snapshot.Entry, bool, error) {
+	if target == "" {
+		all, err := store.Entries()
+		if err != nil {
+			return nil, false, err
+		}
+		latest := map[string]snapshot.Entry{}
+		for _, entry := range all {
+			if !declared[entry.PluginName] {
+				continue
+			}
+			if existing, ok := latest[entry.PluginName]; !ok || entry.Timestamp.After(existing.Timestamp) {
+				latest[entry.PluginName] = entry
+			}
+		}
+		entries := make([]snapshot.Entry, 0, len(latest))
+		for _, entry := range latest {
+			entries = append(entries, entry)
+		}
+		return entries, true, nil
+	}
+
+	if ref == "" {
+		set, setErr := store.LoadSet(target)
+		if setErr == nil {
+			return set.Entries, true, nil
+		}
+		entry, err := store.Latest(target)
+		if err != nil {
+			// Plugin names like @scope/pkg are not valid set names, so a set
+			// error only matters when the plugin lookup found nothing either.
+			if errors.Is(err, snapshot.ErrSnapshotNotFound) && !errors.Is(setErr, snapshot.ErrSnapshotNotFound) && !strings.ContainsAny(target, `/\`) {
+				return nil, false, setErr
+			}
+			return nil, false, err
+		}
+		return []snapshot.Entry{entry}, false, nil
+	}
+	entry, err := store.Find(target, ref)
+	if err != nil {
+		return nil, false, err
+	}
+	return []snapshot.Entry{entry}, false, nil
+}
+
+// diffSnapshot pairs snapshot entries with current plugins by name. When a
+// plugin is declared in several places, an entry and spec from the same
+// source and config are compared; otherwise the ones OpenCode would use win.
+func diffSnapshot(entries []snapshot.Entry, current []opencode.PluginSpec, installedByName map[string]cache.Entry) []pluginChange {
+	entriesByName := map[string][]snapshot.Entry{}
+	specsByName := map[string][]opencode.PluginSpec{}
+	seen := map[string]struct{}{}
+	names := []string{}
+	addName := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	for _, entry := range entries {
+		addName(entry.PluginName)
+		entriesByName[entry.PluginName] = append(entriesByName[entry.PluginName], entry)
+	}
+	for _, spec := range current {
+		addName(spec.Name)
+		specsByName[spec.Name] = append(specsByName[spec.Name], spec)
+	}
+	sort.Strings(names)
+
+	changes := make([]pluginChange, 0, len(names))
+	for _, name := range names {
+		candidates := entriesByName[name]
+		specs := specsByName[name]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return opencode.Precedence(opencode.Source(candidates[i].Source)) < opencode.Precedence(opencode.Source(candidates[j].Source))
+		})
+		sort.SliceStable(specs, func(i, j int) bool {
+			return opencode.Precedence(specs[i].Source) < opencode.Precedence(specs[j].Source)
+		})
+
+		if len(specs) == 0 {
+			entry := candidates[0]
+			changes = append(changes, pluginChange{
+				Plugin:    name,
+				Snapshot:  entry.ID,
+				Change:    changeRemoved,
+				Spec:      valueChange{Snapshot: entry.PreviousSpec},
+				Installed: valueChange{Snapshot: entry.PreviousInstalled, Current: currentInstalled(name, installedByName)},
+				Config:    valueChange{Snapshot: entryLocation(entry)},
+			})
+			continue
+		}
+		if len(candidates) == 0 {
+			spec := specs[0]
+			changes = append(changes, pluginChange{
+				Plugin:    name,
+				Change:    changeAdded,
+				Spec:      valueChange{Current: spec.DeclaredSpec},
+				Installed: valueChange{Current: specInstalled(spec, installedByName)},
+				Config:    valueChange{Current: specLocation(spec)},
+			})
+			continue
+		}
+
+		entry, spec := matchSnapshotEntry(candidates, specs)
+		change := pluginChange{
+			Plugin:    name,
+			Snapshot:  entry.ID,
+			Spec:      valueChange{Snapshot: entry.PreviousSpec, Current: spec.DeclaredSpec},
+			Installed: valueChange{Snapshot: entry.PreviousInstalled, Current: specInstalled(spec, installedByName)},
+			Config:    valueChange{Snapshot: entryLocation(entry), Current: specLocation(spec)},
+			Change:    changeUnchanged,
+		}
+		if change.Spec.changed() || change.Installed.changed() || change.Config.changed() {
+			change.Change = changeChanged
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// matchSnapshotEntry picks the entry and spec to compare for one plugin. Both
+// lists are sorted by precedence, highest first.
+func matchSnapshotEntry(entries []snapshot.Entry, specs []opencode.PluginSpec) (snapshot.Entry, opencode.PluginSpec) {
+	for _, spec := range specs {
+		for _, entry := range entries {
+			if opencode.Source(entry.Source) == spec.Source && entryLocation(entry) == specLocation(spec) {
+				return entry, spec
+			}
+		}
+	}
+	return entries[0], specs[0]
+}
+
+// entryLocation is where a snapshot entry was declared: the plugin file for a
+// local plugin, its config otherwise.
+func entryLocation(entry snapshot.Entry) string {
+	if opencode.Source(entry.Source) == opencode.SourceLocal {
+		return entry.LocalPath
+	}
+	return entry.ConfigPath
+}
+
+// specLocation is where a discovered spec is declared, as entryLocation.
+func specLocation(spec opencode.PluginSpec) string {
+	if spec.Source == opencode.SourceLocal {
+		return spec.LocalPath
+	}
+	return spec.ConfigPath
+}
+
+// specInstalled reports the installed version of a spec the way a snapshot
+// records it.
+func specInstalled(spec opencode.PluginSpec, installedByName map[string]cache.Entry) string {
+	switch spec.Source {
+	case opencode.SourceLocal:
+		if spec.Version != "" {
+			return spec.Version
+		}
+		return "local"
+	case opencode.SourceDependency:
+		if entry, ok := dependencyEntry(spec); ok {
+			return entry.Version
+		}
+		return "missing"
+	}
+	return currentInstalled(spec.Name, installedByName)
+}
+
+func currentInstalled(name string, installedByName map[string]cache.Entry) string {
+	if entry, ok := installedByName[name]; ok {
+		return entry.Version
+	}
+	return "missing"
+}
+
+func renderSnapshotDiff(w io.Writer, report snapshotDiffReport) {
+	if len(report.Plugins) == 0 {
+		fmt.Fprintln(w, "No plugins to compare.")
+		return
+	}
+
+	counts := map[string]int{}
+	headers := []string{"PLUGIN", "CHANGE", "SPEC", "INSTALLED", "CONFIG"}
+	rows := make([][]string, 0, len(report.Plugins))
+	for _, change := range report.Plugins {
+		counts[change.Change]++
+		rows = append(rows, []string{
+			change.Plugin,
+			change.Change,
+			formatValueChange(change.Spec),
+			formatValueChange(change.Installed),
+			formatValueChange(change.Config),
+		})
+	}
+	renderTable(w, headers, rows)
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "%d changed, %d added, %d removed, %d unchanged since snapshot %s.\n",
+		counts[changeChanged], counts[changeAdded], counts[changeRemoved], counts[changeUnchanged], report.Target)
+}
+
+func formatValueChange(v valueChange) string {
+	switch {
+	case v.Snapshot == "" && v.Current == "":
+		return "-"
+	case v.Snapshot == "":
+		return "+ " + v.Current
+	case v.Current == "":
+		return "- " + v.Snapshot
+	case v.changed():
+		return v.Snapshot + " -> " + v.Current
+	}
+	return v.Snapshot
+}
+
+// snapshotRoots returns the directories portable snapshot paths are recorded
+// against on this machine. Discovery errors leave the affected root unset, so
+// entries fall back to their recorded absolute paths.
+func snapshotRoots(opts CommonOptions, remaps []snapshot.Remap) snapshot.Roots {
+	result, _ := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	roots := snapshot.Roots{Remaps: remaps}
+	if dir, err := projectDir(opts, result); err == nil {
+		roots.Project = dir
+	}
+	switch {
+	case result.GlobalConfig != "":
+		roots.Global = filepath.Dir(result.GlobalConfig)
+	case opts.GlobalConfig != "":
+		roots.Global = filepath.Dir(opts.GlobalConfig)
+	default:
+		if path := opencode.DefaultGlobalConfigPath(); path != "" {
+			roots.Global = filepath.Dir(path)
+		}
+	}
+	roots.ConfigDir = strings.TrimSpace(os.Getenv("OPENCODE_CONFIG_DIR"))
+	return roots
+}
+
+func parseRemaps(values []string) ([]snapshot.Remap, error) {
+	remaps := make([]snapshot.Remap, 0, len(values))
+	for _, value := range values {
+		remap, err := snapshot.ParseRemap(value)
+		if err != nil {
+			return nil, err
+		}
+		remaps = append(remaps, remap)
+	}
+	return remaps, nil
+}
@@ -2,12 +2,14 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/AksharP5/Patchline/internal/opencode"
 	"github.com/AksharP5/Patchline/internal/snapshot"
 )
 
@@ -33,7 +35,7 @@ func TestSnapshotCommandWritesEntries(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := snapshotCommand(opts, &stdout, &stderr)
+	code := snapshotCommand(opts, "", &stdout, &stderr)
 	if code != 0 {
 		t.Fatalf("expected success, got %d: %s", code, stderr.String())
 	}
@@ -68,7 +70,7 @@ func TestRollbackCommandRestoresConfigAndCache(t *testing.T) {
 
 	snapshotDir := filepath.Join(root, "snapshots")
 	store := snapshot.Store{Directory: snapshotDir}
-	err := store.Save(snapshot.Entry{
+	_, err := store.Save(snapshot.Entry{
 		PluginName:        "alpha",
 		PreviousSpec:      "alpha@1.0.0",
 		PreviousInstalled: "1.0.0",
@@ -89,7 +91,7 @@ func TestRollbackCommandRestoresConfigAndCache(t *testing.T) {
 
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
-	code := rollbackCommand(opts, "alpha", &stdout, &stderr)
+	code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr)
 	if code != 0 {
 		t.Fatalf("expected success, got %d: %s", code, stderr.String())
 	}
@@ -106,6 +108,422 @@ func TestRollbackCommandRestoresConfigAndCache(t *testing.T) {
 	}
 }
 
+func TestRollbackCommandToEntryRecordsRedo(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@3.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"3.0.0"}`)
+
+	snapshotDir := filepath.Join(root, "snapshots")
+	store := snapshot.Store{Directory: snapshotDir}
+	base := time.Now().Add(-2 * time.Hour)
+	for i, spec := range []string{"alpha@1.0.0", "alpha@2.0.0"} {
+		_, err := store.Save(snapshot.Entry{
+			PluginName:   "alpha",
+			PreviousSpec: spec,
+			ConfigPath:   configPath,
+			Source:       "project",
+			Reason:       "upgrade",
+			Timestamp:    base.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("save snapshot: %v", err)
+		}
+	}
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: snapshotDir}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{To: "1.0.0"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected success, got %d: %s", code, stderr.String())
+	}
+	updated, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(updated), "alpha@1.0.0") {
+		t.Fatalf("expected config rollback, got %s", string(updated))
+	}
+
+	record, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if record.Reason != "rollback" || record.PreviousSpec != "alpha@3.0.0" || record.PreviousInstalled != "3.0.0" {
+		t.Fatalf("expected rollback record of prior state, got %#v", record)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{To: record.ID}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected redo success, got %d: %s", code, stderr.String())
+	}
+	updated, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(updated), "alpha@3.0.0") {
+		t.Fatalf("expected redo to restore alpha@3.0.0, got %s", string(updated))
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := historyCommand(opts, "alpha", &stdout, &stderr); code != 0 {
+		t.Fatalf("expected history success, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "rollback to "+record.ID) {
+		t.Fatalf("expected rollback entry in history, got %s", stdout.String())
+	}
+}
+
+func TestNamedSnapshotRestoresAllConfigs(t *testing.T) {
+	root := t.TempDir()
+	projectConfig := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(projectConfig, []byte(`{"plugin": ["alpha@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	globalConfig := filepath.Join(root, "global", "opencode.json")
+	if err := os.MkdirAll(filepath.Dir(globalConfig), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(globalConfig, []byte(`{"plugin": ["beta@2.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{
+		ProjectRoot:  root,
+		GlobalConfig: globalConfig,
+		CacheDir:     cacheDir,
+		SnapshotDir:  filepath.Join(root, "snapshots"),
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "pre-upgrade", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	if code := snapshotCommand(opts, "pre-upgrade", &stdout, &stderr); code != 1 {
+		t.Fatalf("expected duplicate name to fail, got %d", code)
+	}
+
+	if err := opencode.UpdatePluginSpec(projectConfig, "alpha", "alpha@1.5.0"); err != nil {
+		t.Fatalf("update config: %v", err)
+	}
+	if err := opencode.UpdatePluginSpec(globalConfig, "beta", "beta@3.0.0"); err != nil {
+		t.Fatalf("update config: %v", err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := restoreCommand(opts, "pre-upgrade", nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("restore failed: %d %s", code, stderr.String())
+	}
+	for path, want := range map[string]string{projectConfig: "alpha@1.0.0", globalConfig: "beta@2.0.0"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read config: %v", err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %s in %s, got %s", want, path, string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "alpha")); !os.IsNotExist(err) {
+		t.Fatalf("expected cache invalidation, got %v", err)
+	}
+
+	stdout.Reset()
+	if code := snapshotListCommand(opts, &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot list failed: %d", code)
+	}
+	if !strings.Contains(stdout.String(), "pre-upgrade") {
+		t.Fatalf("expected named snapshot in list, got %s", stdout.String())
+	}
+}
+
+func TestRestoreCommandRestoresAddedAndRemovedPlugins(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@1.0.0", "beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "gamma"), `{"name":"gamma","version":"1.0.0"}`)
+	opts := CommonOptions{ProjectRoot: root, GlobalConfig: filepath.Join(root, "global", "opencode.json"), CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@2.0.0", "gamma@1.0.0"], "theme": "dark"}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	writeTestFile(t, opts.GlobalConfig, `{"plugin": ["delta@1.0.0"]}`)
+
+	stdout.Reset()
+	if code := restoreCommand(opts, "before", nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("restore failed: %d %s", code, stderr.String())
+	}
+	if got := readTestFile(t, configPath); got != `{"plugin": ["alpha@1.0.0", "beta@1.0.0"], "theme": "dark"}` {
+		t.Fatalf("expected the snapshot's plugin list with later settings kept, got %s", got)
+	}
+	if got := readTestFile(t, opts.GlobalConfig); got != `{"plugin": ["delta@1.0.0"]}` {
+		t.Fatalf("expected a config the snapshot did not capture to be left alone, got %s", got)
+	}
+	for _, want := range []string{"Restored alpha -> alpha@1.0.0", "Declared beta again", "Removed gamma"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected %q, got %s", want, stdout.String())
+		}
+	}
+	if strings.Contains(stdout.String(), "Removed delta") {
+		t.Fatalf("expected delta to be kept, got %s", stdout.String())
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "gamma")); !os.IsNotExist(err) {
+		t.Fatalf("expected the removed plugin's cache to be invalidated, got %v", err)
+	}
+}
+
+func TestRestoreCommandRevertsWhenAWriteFails(t *testing.T) {
+	root := t.TempDir()
+	projectConfig := filepath.Join(root, "opencode.json")
+	writeTestFile(t, projectConfig, `{"plugin": ["alpha@1.0.0"]}`)
+	globalConfig := filepath.Join(root, "zz-global", "opencode.jsonc")
+	writeTestFile(t, globalConfig, `{"plugin": ["beta@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	opts := CommonOptions{ProjectRoot: root, GlobalConfig: globalConfig, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+
+	project := `{"plugin": ["alpha@2.0.0"]}`
+	writeTestFile(t, projectConfig, project)
+	global := "{\"plugin\": [\n  // pinned by hand\n  \"beta@2.0.0\"\n]}"
+	writeTestFile(t, globalConfig, global)
+
+	if code := restoreCommand(opts, "before", nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected restore to fail, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "reverted") {
+		t.Fatalf("expected revert message, got %s", stderr.String())
+	}
+	if got := readTestFile(t, projectConfig); got != project {
+		t.Fatalf("expected the config written first to be reverted, got %s", got)
+	}
+	if got := readTestFile(t, globalConfig); got != global {
+		t.Fatalf("expected config untouched, got %s", got)
+	}
+}
+
+func TestSnapshotPruneCommand(t *testing.T) {
+	root := t.TempDir()
+	snapshotDir := filepath.Join(root, "snapshots")
+	store := snapshot.Store{Directory: snapshotDir}
+	for i := 0; i < 4; i++ {
+		_, err := store.Save(snapshot.Entry{
+			PluginName:   "alpha",
+			PreviousSpec: "alpha@1.0.0",
+			Reason:       "upgrade",
+			Timestamp:    time.Now().Add(time.Duration(i-10) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("save snapshot: %v", err)
+		}
+	}
+
+	opts := CommonOptions{SnapshotDir: snapshotDir}
+	keepLast := 1
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotPruneCommand(opts, pruneOverrides{KeepLast: &keepLast}, &stdout, &stderr); code != 0 {
+		t.Fatalf("prune failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Removed 3 snapshot(s) of alpha") {
+		t.Fatalf("expected prune summary, got %s", stdout.String())
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry left, got %d", len(entries))
+	}
+}
+
+func TestSnapshotFsckCommand(t *testing.T) {
+	snapshotDir := filepath.Join(t.TempDir(), "snapshots")
+	writeTestFile(t, filepath.Join(snapshotDir, "alpha.json"), `[{"timestamp":"2026-03-01T12:00:00Z","pluginName":"alpha","previousSpec":"alpha@1.0.0"},`)
+
+	opts := CommonOptions{SnapshotDir: snapshotDir}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotFsckCommand(opts, false, false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected problems to fail fsck, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "alpha.json") || !strings.Contains(stdout.String(), "--repair") {
+		t.Fatalf("expected corrupt file and repair hint, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := snapshotFsckCommand(opts, true, false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected repair to succeed, got %d %s %s", code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "repaired") {
+		t.Fatalf("expected repaired status, got %s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := snapshotFsckCommand(opts, false, false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected clean fsck, got %d %s", code, stdout.String())
+	}
+}
+
+func TestSnapshotDiffCommand(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	projectConfig := filepath.Join(root, "opencode.json")
+	writeTestFile(t, projectConfig, `{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+
+	writeTestFile(t, projectConfig, `{"plugin": ["alpha@1.2.0", "gamma@1.0.0"]}`)
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.2.0"}`)
+
+	stdout.Reset()
+	if code := snapshotDiffCommand(opts, "before", "", nil, true, &stdout, &stderr); code != 0 {
+		t.Fatalf("diff failed: %d %s", code, stderr.String())
+	}
+	var report snapshotDiffReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	want := map[string]string{"alpha": changeChanged, "beta": changeRemoved, "gamma": changeAdded}
+	if len(report.Plugins) != len(want) {
+		t.Fatalf("expected %d plugins, got %#v", len(want), report.Plugins)
+	}
+	for _, change := range report.Plugins {
+		if want[change.Plugin] != change.Change {
+			t.Fatalf("expected %s to be %s, got %s", change.Plugin, want[change.Plugin], change.Change)
+		}
+		if change.Plugin == "alpha" && (change.Spec.Snapshot != "alpha@1.0.0" || change.Installed.Current != "1.2.0") {
+			t.Fatalf("unexpected alpha change %#v", change)
+		}
+	}
+
+	stdout.Reset()
+	if code := snapshotDiffCommand(opts, "alpha", "", nil, false, &stdout, &stderr); code != 0 {
+		t.Fatalf("plugin diff failed: %d %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "alpha@1.0.0 -> alpha@1.2.0") || strings.Contains(stdout.String(), "gamma") {
+		t.Fatalf("expected only the alpha change, got %s", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := snapshotDiffCommand(opts, "missing", "", nil, false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected unknown snapshot to fail, got %d", code)
+	}
+}
+
+func TestSnapshotDiffDefaultsToNewestSetOrDeclaredPlugins(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	projectConfig := filepath.Join(root, "opencode.json")
+	writeTestFile(t, projectConfig, `{"plugin": ["alpha@1.0.0"]}`)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	opts := CommonOptions{ProjectRoot: root, CacheDir: cacheDir, SnapshotDir: filepath.Join(root, "snapshots")}
+
+	store := snapshot.Store{Directory: opts.SnapshotDir}
+	for _, name := range []string{"alpha", "dropped"} {
+		if _, err := store.Save(snapshot.Entry{PluginName: name, PreviousSpec: name + "@1.0.0", ConfigPath: projectConfig}); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotDiffCommand(opts, "", "", nil, true, &stdout, &stderr); code != 0 {
+		t.Fatalf("diff failed: %d %s", code, stderr.String())
+	}
+	var report snapshotDiffReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if report.Target != "latest" || len(report.Plugins) != 1 || report.Plugins[0].Plugin != "alpha" {
+		t.Fatalf("expected only the declared plugin, got %#v", report)
+	}
+
+	if code := snapshotCommand(opts, "baseline", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	writeTestFile(t, projectConfig, `{"plugin": []}`)
+	stdout.Reset()
+	if code := snapshotDiffCommand(opts, "", "", nil, true, &stdout, &stderr); code != 0 {
+		t.Fatalf("diff failed: %d %s", code, stderr.String())
+	}
+	report = snapshotDiffReport{}
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if report.Target != "baseline" || len(report.Plugins) != 1 || report.Plugins[0].Change != changeRemoved {
+		t.Fatalf("expected alpha removed since the baseline set, got %#v", report)
+	}
+}
+
+func TestSnapshotDiffKeepsLocalDependencyAndLinkedPlugins(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	checkout := filepath.Join(root, "src", "beta")
+	writePackageJSON(t, checkout, `{"name":"beta","version":"2.1.0-dev"}`)
+	projectConfig := filepath.Join(root, "opencode.json")
+	writeTestFile(t, projectConfig, `{"plugin": ["alpha@1.0.0", "`+opencode.LinkSpec(checkout)+`"]}`)
+	writeTestFile(t, filepath.Join(root, ".opencode", "plugin", "tool.ts"), "export default {}\n")
+	manifest := filepath.Join(root, ".opencode", "package.json")
+	writeTestFile(t, manifest, `{"dependencies": {"@opencode-ai/plugin": "0.5.1"}}`)
+	writePackageJSON(t, filepath.Join(root, "cache", "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	opts := CommonOptions{ProjectRoot: root, CacheDir: filepath.Join(root, "cache"), SnapshotDir: filepath.Join(root, "snapshots")}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := snapshotCommand(opts, "before", &stdout, &stderr); code != 0 {
+		t.Fatalf("snapshot failed: %d %s", code, stderr.String())
+	}
+	writeTestFile(t, manifest, `{"dependencies": {"@opencode-ai/plugin": "0.6.0"}}`)
+
+	stdout.Reset()
+	if code := snapshotDiffCommand(opts, "before", "", nil, true, &stdout, &stderr); code != 0 {
+		t.Fatalf("diff failed: %d %s", code, stderr.String())
+	}
+	var report snapshotDiffReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	want := map[string]string{"alpha": changeUnchanged, "beta": changeUnchanged, "tool": changeUnchanged, "@opencode-ai/plugin": changeChanged}
+	if len(report.Plugins) != len(want) {
+		t.Fatalf("expected %d plugins, got %#v", len(want), report.Plugins)
+	}
+	for _, change := range report.Plugins {
+		if want[change.Plugin] != change.Change {
+			t.Fatalf("expected %s to be %s, got %#v", change.Plugin, want[change.Plugin], change)
+		}
+	}
+}
+
 func writePackageJSON(t *testing.T, dir string, content string) {
 	t.Helper()
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -116,3 +534,12 @@ func writePackageJSON(t *testing.T, dir string, content string) {
 		t.Fatalf("write: %v", err)
 	}
 }
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
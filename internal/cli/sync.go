@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"sort"
 
 	"github.com/AksharP5/Patchline/internal/cache"
@@ -20,11 +21,15 @@ type syncRow struct {
 	Source    string
 }
 
+// syncPlan lists the cache entries to refresh. Config-dir dependencies are
+// not cached; DependencyTargets are removed from node_modules instead so
+// OpenCode installs the declared version.
 type syncPlan struct {
-	Rows            []syncRow
-	RefreshTargets  []string
-	SkippedUnpinned int
-	LocalCount      int
+	Rows              []syncRow
+	RefreshTargets    []string
+	DependencyTargets []opencode.PluginSpec
+	SkippedUnpinned   int
+	LocalCount        int
 }
 
 func syncCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
@@ -33,6 +38,7 @@ func syncCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
 		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
 		return 1
 	}
+	warnDiscovery(stderr, result)
 
 	ctx := context.Background()
 	cacheDir, candidates := cache.ResolveDir(opts.CacheDir)
@@ -56,7 +62,7 @@ func syncCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
 	plan := buildSyncPlan(result.Plugins, entries)
 	renderSyncTable(stdout, plan.Rows)
 
-	if len(plan.RefreshTargets) == 0 {
+	if len(plan.RefreshTargets) == 0 && len(plan.DependencyTargets) == 0 {
 		fmt.Fprintln(stdout, "")
 		fmt.Fprintln(stdout, "Cache already matches pinned config.")
 		if plan.SkippedUnpinned > 0 {
@@ -82,6 +88,18 @@ func syncCommand(opts CommonOptions, stdout io.Writer, stderr io.Writer) int {
 		}
 		refreshed++
 	}
+	for _, spec := range plan.DependencyTargets {
+		dir := opencode.DependencyDir(spec)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			missing++
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Fprintf(stderr, "failed to refresh %s: %v\n", spec.Name, err)
+			return 1
+		}
+		refreshed++
+	}
 
 	fmt.Fprintln(stdout, "")
 	if refreshed > 0 {
@@ -107,6 +125,7 @@ func buildSyncPlan(specs []opencode.PluginSpec, entries []cache.Entry) syncPlan
 
 	rows := []syncRow{}
 	targets := []string{}
+	dependencyTargets := []opencode.PluginSpec{}
 	skippedUnpinned := 0
 	localCount := 0
 
@@ -124,7 +143,23 @@ func buildSyncPlan(specs []opencode.PluginSpec, entries []cache.Entry) syncPlan
 			continue
 		}
 
+		if spec.LinkPath != "" {
+			localCount++
+			rows = append(rows, syncRow{
+				Name:      spec.Name,
+				Declared:  spec.DeclaredSpec,
+				Installed: "local",
+				Status:    string(model.StatusLinked),
+				Action:    "skip",
+				Source:    string(spec.Source),
+			})
+			continue
+		}
+
 		entry, ok := installedByName[spec.Name]
+		if spec.Source == opencode.SourceDependency {
+			entry, ok = dependencyEntry(spec)
+		}
 		installed := "missing"
 		if ok {
 			installed = entry.Version
@@ -132,18 +167,29 @@ func buildSyncPlan(specs []opencode.PluginSpec, entries []cache.Entry) syncPlan
 
 		status := string(model.StatusOK)
 		action := "noop"
-		if spec.Pinned == "" {
+		if ok && entry.Corrupt() {
+			status = string(model.StatusCorrupt)
+			action = "refresh"
+			if installed == "" {
+				installed = "unknown"
+			}
+		} else if spec.Pinned == "" {
 			status = string(model.StatusUnknown)
 			action = "skip"
 			skippedUnpinned++
 		} else if installed == "missing" {
 			status = string(model.StatusMissing)
 			action = "refresh"
-			targets = append(targets, spec.Name)
 		} else if spec.Pinned != installed {
 			status = string(model.StatusMismatch)
 			action = "refresh"
-			targets = append(targets, spec.Name)
+		}
+		if action == "refresh" {
+			if spec.Source == opencode.SourceDependency {
+				dependencyTargets = append(dependencyTargets, spec)
+			} else {
+				targets = append(targets, spec.Name)
+			}
 		}
 
 		rows = append(rows, syncRow{
@@ -164,10 +210,11 @@ func buildSyncPlan(specs []opencode.PluginSpec, entries []cache.Entry) syncPlan
 	})
 
 	return syncPlan{
-		Rows:            rows,
-		RefreshTargets:  uniqueNames(targets),
-		SkippedUnpinned: skippedUnpinned,
-		LocalCount:      localCount,
+		Rows:              rows,
+		RefreshTargets:    uniqueNames(targets),
+		DependencyTargets: dependencyTargets,
+		SkippedUnpinned:   skippedUnpinned,
+		LocalCount:        localCount,
 	}
 }
 
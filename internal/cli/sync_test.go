@@ -48,6 +48,27 @@ func TestBuildSyncPlan(t *testing.T) {
 	}
 }
 
+func TestBuildSyncPlanRefreshesCorruptEntries(t *testing.T) {
+	specs := []opencode.PluginSpec{
+		{Name: "alpha", DeclaredSpec: "alpha", Source: opencode.SourceProject},
+	}
+	entries := []cache.Entry{
+		{Name: "alpha", Version: "1.0.0", Issues: []string{"missing entry point index.js"}},
+	}
+
+	plan := buildSyncPlan(specs, entries)
+	if len(plan.RefreshTargets) != 1 || plan.RefreshTargets[0] != "alpha" {
+		t.Fatalf("expected corrupt alpha to refresh, got %v", plan.RefreshTargets)
+	}
+	if plan.SkippedUnpinned != 0 {
+		t.Fatalf("expected corrupt entry not counted as unpinned skip")
+	}
+	row := findSyncRow(plan.Rows, "alpha")
+	if row.Status != string(model.StatusCorrupt) || row.Action != "refresh" {
+		t.Fatalf("expected alpha corrupt/refresh, got %s/%s", row.Status, row.Action)
+	}
+}
+
 func TestSyncCommandMissingCacheDir(t *testing.T) {
 	root := t.TempDir()
 	missing := root + "/missing"
@@ -19,6 +19,7 @@ type upgradeTarget struct {
 	Source     string
 	Declared   string
 	Pinned     string
+	Template   string
 }
 
 func upgradeCommand(opts CommonOptions, name string, target string, mode string, all bool, stdout io.Writer, stderr io.Writer) int {
@@ -27,10 +28,13 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
 		return 1
 	}
+	warnDiscovery(stderr, result)
 
 	targets := selectUpgradeTargets(result.Plugins, name, all)
 	if len(targets) == 0 {
-		if all {
+		if linkPath, ok := linkedSpec(result.Plugins, name); ok && !all {
+			fmt.Fprintf(stderr, "%s is linked to %s; run `patchline unlink %s` before upgrading\n", name, linkPath, name)
+		} else if all {
 			fmt.Fprintln(stderr, "no npm plugins found to upgrade")
 		} else {
 			fmt.Fprintf(stderr, "plugin not found: %s\n", name)
@@ -43,6 +47,12 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 		return 2
 	}
 
+	pol, err := loadPolicy(opts, result)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load policy: %v\n", err)
+		return 2
+	}
+
 	snapshotDir, candidates := snapshot.ResolveDir(opts.SnapshotDir)
 	if snapshotDir == "" {
 		fmt.Fprintln(stderr, "snapshot directory not found")
@@ -51,7 +61,12 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 	if opts.SnapshotDir == "" && len(candidates) > 0 {
 		fmt.Fprintf(stderr, "Using snapshot directory: %s\n", snapshotDir)
 	}
-	store := snapshot.Store{Directory: snapshotDir}
+	store, err := snapshot.Open(snapshotDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load snapshot retention: %v\n", err)
+		return 1
+	}
+	store.Roots = snapshotRoots(opts, nil)
 
 	ctx := context.Background()
 	cacheDir, cacheCandidates := cache.ResolveDir(opts.CacheDir)
@@ -73,13 +88,21 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 		installedByName[entry.Name] = entry
 	}
 
+	registry := registryFor(opts)
 	infoCache := map[string]npm.PackageInfo{}
 	updated := 0
 	skipped := 0
+	refused := 0
+	templated := 0
+	var inline inlineUpdates
 	for _, targetSpec := range targets {
 		installedVersion := ""
 		installedLabel := "missing"
-		if entry, ok := installedByName[targetSpec.Name]; ok {
+		entry, ok := installedByName[targetSpec.Name]
+		if targetSpec.Source == string(opencode.SourceDependency) {
+			entry, ok = dependencyEntry(opencode.PluginSpec{Name: targetSpec.Name, ConfigPath: targetSpec.ConfigPath})
+		}
+		if ok {
 			installedVersion = entry.Version
 			installedLabel = entry.Version
 		}
@@ -89,7 +112,7 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 		if resolved == "" {
 			info, ok := infoCache[targetSpec.Name]
 			if !ok {
-				info, err = npm.FetchPackageInfo(ctx, targetSpec.Name)
+				info, err = registry.FetchPackageInfo(ctx, targetSpec.Name)
 				if err != nil {
 					fmt.Fprintf(stderr, "failed to fetch %s: %v\n", targetSpec.Name, err)
 					return 1
@@ -110,9 +133,33 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 			continue
 		}
 
-		err := store.Save(snapshot.Entry{
+		// Dependencies are left out of policy checks, as in check.
+		if targetSpec.Source != string(opencode.SourceDependency) {
+			if violations := pol.CheckPackage(targetSpec.Name, resolved); len(violations) > 0 {
+				fmt.Fprintf(stderr, "refusing to upgrade %s to %s: %s\n", targetSpec.Name, resolved, policyMessages(violations))
+				refused++
+				continue
+			}
+		}
+
+		if targetSpec.Template != "" && !opts.Flatten {
+			printTemplatedRefusal(stderr, targetSpec.Name, targetSpec.Template, targetSpec.ConfigPath)
+			templated++
+			continue
+		}
+
+		if opencode.IsInlineConfig(targetSpec.ConfigPath) {
+			if err := inline.add(opts, targetSpec.Name, newSpec); err != nil {
+				fmt.Fprintf(stderr, "failed to update config for %s: %v\n", targetSpec.Name, err)
+				return 1
+			}
+			continue
+		}
+
+		_, err := store.Save(snapshot.Entry{
 			PluginName:        targetSpec.Name,
 			PreviousSpec:      targetSpec.Declared,
+			Template:          targetSpec.Template,
 			PreviousInstalled: installedLabel,
 			Source:            targetSpec.Source,
 			Reason:            "upgrade",
@@ -123,12 +170,12 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 			return 1
 		}
 
-		if err := opencode.UpdatePluginSpec(targetSpec.ConfigPath, targetSpec.Name, newSpec); err != nil {
+		if err := updatePluginSpec(opts, targetSpec.ConfigPath, targetSpec.Name, newSpec); err != nil {
 			fmt.Fprintf(stderr, "failed to update config for %s: %v\n", targetSpec.Name, err)
 			return 1
 		}
 
-		if cacheDir != "" {
+		if cacheDir != "" && targetSpec.Source != string(opencode.SourceDependency) {
 			if _, err := cache.Invalidate(ctx, cacheDir, targetSpec.Name); err != nil {
 				fmt.Fprintf(stderr, "failed to invalidate cache for %s: %v\n", targetSpec.Name, err)
 				return 1
@@ -139,6 +186,21 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 		updated++
 	}
 
+	pending := inline.report(stdout)
+	if refused > 0 || templated > 0 || pending {
+		if updated > 0 {
+			fmt.Fprintln(stdout, "")
+			fmt.Fprintf(stdout, "Updated %d plugin(s). Run OpenCode to reinstall.\n", updated)
+		}
+		if refused > 0 {
+			fmt.Fprintf(stderr, "%d plugin(s) were blocked by policy.\n", refused)
+		}
+		if templated > 0 {
+			fmt.Fprintf(stderr, "%d plugin(s) use config substitution and were left unchanged.\n", templated)
+		}
+		return 1
+	}
+
 	if updated == 0 {
 		if skipped > 0 {
 			fmt.Fprintln(stdout, "All plugins already match the target versions.")
@@ -159,7 +221,7 @@ func upgradeCommand(opts CommonOptions, name string, target string, mode string,
 func selectUpgradeTargets(specs []opencode.PluginSpec, name string, all bool) []upgradeTarget {
 	byName := map[string][]opencode.PluginSpec{}
 	for _, spec := range specs {
-		if spec.Source == opencode.SourceLocal || spec.ConfigPath == "" {
+		if spec.Source == opencode.SourceLocal || spec.LinkPath != "" || spec.ConfigPath == "" {
 			continue
 		}
 		byName[spec.Name] = append(byName[spec.Name], spec)
@@ -182,24 +244,22 @@ func selectUpgradeTargets(specs []opencode.PluginSpec, name string, all bool) []
 	return selectPreferredTargets(byName[name])
 }
 
+// selectPreferredTargets returns the declarations of one plugin to update:
+// those in the highest precedence config source, plus every config-dir
+// dependency of the same name, which OpenCode installs separately.
 func selectPreferredTargets(specs []opencode.PluginSpec) []upgradeTarget {
 	if len(specs) == 0 {
 		return nil
 	}
+	dependencies := filterTargets(specs, opencode.SourceDependency)
 
-	order := []opencode.Source{
-		opencode.SourceCustom,
-		opencode.SourceCustomDir,
-		opencode.SourceProject,
-		opencode.SourceGlobal,
-	}
-	for _, source := range order {
+	for _, source := range opencode.ConfigSources() {
 		filtered := filterTargets(specs, source)
 		if len(filtered) > 0 {
-			return filtered
+			return append(filtered, dependencies...)
 		}
 	}
-	return nil
+	return dependencies
 }
 
 func filterTargets(specs []opencode.PluginSpec, source opencode.Source) []upgradeTarget {
@@ -222,6 +282,7 @@ func filterTargets(specs []opencode.PluginSpec, source opencode.Source) []upgrad
 			Source:     string(spec.Source),
 			Declared:   spec.DeclaredSpec,
 			Pinned:     spec.Pinned,
+			Template:   spec.Template,
 		})
 	}
 	return out
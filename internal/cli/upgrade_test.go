@@ -160,3 +160,142 @@ func TestChooseBaseVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestUpgradeCommandRefusesTemplatedEntry(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("PATCHLINE_TEST_ALPHA", "1.0.0")
+	configPath := filepath.Join(root, "opencode.json")
+	config := `{"plugin": ["alpha@{env:PATCHLINE_TEST_ALPHA}"]}`
+	if err := os.WriteFile(configPath, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	opts := CommonOptions{
+		ProjectRoot: root,
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "snapshots"),
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "alpha", "1.2.0", "", false, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected refusal, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "pass --flatten") {
+		t.Fatalf("expected flatten hint, got %s", stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if string(data) != config {
+		t.Fatalf("expected config untouched, got %s", data)
+	}
+
+	opts.Flatten = true
+	stdout.Reset()
+	stderr.Reset()
+	if code := upgradeCommand(opts, "alpha", "1.2.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected flatten upgrade, got %d: %s", code, stderr.String())
+	}
+	data, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), `"alpha@1.2.0"`) {
+		t.Fatalf("expected literal spec, got %s", data)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := rollbackCommand(opts, "alpha", rollbackOptions{}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected rollback, got %d: %s", code, stderr.String())
+	}
+	data, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), `"alpha@{env:PATCHLINE_TEST_ALPHA}"`) {
+		t.Fatalf("expected rollback to restore the template, got %s", data)
+	}
+}
+
+func TestUpgradeCommandSkipsUnexpandedEntry(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["{file:missing.txt}", "beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), `{"name":"beta","version":"1.0.0"}`)
+	opts := CommonOptions{
+		ProjectRoot: root,
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "snapshots"),
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "beta", "1.2.0", "", false, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected beta to upgrade, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: skipping {file:missing.txt} in "+configPath) {
+		t.Fatalf("expected a warning for the unexpanded entry, got %s", stderr.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if !strings.Contains(string(data), `"{file:missing.txt}"`) || !strings.Contains(string(data), `"beta@1.2.0"`) {
+		t.Fatalf("expected only beta to change, got %s", data)
+	}
+}
+
+func TestUpgradeCommandPrintsInlineReplacement(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	original := `{"plugin": ["alpha@1.0.0", "beta@1.0.0"]}`
+	t.Setenv("OPENCODE_CONFIG_CONTENT", original)
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), `{"name":"alpha","version":"1.0.0"}`)
+	opts := CommonOptions{
+		ProjectRoot: root,
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "snapshots"),
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	if code := upgradeCommand(opts, "", "1.2.0", "", true, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1 for an unapplied change, got %d: %s", code, stderr.String())
+	}
+	if strings.Count(stdout.String(), "Set it to:") != 1 {
+		t.Fatalf("expected the replacement value once, got %s", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "which declares alpha, beta. Set it to:\n{\"plugin\":[\"alpha@1.2.0\",\"beta@1.2.0\"]}\n") {
+		t.Fatalf("expected replacement value, got %s", stdout.String())
+	}
+	if os.Getenv("OPENCODE_CONFIG_CONTENT") != original {
+		t.Fatalf("expected the environment to be left alone")
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "alpha")); err != nil {
+		t.Fatalf("expected the cache to be left alone: %v", err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "$") || strings.HasPrefix(entry.Name(), "opencode") {
+			t.Fatalf("expected no config file to be written, found %s", entry.Name())
+		}
+	}
+
+	store := snapshot.Store{Directory: opts.SnapshotDir}
+	if _, err := store.Latest("alpha"); !errors.Is(err, snapshot.ErrSnapshotNotFound) {
+		t.Fatalf("expected no snapshot for an unapplied change, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/cache"
+	"github.com/AksharP5/Patchline/internal/lockfile"
+	"github.com/AksharP5/Patchline/internal/model"
+	"github.com/AksharP5/Patchline/internal/npm"
+	"github.com/AksharP5/Patchline/internal/opencode"
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+type verifyOptions struct {
+	All        bool
+	Quarantine bool
+	JSON       bool
+	Lockfile   bool
+}
+
+type verifyRow struct {
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	Status      string         `json:"status"`
+	Diff        cache.FileDiff `json:"diff"`
+	Error       string         `json:"error,omitempty"`
+	Quarantined string         `json:"quarantined,omitempty"`
+}
+
+func verifyCommand(opts CommonOptions, vopts verifyOptions, stdout io.Writer, stderr io.Writer) int {
+	if opts.Offline {
+		fmt.Fprintln(stderr, "verify requires registry access; disable --offline")
+		return 2
+	}
+
+	result, err := opencode.Discover(opts.ProjectRoot, opts.GlobalConfig, []string(opts.LocalDirs))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to discover plugins: %v\n", err)
+		return 1
+	}
+
+	cacheDir, candidates := cache.ResolveDir(opts.CacheDir)
+	if cacheDir == "" {
+		if len(candidates) > 0 {
+			fmt.Fprintf(stderr, "cache directory not found. Checked: %s\n", strings.Join(candidates, ", "))
+		} else {
+			fmt.Fprintln(stderr, "cache directory not found")
+		}
+		return 1
+	}
+
+	ctx := context.Background()
+	entries, err := cache.Detect(ctx, cacheDir)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to scan cache directory: %v\n", err)
+		return 1
+	}
+
+	quarantineDir := ""
+	if vopts.Quarantine {
+		snapshotDir, _ := snapshot.ResolveDir(opts.SnapshotDir)
+		if snapshotDir == "" {
+			fmt.Fprintln(stderr, "snapshot directory not found; cannot place quarantine")
+			return 1
+		}
+		quarantineDir = filepath.Join(snapshotDir, "quarantine")
+	}
+
+	registry := registryFor(opts)
+	targets := selectVerifyTargets(result.Plugins, entries, vopts.All)
+	locked := map[string]lockfile.Package{}
+	if vopts.Lockfile {
+		if code := lockCheckCommand(opts, stdout, stderr); code != 0 {
+			return code
+		}
+		lock, _, code := loadProjectLockfile(opts, result, stderr)
+		if code != 0 {
+			return code
+		}
+		targets, locked = selectLockedTargets(lock, entries)
+	}
+
+	rows := []verifyRow{}
+	for _, target := range targets {
+		row := verifyRow{Name: target.Name, Version: target.Version}
+		if target.Path == "" {
+			row.Version = "missing"
+			row.Status = string(model.StatusMissing)
+			rows = append(rows, row)
+			continue
+		}
+
+		var diff cache.FileDiff
+		if pkg, ok := locked[target.Name]; ok {
+			diff, err = verifyTarball(ctx, registry, target, pkg.Resolved, pkg.Integrity, pkg.Shasum)
+		} else {
+			diff, err = verifyEntry(ctx, registry, target)
+		}
+		if err != nil {
+			row.Status = string(model.StatusUnknown)
+			row.Error = err.Error()
+			rows = append(rows, row)
+			continue
+		}
+		row.Diff = diff
+		row.Status = string(model.StatusOK)
+		if !diff.Clean() {
+			row.Status = string(model.StatusTampered)
+			if vopts.Quarantine {
+				moved, err := cache.Quarantine(ctx, cacheDir, target, quarantineDir)
+				if err != nil {
+					fmt.Fprintf(stderr, "failed to quarantine %s: %v\n", target.Name, err)
+					return 1
+				}
+				row.Quarantined = moved
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if vopts.JSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(rows); err != nil {
+			fmt.Fprintf(stderr, "failed to encode report: %v\n", err)
+			return 1
+		}
+	} else {
+		renderVerifyReport(stdout, rows)
+	}
+
+	for _, row := range rows {
+		if row.Status != string(model.StatusOK) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// selectVerifyTargets returns the cache entries for declared npm plugins, or
+// every cache entry when all is set. Declared plugins without a cache entry are
+// returned with an empty path.
+func selectVerifyTargets(specs []opencode.PluginSpec, entries []cache.Entry, all bool) []cache.Entry {
+	byName := map[string]cache.Entry{}
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+
+	seen := map[string]struct{}{}
+	targets := []cache.Entry{}
+	add := func(entry cache.Entry) {
+		if _, ok := seen[entry.Name]; ok {
+			return
+		}
+		seen[entry.Name] = struct{}{}
+		targets = append(targets, entry)
+	}
+
+	for _, spec := range specs {
+		if spec.Source == opencode.SourceLocal || spec.Source == opencode.SourceDependency || spec.LinkPath != "" {
+			continue
+		}
+		if entry, ok := byName[spec.Name]; ok {
+			add(entry)
+			continue
+		}
+		add(cache.Entry{Name: spec.Name})
+	}
+	if all {
+		for _, entry := range entries {
+			add(entry)
+		}
+	}
+
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	return targets
+}
+
+// selectLockedTargets returns the cache entries for every package recorded in
+// the lockfile, keyed to their locked metadata.
+func selectLockedTargets(lock lockfile.File, entries []cache.Entry) ([]cache.Entry, map[string]lockfile.Package) {
+	byName := map[string]cache.Entry{}
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+
+	locked := map[string]lockfile.Package{}
+	for _, plugin := range lock.Plugins {
+		locked[plugin.Name] = plugin.Package
+		for _, dep := range plugin.Dependencies {
+			locked[dep.Name] = dep
+		}
+	}
+
+	targets := make([]cache.Entry, 0, len(locked))
+	for name := range locked {
+		entry, ok := byName[name]
+		if !ok {
+			entry = cache.Entry{Name: name}
+		}
+		targets = append(targets, entry)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	return targets, locked
+}
+
+// verifyEntry downloads the published tarball for the installed version,
+// checks its integrity and compares its files with the cache directory.
+func verifyEntry(ctx context.Context, registry npm.Registry, entry cache.Entry) (cache.FileDiff, error) {
+	if entry.Version == "" {
+		return cache.FileDiff{}, fmt.Errorf("installed version is unknown")
+	}
+	info, err := registry.FetchVersion(ctx, entry.Name, entry.Version)
+	if err != nil {
+		return cache.FileDiff{}, err
+	}
+	return verifyTarball(ctx, registry, entry, info.Tarball, info.Integrity, info.Shasum)
+}
+
+// verifyTarball downloads a tarball, checks it against the expected digests and
+// compares its files with the cache directory.
+func verifyTarball(ctx context.Context, registry npm.Registry, entry cache.Entry, tarballURL string, integrity string, shasum string) (cache.FileDiff, error) {
+	data, err := registry.FetchTarball(ctx, tarballURL)
+	if err != nil {
+		return cache.FileDiff{}, err
+	}
+	if err := npm.VerifyIntegrity(data, integrity, shasum); err != nil {
+		return cache.FileDiff{}, fmt.Errorf("tarball %s: %w", tarballURL, err)
+	}
+	files, manifest, err := npm.TarballFiles(data)
+	if err != nil {
+		return cache.FileDiff{}, err
+	}
+	return cache.CompareFiles(files, manifest, entry.Path)
+}
+
+func renderVerifyReport(w io.Writer, rows []verifyRow) {
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No npm plugins found.")
+		return
+	}
+
+	headers := []string{"NAME", "VERSION", "STATUS", "MODIFIED", "ADDED", "MISSING"}
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		table = append(table, []string{
+			row.Name,
+			row.Version,
+			row.Status,
+			strconv.Itoa(len(row.Diff.Modified)),
+			strconv.Itoa(len(row.Diff.Added)),
+			strconv.Itoa(len(row.Diff.Missing)),
+		})
+	}
+	renderTable(w, headers, table)
+
+	for _, row := range rows {
+		if row.Error != "" {
+			fmt.Fprintln(w, "")
+			fmt.Fprintf(w, "%s: %s\n", row.Name, row.Error)
+			continue
+		}
+		if row.Diff.Clean() {
+			continue
+		}
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "%s@%s:\n", row.Name, row.Version)
+		for _, name := range row.Diff.Modified {
+			fmt.Fprintf(w, "  M %s\n", name)
+		}
+		for _, name := range row.Diff.Added {
+			fmt.Fprintf(w, "  A %s\n", name)
+		}
+		for _, name := range row.Diff.Missing {
+			fmt.Fprintf(w, "  D %s\n", name)
+		}
+		if row.Quarantined != "" {
+			fmt.Fprintf(w, "  quarantined to %s\n", row.Quarantined)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AksharP5/Patchline/internal/model"
+)
+
+func TestVerifyCommandDetectsTampering(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	alphaManifest := `{"name":"alpha","version":"1.0.0"}`
+	betaManifest := `{"name":"beta","version":"2.0.0"}`
+	server := newTestRegistry(t, map[string]map[string]string{
+		"alpha@1.0.0": {"package/package.json": alphaManifest, "package/index.js": "ok"},
+		"beta@2.0.0":  {"package/package.json": betaManifest, "package/index.js": "ok"},
+	})
+
+	cacheDir := filepath.Join(root, "cache")
+	writePackageJSON(t, filepath.Join(cacheDir, "alpha"), alphaManifest)
+	writeTestFile(t, filepath.Join(cacheDir, "alpha", "index.js"), "ok")
+	writePackageJSON(t, filepath.Join(cacheDir, "beta"), betaManifest)
+	writeTestFile(t, filepath.Join(cacheDir, "beta", "index.js"), "evil")
+
+	opts := CommonOptions{
+		ProjectRoot: root,
+		CacheDir:    cacheDir,
+		SnapshotDir: filepath.Join(root, "data", "snapshots"),
+		Registry:    server.URL,
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := verifyCommand(opts, verifyOptions{JSON: true, Quarantine: true}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("expected failure exit code for tampered plugin, got %d: %s", code, stderr.String())
+	}
+
+	var rows []verifyRow
+	if err := json.Unmarshal(stdout.Bytes(), &rows); err != nil {
+		t.Fatalf("decode rows: %v", err)
+	}
+	byName := map[string]verifyRow{}
+	for _, row := range rows {
+		byName[row.Name] = row
+	}
+	if byName["alpha"].Status != string(model.StatusOK) {
+		t.Fatalf("expected alpha verified, got %#v", byName["alpha"])
+	}
+	beta := byName["beta"]
+	if beta.Status != string(model.StatusTampered) || len(beta.Diff.Modified) != 1 || beta.Diff.Modified[0] != "index.js" {
+		t.Fatalf("expected beta tampered index.js, got %#v", beta)
+	}
+	if !strings.HasPrefix(beta.Quarantined, filepath.Join(opts.SnapshotDir, "quarantine")) {
+		t.Fatalf("expected beta quarantined under the snapshot dir, got %q", beta.Quarantined)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "beta")); !os.IsNotExist(err) {
+		t.Fatalf("expected beta removed from cache, got %v", err)
+	}
+}
+
+func TestVerifyCommandRejectsOffline(t *testing.T) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	code := verifyCommand(CommonOptions{Offline: true}, verifyOptions{}, &stdout, &stderr)
+	if code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+}
+
+// newTestRegistry serves version manifests and tarballs for the given
+// "name@version" packages.
+func newTestRegistry(t *testing.T, packages map[string]map[string]string) *httptest.Server {
+	t.Helper()
+	tarballs := map[string][]byte{}
+	for key, files := range packages {
+		tarballs[key] = buildTestTarball(t, files)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		if strings.HasSuffix(path, ".tgz") {
+			data, ok := tarballs[strings.TrimSuffix(path, ".tgz")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+			return
+		}
+
+		at := strings.LastIndex(path, "/")
+		if at <= 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		key := path[:at] + "@" + path[at+1:]
+		data, ok := tarballs[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sum := sha512.Sum512(data)
+		payload := map[string]any{
+			"name":    path[:at],
+			"version": path[at+1:],
+			"dist": map[string]string{
+				"tarball":   server.URL + "/" + key + ".tgz",
+				"integrity": "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(payload)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func buildTestTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+// Package ignore matches paths against .gitignore files.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the ignore file read from each directory.
+const FileName = ".gitignore"
+
+type rule struct {
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher holds the rules of every .gitignore loaded so far. Paths are slash
+// separated and relative to the walk root.
+type Matcher struct {
+	rules []rule
+}
+
+// Load reads the .gitignore in dir, if any. rel is dir relative to the walk
+// root ("" for the root itself). Parent directories must be loaded first so
+// deeper rules take precedence.
+func (m *Matcher) Load(dir string, rel string) error {
+	file, err := os.Open(filepath.Join(dir, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m.Add(rel, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Add parses one .gitignore line that applies below base.
+func (m *Matcher) Add(base string, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	r := rule{base: strings.Trim(base, "/")}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+	r.pattern = line
+	m.rules = append(m.rules, r)
+}
+
+// Ignored reports whether rel is ignored. The last matching rule wins.
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, r.base+"/")
+		}
+		var matched bool
+		if r.anchored {
+			matched = matchSegments(strings.Split(r.pattern, "/"), strings.Split(sub, "/"))
+		} else {
+			matched, _ = path.Match(r.pattern, path.Base(sub))
+		}
+		if matched {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches any number of segments.
+func matchSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], name[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
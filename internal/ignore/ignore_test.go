@@ -0,0 +1,65 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcherRules(t *testing.T) {
+	var m Matcher
+	for _, line := range []string{
+		"# comment",
+		"dist",
+		"build/",
+		"/tmp",
+		"docs/**/generated",
+		"*.log",
+		"!keep.log",
+	} {
+		m.Add("", line)
+	}
+	m.Add("packages/web", "cache")
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"dist", true, true},
+		{"packages/app/dist", true, true},
+		{"build", true, true},
+		{"build", false, false},
+		{"tmp", true, true},
+		{"packages/tmp", true, false},
+		{"docs/a/b/generated", true, true},
+		{"docs/generated", true, true},
+		{"server.log", false, true},
+		{"keep.log", false, false},
+		{"packages/web/cache", true, true},
+		{"packages/app/cache", true, false},
+		{"src", true, false},
+	}
+	for _, tc := range cases {
+		if got := m.Ignored(tc.path, tc.isDir); got != tc.want {
+			t.Fatalf("Ignored(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestMatcherLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("vendor/\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var m Matcher
+	if err := m.Load(dir, ""); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := m.Load(filepath.Join(dir, "missing"), "missing"); err != nil {
+		t.Fatalf("expected missing file to be skipped, got %v", err)
+	}
+	if !m.Ignored("vendor", true) {
+		t.Fatalf("expected vendor to be ignored")
+	}
+}
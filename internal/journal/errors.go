@@ -0,0 +1,12 @@
+package journal
+
+import "errors"
+
+var (
+	// ErrOperationNotFound indicates no journal record matches an id.
+	ErrOperationNotFound = errors.New("operation not found")
+	// ErrAlreadyUndone indicates an operation was already reversed.
+	ErrAlreadyUndone = errors.New("operation already undone")
+	// ErrInvalidJournalDir indicates the journal directory is invalid.
+	ErrInvalidJournalDir = errors.New("invalid journal directory")
+)
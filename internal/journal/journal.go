@@ -0,0 +1,218 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AksharP5/Patchline/internal/snapshot"
+)
+
+// FileName is the append-only journal inside the journal directory.
+const FileName = "journal.jsonl"
+
+// Operation records one mutating command and everything it changed.
+type Operation struct {
+	ID        string        `json:"id"`
+	Timestamp time.Time     `json:"timestamp"`
+	User      string        `json:"user"`
+	Command   []string      `json:"command"`
+	ExitCode  int           `json:"exitCode"`
+	Files     []FileChange  `json:"files,omitempty"`
+	Cache     []CacheChange `json:"cache,omitempty"`
+	Snapshots []string      `json:"snapshots,omitempty"`
+	Pruned    []string      `json:"pruned,omitempty"`
+	Undoes    string        `json:"undoes,omitempty"`
+}
+
+// FileChange records a file's content hash before and after an operation.
+// An empty hash means the file did not exist.
+type FileChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// CacheChange records a cache directory that appeared, disappeared or changed
+// version during an operation.
+type CacheChange struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Cache change actions.
+const (
+	CacheRemoved = "removed"
+	CacheAdded   = "added"
+	CacheChanged = "changed"
+)
+
+// Journal is an append-only operation log with content-addressed file copies.
+type Journal struct {
+	Directory string
+}
+
+// DirFor returns the journal directory inside the snapshot directory.
+func DirFor(snapshotDir string) string {
+	return filepath.Join(snapshotDir, "journal")
+}
+
+// Empty reports whether the operation changed nothing.
+func (op Operation) Empty() bool {
+	return len(op.Files) == 0 && len(op.Cache) == 0 && len(op.Snapshots) == 0 && len(op.Pruned) == 0
+}
+
+// Append assigns an id if needed and appends the operation to the journal.
+func (j Journal) Append(op Operation) (Operation, error) {
+	if j.Directory == "" {
+		return Operation{}, fmt.Errorf("%w: directory is empty", ErrInvalidJournalDir)
+	}
+	if op.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return Operation{}, err
+		}
+		op.ID = id
+	}
+	if op.Timestamp.IsZero() {
+		op.Timestamp = time.Now().UTC()
+	}
+	if err := os.MkdirAll(j.Directory, 0o755); err != nil {
+		return Operation{}, fmt.Errorf("create journal dir: %w", err)
+	}
+
+	data, err := json.Marshal(op)
+	if err != nil {
+		return Operation{}, fmt.Errorf("marshal operation: %w", err)
+	}
+	file, err := os.OpenFile(j.path(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Operation{}, fmt.Errorf("open journal: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return Operation{}, fmt.Errorf("write journal: %w", err)
+	}
+	return op, nil
+}
+
+// List returns every operation, oldest first. Unreadable lines are skipped.
+func (j Journal) List() ([]Operation, error) {
+	if j.Directory == "" {
+		return nil, fmt.Errorf("%w: directory is empty", ErrInvalidJournalDir)
+	}
+	data, err := os.ReadFile(j.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	ops := []Operation{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal(line, &op); err != nil {
+			continue
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+	return ops, nil
+}
+
+// Find returns the operation with the given id or unique id prefix.
+func (j Journal) Find(id string) (Operation, error) {
+	ops, err := j.List()
+	if err != nil {
+		return Operation{}, err
+	}
+	matches := []Operation{}
+	for _, op := range ops {
+		if op.ID == id {
+			return op, nil
+		}
+		if strings.HasPrefix(op.ID, id) {
+			matches = append(matches, op)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return Operation{}, fmt.Errorf("%w: %s", ErrOperationNotFound, id)
+}
+
+// LastUndoable returns the newest operation that changed files, is not an
+// undo and has not been undone.
+func (j Journal) LastUndoable() (Operation, error) {
+	ops, err := j.List()
+	if err != nil {
+		return Operation{}, err
+	}
+	undone := UndoneIDs(ops)
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].Undoes != "" || len(ops[i].Files) == 0 {
+			continue
+		}
+		if _, ok := undone[ops[i].ID]; ok {
+			continue
+		}
+		return ops[i], nil
+	}
+	return Operation{}, ErrOperationNotFound
+}
+
+// UndoneIDs returns the ids of operations reversed by a later undo.
+func UndoneIDs(ops []Operation) map[string]struct{} {
+	undone := map[string]struct{}{}
+	for _, op := range ops {
+		if op.Undoes != "" {
+			undone[op.Undoes] = struct{}{}
+		}
+	}
+	return undone
+}
+
+// PutContent stores a file copy and returns its hash.
+func (j Journal) PutContent(data []byte) (string, error) {
+	return j.blobs().PutBlob(data)
+}
+
+// Content returns a stored file copy.
+func (j Journal) Content(hash string) ([]byte, error) {
+	return j.blobs().Blob(hash)
+}
+
+func (j Journal) blobs() snapshot.Store {
+	return snapshot.Store{Directory: j.Directory}
+}
+
+func (j Journal) path() string {
+	return filepath.Join(j.Directory, FileName)
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate operation id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournalAppendListFind(t *testing.T) {
+	j := Journal{Directory: t.TempDir()}
+	first, err := j.Append(Operation{Command: []string{"patchline", "upgrade", "alpha"}, Files: []FileChange{{Path: "/tmp/a", Before: "sha256-1", After: "sha256-2"}}})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	second, err := j.Append(Operation{Command: []string{"patchline", "sync"}, Cache: []CacheChange{{Name: "alpha", Path: "/tmp/cache/alpha", Action: CacheRemoved}}})
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if len(first.ID) != 8 || first.ID == second.ID {
+		t.Fatalf("expected distinct ids, got %q and %q", first.ID, second.ID)
+	}
+
+	ops, err := j.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ops) != 2 || ops[0].ID != first.ID || ops[1].ID != second.ID {
+		t.Fatalf("expected operations oldest first, got %#v", ops)
+	}
+
+	found, err := j.Find(second.ID[:4])
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if found.ID != second.ID {
+		t.Fatalf("expected %s, got %s", second.ID, found.ID)
+	}
+	if _, err := j.Find("zzzz"); !errors.Is(err, ErrOperationNotFound) {
+		t.Fatalf("expected ErrOperationNotFound, got %v", err)
+	}
+}
+
+func TestJournalSkipsCorruptLines(t *testing.T) {
+	j := Journal{Directory: t.TempDir()}
+	if _, err := j.Append(Operation{Command: []string{"patchline", "lock"}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	file, err := os.OpenFile(filepath.Join(j.Directory, FileName), os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := file.WriteString("{not json\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	file.Close()
+
+	ops, err := j.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("expected one readable operation, got %d", len(ops))
+	}
+}
+
+func TestJournalLastUndoable(t *testing.T) {
+	j := Journal{Directory: t.TempDir()}
+	if _, err := j.LastUndoable(); !errors.Is(err, ErrOperationNotFound) {
+		t.Fatalf("expected ErrOperationNotFound for empty journal, got %v", err)
+	}
+
+	files := []FileChange{{Path: "opencode.json", Before: "sha256-a", After: "sha256-b"}}
+	first, _ := j.Append(Operation{Command: []string{"patchline", "upgrade", "alpha"}, Files: files})
+	second, _ := j.Append(Operation{Command: []string{"patchline", "upgrade", "beta"}, Files: files})
+	if _, err := j.Append(Operation{Command: []string{"patchline", "undo"}, Undoes: second.ID, Files: files}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := j.Append(Operation{Command: []string{"patchline", "sync"}, Cache: []CacheChange{{Name: "alpha", Path: "alpha", Action: CacheRemoved}}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := j.Append(Operation{Command: []string{"patchline", "snapshot"}, Snapshots: []string{"alpha-1"}}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	op, err := j.LastUndoable()
+	if err != nil {
+		t.Fatalf("last undoable: %v", err)
+	}
+	if op.ID != first.ID {
+		t.Fatalf("expected %s, got %s", first.ID, op.ID)
+	}
+}
+
+func TestJournalContentRoundTrip(t *testing.T) {
+	j := Journal{Directory: t.TempDir()}
+	hash, err := j.PutContent([]byte("{\"plugin\": []}\n"))
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	data, err := j.Content(hash)
+	if err != nil {
+		t.Fatalf("content: %v", err)
+	}
+	if string(data) != "{\"plugin\": []}\n" {
+		t.Fatalf("unexpected content %q", string(data))
+	}
+}
@@ -0,0 +1,10 @@
+package lockfile
+
+import "errors"
+
+var (
+	// ErrLockfileNotFound indicates no patchline.lock exists at the expected path.
+	ErrLockfileNotFound = errors.New("lockfile not found")
+	// ErrUnsupportedVersion indicates the lockfile was written by a newer format.
+	ErrUnsupportedVersion = errors.New("unsupported lockfile version")
+)
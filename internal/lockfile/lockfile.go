@@ -0,0 +1,101 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+)
+
+// FileName is the lockfile name written next to the project config.
+const FileName = "patchline.lock"
+
+// FormatVersion is the lockfile format this build reads and writes.
+const FormatVersion = 1
+
+// File is the on-disk lockfile.
+type File struct {
+	LockfileVersion int      `json:"lockfileVersion"`
+	Plugins         []Plugin `json:"plugins"`
+}
+
+// Plugin records the exact bits a declared plugin resolves to.
+type Plugin struct {
+	Name         string    `json:"name"`
+	Spec         string    `json:"spec"`
+	Source       string    `json:"source"`
+	Package      Package   `json:"package"`
+	Dependencies []Package `json:"dependencies,omitempty"`
+}
+
+// Package is one resolved npm package version.
+type Package struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Registry  string `json:"registry"`
+	Resolved  string `json:"resolved"`
+	Integrity string `json:"integrity"`
+	Shasum    string `json:"shasum,omitempty"`
+}
+
+// PathFor returns the lockfile path for the given project directory.
+func PathFor(dir string) string {
+	return filepath.Join(dir, FileName)
+}
+
+// Plugin returns the locked plugin with the given name.
+func (f File) Plugin(name string) (Plugin, bool) {
+	for _, plugin := range f.Plugins {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// Load reads a lockfile from disk.
+func Load(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return File{}, fmt.Errorf("%w: %s", ErrLockfileNotFound, path)
+		}
+		return File{}, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return File{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if file.LockfileVersion > FormatVersion {
+		return File{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, file.LockfileVersion)
+	}
+	return file, nil
+}
+
+// Save writes the lockfile with plugins and dependencies in a stable order.
+func Save(path string, file File) error {
+	file.LockfileVersion = FormatVersion
+	sort.Slice(file.Plugins, func(i, j int) bool {
+		return file.Plugins[i].Name < file.Plugins[j].Name
+	})
+	for i := range file.Plugins {
+		deps := file.Plugins[i].Dependencies
+		sort.Slice(deps, func(a, b int) bool {
+			return deps[a].Name < deps[b].Name
+		})
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal lockfile: %w", err)
+	}
+	data = append(data, '\n')
+	if err := atomicfile.Write(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	path := PathFor(t.TempDir())
+	file := File{
+		Plugins: []Plugin{
+			{
+				Name:    "zeta",
+				Spec:    "zeta@1.0.0",
+				Package: Package{Name: "zeta", Version: "1.0.0"},
+			},
+			{
+				Name:    "alpha",
+				Spec:    "alpha@2.0.0",
+				Package: Package{Name: "alpha", Version: "2.0.0", Integrity: "sha512-abc"},
+				Dependencies: []Package{
+					{Name: "dep-b", Version: "1.0.0"},
+					{Name: "dep-a", Version: "1.0.0"},
+				},
+			},
+		},
+	}
+	if err := Save(path, file); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.LockfileVersion != FormatVersion {
+		t.Fatalf("expected version %d, got %d", FormatVersion, loaded.LockfileVersion)
+	}
+	if loaded.Plugins[0].Name != "alpha" || loaded.Plugins[0].Dependencies[0].Name != "dep-a" {
+		t.Fatalf("expected sorted plugins and dependencies, got %#v", loaded.Plugins)
+	}
+	plugin, ok := loaded.Plugin("alpha")
+	if !ok || plugin.Package.Integrity != "sha512-abc" {
+		t.Fatalf("expected alpha lookup, got %#v", plugin)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(PathFor(dir)); !errors.Is(err, ErrLockfileNotFound) {
+		t.Fatalf("expected ErrLockfileNotFound, got %v", err)
+	}
+
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte(`{"lockfileVersion": 99}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Load(path); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
@@ -6,9 +6,14 @@ const (
 	StatusOK        Status = "ok"
 	StatusMissing   Status = "missing"
 	StatusMismatch  Status = "mismatch"
+	StatusCorrupt   Status = "corrupt"
+	StatusTampered  Status = "tampered"
 	StatusUnmanaged Status = "unmanaged"
 	StatusOutdated  Status = "outdated"
 	StatusUnknown   Status = "unknown"
+	StatusModified  Status = "modified"
+	StatusLinked    Status = "linked"
+	StatusDisabled  Status = "disabled"
 )
 
 type Plugin struct {
@@ -20,4 +25,6 @@ type Plugin struct {
 	ConfigPath     string
 	CachePath      string
 	LocalDirectory string
+	Template       string
+	Issues         []string
 }
@@ -7,4 +7,6 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 	// ErrPackageNotFound indicates the npm package was not found in the registry.
 	ErrPackageNotFound = errors.New("package not found")
+	// ErrIntegrityMismatch indicates downloaded bytes do not match the published digest.
+	ErrIntegrityMismatch = errors.New("integrity mismatch")
 )
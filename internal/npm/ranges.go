@@ -0,0 +1,249 @@
+package npm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is a parsed npm-style version range such as "^1.2.0", "<3" or
+// ">=1.0.0 <2.0.0 || 3.x". Prerelease tags are ignored when matching.
+type Range struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op      string
+	version Semver
+}
+
+// ParseRange parses an npm version range expression.
+func ParseRange(expr string) (Range, error) {
+	r := Range{raw: strings.TrimSpace(expr)}
+	for _, part := range strings.Split(expr, "||") {
+		set, err := parseComparatorSet(part)
+		if err != nil {
+			return Range{}, fmt.Errorf("parse range %q: %w", expr, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as written.
+func (r Range) String() string {
+	return r.raw
+}
+
+// Contains reports whether version satisfies the range.
+func (r Range) Contains(version string) bool {
+	v, ok := parseSemver(version)
+	if !ok {
+		return false
+	}
+	for _, set := range r.sets {
+		if setContains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxSatisfying returns the highest version that satisfies the range.
+func (r Range) MaxSatisfying(versions []string) (string, error) {
+	return selectHighest(versions, func(candidate Semver) bool {
+		for _, set := range r.sets {
+			if setContains(set, candidate) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func setContains(set []comparator, v Semver) bool {
+	for _, c := range set {
+		cmp := compareSemver(v, c.version)
+		var ok bool
+		switch c.op {
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func parseComparatorSet(expr string) ([]comparator, error) {
+	tokens := strings.Fields(expr)
+	if len(tokens) == 3 && tokens[1] == "-" {
+		return parseHyphenRange(tokens[0], tokens[2])
+	}
+
+	set := []comparator{}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if isOperator(token) && i+1 < len(tokens) {
+			token += tokens[i+1]
+			i++
+		}
+		comparators, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, comparators...)
+	}
+	return set, nil
+}
+
+func parseHyphenRange(low string, high string) ([]comparator, error) {
+	lower, _, ok := parsePartial(low)
+	if !ok {
+		return nil, fmt.Errorf("invalid version %q", low)
+	}
+	upper, parts, ok := parsePartial(high)
+	if !ok {
+		return nil, fmt.Errorf("invalid version %q", high)
+	}
+	set := []comparator{{op: ">=", version: lower}}
+	switch parts {
+	case 0:
+	case 1:
+		set = append(set, comparator{op: "<", version: Semver{Major: upper.Major + 1}})
+	case 2:
+		set = append(set, comparator{op: "<", version: Semver{Major: upper.Major, Minor: upper.Minor + 1}})
+	default:
+		set = append(set, comparator{op: "<=", version: upper})
+	}
+	return set, nil
+}
+
+func isOperator(token string) bool {
+	switch token {
+	case "<", "<=", ">", ">=", "=", "^", "~":
+		return true
+	}
+	return false
+}
+
+func parseComparator(token string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{"<=", ">=", "<", ">", "=", "^", "~"} {
+		if strings.HasPrefix(token, prefix) {
+			op = prefix
+			token = strings.TrimPrefix(token, prefix)
+			break
+		}
+	}
+	if op == "~" {
+		token = strings.TrimPrefix(token, ">")
+	}
+
+	v, parts, ok := parsePartial(token)
+	if !ok {
+		return nil, fmt.Errorf("invalid version %q", token)
+	}
+
+	nextMajor := Semver{Major: v.Major + 1}
+	nextMinor := Semver{Major: v.Major, Minor: v.Minor + 1}
+	switch op {
+	case "", "=":
+		switch parts {
+		case 0:
+			return nil, nil
+		case 1:
+			return []comparator{{">=", v}, {"<", nextMajor}}, nil
+		case 2:
+			return []comparator{{">=", v}, {"<", nextMinor}}, nil
+		}
+		return []comparator{{"=", v}}, nil
+	case ">":
+		switch parts {
+		case 0:
+			return []comparator{{"<", Semver{}}}, nil
+		case 1:
+			return []comparator{{">=", nextMajor}}, nil
+		case 2:
+			return []comparator{{">=", nextMinor}}, nil
+		}
+		return []comparator{{">", v}}, nil
+	case ">=":
+		return []comparator{{">=", v}}, nil
+	case "<":
+		if parts == 0 {
+			return []comparator{{"<", Semver{}}}, nil
+		}
+		return []comparator{{"<", v}}, nil
+	case "<=":
+		switch parts {
+		case 0:
+			return nil, nil
+		case 1:
+			return []comparator{{"<", nextMajor}}, nil
+		case 2:
+			return []comparator{{"<", nextMinor}}, nil
+		}
+		return []comparator{{"<=", v}}, nil
+	case "~":
+		switch parts {
+		case 0:
+			return nil, nil
+		case 1:
+			return []comparator{{">=", v}, {"<", nextMajor}}, nil
+		}
+		return []comparator{{">=", v}, {"<", nextMinor}}, nil
+	case "^":
+		switch {
+		case parts == 0:
+			return nil, nil
+		case v.Major > 0 || parts == 1:
+			return []comparator{{">=", v}, {"<", nextMajor}}, nil
+		case v.Minor > 0 || parts == 2:
+			return []comparator{{">=", v}, {"<", nextMinor}}, nil
+		}
+		return []comparator{{">=", v}, {"<", Semver{Patch: v.Patch + 1}}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %q", op)
+}
+
+// parsePartial parses versions such as "1", "1.2", "1.x", "1.2.3" or "*" and
+// returns how many components were given.
+func parsePartial(value string) (Semver, int, bool) {
+	clean := strings.TrimPrefix(strings.TrimSpace(value), "v")
+	if idx := strings.IndexAny(clean, "-+"); idx >= 0 {
+		clean = clean[:idx]
+	}
+	if clean == "" || clean == "*" || clean == "x" || clean == "X" {
+		return Semver{}, 0, true
+	}
+
+	parts := strings.Split(clean, ".")
+	if len(parts) > 3 {
+		return Semver{}, 0, false
+	}
+	numbers := [3]int{}
+	count := 0
+	for i, part := range parts {
+		if part == "*" || part == "x" || part == "X" {
+			break
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return Semver{}, 0, false
+		}
+		numbers[i] = number
+		count++
+	}
+	return Semver{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, count, true
+}
@@ -0,0 +1,67 @@
+package npm
+
+import "testing"
+
+func TestRangeContains(t *testing.T) {
+	cases := []struct {
+		expr    string
+		version string
+		want    bool
+	}{
+		{"<3", "2.9.9", true},
+		{"<3", "3.0.0", false},
+		{"<=2", "2.5.0", true},
+		{"<=2", "3.0.0", false},
+		{"^1.2.0", "1.9.0", true},
+		{"^1.2.0", "2.0.0", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"1.x", "1.4.0", true},
+		{"1.x", "2.0.0", false},
+		{"*", "9.9.9", true},
+		{">=1.0.0 <2.0.0", "1.5.0", true},
+		{">= 1.0.0 < 2.0.0", "2.0.0", false},
+		{"1.0.0 - 1.4", "1.4.9", true},
+		{"1.0.0 - 1.4", "1.5.0", false},
+		{"<1 || >=3", "3.1.0", true},
+		{"<1 || >=3", "2.0.0", false},
+		{"1.2.3", "1.2.3", true},
+		{">2", "2.9.0", false},
+		{">2", "3.0.0", true},
+	}
+
+	for _, tc := range cases {
+		r, err := ParseRange(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := r.Contains(tc.version); got != tc.want {
+			t.Fatalf("%q contains %q: expected %v, got %v", tc.expr, tc.version, tc.want, got)
+		}
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	for _, expr := range []string{"abc", ">=1.a", "1.2.3.4"} {
+		if _, err := ParseRange(expr); err == nil {
+			t.Fatalf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestRangeMaxSatisfying(t *testing.T) {
+	r, err := ParseRange("^1.0.0")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	got, err := r.MaxSatisfying([]string{"0.9.0", "1.2.0", "1.10.0", "2.0.0"})
+	if err != nil {
+		t.Fatalf("max satisfying: %v", err)
+	}
+	if got != "1.10.0" {
+		t.Fatalf("expected 1.10.0, got %s", got)
+	}
+}
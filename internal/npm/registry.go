@@ -28,9 +28,37 @@ type registryResponse struct {
 var defaultRegistryBaseURL = "https://registry.npmjs.org"
 var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
 
+// Registry identifies an npm registry endpoint. The zero value talks to the
+// public npm registry with the default HTTP client.
+type Registry struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// URL returns the registry base URL, falling back to the public registry.
+func (r Registry) URL() string {
+	if strings.TrimSpace(r.BaseURL) != "" {
+		return r.BaseURL
+	}
+	return defaultRegistryBaseURL
+}
+
+func (r Registry) httpClient() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return defaultHTTPClient
+}
+
 // FetchPackageInfo retrieves registry metadata for the given package.
+func (r Registry) FetchPackageInfo(ctx context.Context, name string) (PackageInfo, error) {
+	return fetchPackageInfo(ctx, r.httpClient(), r.URL(), name)
+}
+
+// FetchPackageInfo retrieves registry metadata for the given package from the
+// public registry.
 func FetchPackageInfo(ctx context.Context, name string) (PackageInfo, error) {
-	return fetchPackageInfo(ctx, defaultHTTPClient, defaultRegistryBaseURL, name)
+	return Registry{}.FetchPackageInfo(ctx, name)
 }
 
 func fetchPackageInfo(ctx context.Context, client *http.Client, baseURL string, name string) (PackageInfo, error) {
@@ -46,30 +74,12 @@ func fetchPackageInfo(ctx context.Context, client *http.Client, baseURL string,
 	}
 
 	endpoint := baseURL + "/" + url.PathEscape(name)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return PackageInfo{}, fmt.Errorf("fetch %s: %w", name, err)
-	}
-	req.Header.Set("Accept", "application/vnd.npm.install-v1+json")
-
-	resp, err := client.Do(req)
+	resp, err := get(ctx, client, endpoint, "application/vnd.npm.install-v1+json", name)
 	if err != nil {
-		return PackageInfo{}, fmt.Errorf("fetch %s: %w", name, err)
+		return PackageInfo{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return PackageInfo{}, ErrPackageNotFound
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		message := strings.TrimSpace(string(body))
-		if message == "" {
-			message = resp.Status
-		}
-		return PackageInfo{}, fmt.Errorf("fetch %s: npm registry error: %s", name, message)
-	}
-
 	var payload registryResponse
 	decoder := json.NewDecoder(resp.Body)
 	if err := decoder.Decode(&payload); err != nil {
@@ -93,3 +103,35 @@ func fetchPackageInfo(ctx context.Context, client *http.Client, baseURL string,
 		Versions: versions,
 	}, nil
 }
+
+// get issues a GET request and maps registry failures to errors labelled with
+// the package name. Callers must close the response body.
+func get(ctx context.Context, client *http.Client, endpoint string, accept string, label string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", label, err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", label, err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrPackageNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = resp.Status
+		}
+		return nil, fmt.Errorf("fetch %s: npm registry error: %s", label, message)
+	}
+	return resp, nil
+}
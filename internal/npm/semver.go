@@ -2,10 +2,13 @@ package npm
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+var exactVersionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
 // CompareSemver compares two semver strings and returns comparison result.
 // The bool return is false when parsing fails.
 func CompareSemver(current string, latest string) (int, bool) {
@@ -20,6 +23,13 @@ func CompareSemver(current string, latest string) (int, bool) {
 	return compareSemver(currentSemver, latestSemver), true
 }
 
+// IsExactVersion reports whether version names a single release such as
+// "1.2.3" or "2.0.0-beta.1", rather than a range, a dist-tag or a partial
+// version like "1.2".
+func IsExactVersion(version string) bool {
+	return exactVersionPattern.MatchString(strings.TrimSpace(version))
+}
+
 // Semver represents a parsed semantic version.
 type Semver struct {
 	Major int
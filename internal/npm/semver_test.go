@@ -35,6 +35,19 @@ func TestCompareSemver(t *testing.T) {
 	}
 }
 
+func TestIsExactVersion(t *testing.T) {
+	for _, version := range []string{"1.2.3", "v1.2.3", "2.0.0-beta.1", "1.0.0+build.5"} {
+		if !IsExactVersion(version) {
+			t.Fatalf("expected %q to be exact", version)
+		}
+	}
+	for _, version := range []string{"", "1.2", "1", "^1.2.3", "~1.2.3", "1.x", ">=1.0.0", "latest", "1.2.3 || 2.0.0"} {
+		if IsExactVersion(version) {
+			t.Fatalf("expected %q not to be exact", version)
+		}
+	}
+}
+
 func TestSelectTargetVersion(t *testing.T) {
 	versions := []string{"1.2.0", "1.3.5", "2.0.0", "invalid", "1.3.1"}
 
@@ -0,0 +1,189 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// maxTarballSize caps tarball downloads so a misbehaving registry cannot
+// exhaust memory.
+const maxTarballSize = 256 << 20
+
+// VersionInfo captures the registry metadata for a single published version.
+type VersionInfo struct {
+	Name         string
+	Version      string
+	Tarball      string
+	Integrity    string
+	Shasum       string
+	Dependencies map[string]string
+}
+
+type versionResponse struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Dependencies map[string]string `json:"dependencies"`
+	Dist         struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+		Shasum    string `json:"shasum"`
+	} `json:"dist"`
+}
+
+// FetchVersion retrieves the manifest of one published version.
+func (r Registry) FetchVersion(ctx context.Context, name string, version string) (VersionInfo, error) {
+	if name == "" || version == "" {
+		return VersionInfo{}, fmt.Errorf("package name and version are required")
+	}
+	baseURL := strings.TrimRight(strings.TrimSpace(r.URL()), "/")
+	endpoint := baseURL + "/" + url.PathEscape(name) + "/" + url.PathEscape(version)
+	label := name + "@" + version
+
+	resp, err := get(ctx, r.httpClient(), endpoint, "application/json", label)
+	if err != nil {
+		return VersionInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	var payload versionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return VersionInfo{}, fmt.Errorf("fetch %s: %w", label, err)
+	}
+	if payload.Dist.Tarball == "" {
+		return VersionInfo{}, fmt.Errorf("fetch %s: registry response has no tarball", label)
+	}
+
+	return VersionInfo{
+		Name:         payload.Name,
+		Version:      payload.Version,
+		Tarball:      payload.Dist.Tarball,
+		Integrity:    payload.Dist.Integrity,
+		Shasum:       payload.Dist.Shasum,
+		Dependencies: payload.Dependencies,
+	}, nil
+}
+
+// FetchTarball downloads a package tarball.
+func (r Registry) FetchTarball(ctx context.Context, tarballURL string) ([]byte, error) {
+	if tarballURL == "" {
+		return nil, fmt.Errorf("tarball url is required")
+	}
+	resp, err := get(ctx, r.httpClient(), tarballURL, "application/octet-stream", tarballURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTarballSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", tarballURL, err)
+	}
+	if len(data) > maxTarballSize {
+		return nil, fmt.Errorf("fetch %s: tarball exceeds %d bytes", tarballURL, maxTarballSize)
+	}
+	return data, nil
+}
+
+// VerifyIntegrity checks data against a subresource integrity string, falling
+// back to the legacy sha1 shasum when no integrity is published.
+func VerifyIntegrity(data []byte, integrity string, shasum string) error {
+	fields := strings.Fields(integrity)
+	checked := false
+	for _, field := range fields {
+		algo, digest, ok := strings.Cut(field, "-")
+		if !ok {
+			continue
+		}
+		var h hash.Hash
+		switch algo {
+		case "sha512":
+			h = sha512.New()
+		case "sha384":
+			h = sha512.New384()
+		case "sha256":
+			h = sha256.New()
+		case "sha1":
+			h = sha1.New()
+		default:
+			continue
+		}
+		checked = true
+		h.Write(data)
+		if base64.StdEncoding.EncodeToString(h.Sum(nil)) == digest {
+			return nil
+		}
+	}
+	if checked {
+		return ErrIntegrityMismatch
+	}
+
+	if shasum != "" {
+		sum := sha1.Sum(data)
+		if strings.EqualFold(hex.EncodeToString(sum[:]), shasum) {
+			return nil
+		}
+		return ErrIntegrityMismatch
+	}
+	return fmt.Errorf("%w: no integrity or shasum published", ErrIntegrityMismatch)
+}
+
+// TarballFiles lists the regular files in a gzipped package tarball mapped to
+// their sha256 hex digests, and returns the packaged package.json. The leading
+// "package/" directory is stripped from file names.
+func TarballFiles(data []byte) (map[string]string, []byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("open tarball: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string]string{}
+	var manifest []byte
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read tarball: %w", err)
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if _, rest, ok := strings.Cut(name, "/"); ok {
+			name = rest
+		}
+		if name == "" || name == "." || strings.HasPrefix(name, "../") {
+			continue
+		}
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read tarball: %w", err)
+		}
+		sum := sha256.Sum256(content)
+		files[name] = hex.EncodeToString(sum[:])
+		if name == "package.json" {
+			manifest = content
+		}
+	}
+	return files, manifest, nil
+}
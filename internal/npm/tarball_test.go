@@ -0,0 +1,123 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchVersionAndTarball(t *testing.T) {
+	tarball := buildTarball(t, map[string]string{"package/index.js": "module.exports = 1"})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/@scope%2Fpkg/1.0.0", "/@scope/pkg/1.0.0":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"@scope/pkg","version":"1.0.0","dist":{"tarball":"` + server.URL + `/pkg.tgz","integrity":"` + sriSHA512(tarball) + `"}}`))
+		case "/pkg.tgz":
+			_, _ = w.Write(tarball)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	registry := Registry{BaseURL: server.URL, Client: server.Client()}
+	info, err := registry.FetchVersion(context.Background(), "@scope/pkg", "1.0.0")
+	if err != nil {
+		t.Fatalf("fetch version: %v", err)
+	}
+	if info.Tarball != server.URL+"/pkg.tgz" {
+		t.Fatalf("unexpected tarball url %q", info.Tarball)
+	}
+
+	data, err := registry.FetchTarball(context.Background(), info.Tarball)
+	if err != nil {
+		t.Fatalf("fetch tarball: %v", err)
+	}
+	if err := VerifyIntegrity(data, info.Integrity, ""); err != nil {
+		t.Fatalf("verify integrity: %v", err)
+	}
+
+	if _, err := registry.FetchVersion(context.Background(), "absent", "1.0.0"); !errors.Is(err, ErrPackageNotFound) {
+		t.Fatalf("expected ErrPackageNotFound, got %v", err)
+	}
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	data := []byte("payload")
+	sha1Sum := sha1.Sum(data)
+
+	if err := VerifyIntegrity(data, sriSHA512(data), ""); err != nil {
+		t.Fatalf("expected sha512 match, got %v", err)
+	}
+	if err := VerifyIntegrity(data, "", hex.EncodeToString(sha1Sum[:])); err != nil {
+		t.Fatalf("expected shasum match, got %v", err)
+	}
+	if err := VerifyIntegrity([]byte("other"), sriSHA512(data), ""); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected mismatch, got %v", err)
+	}
+	if err := VerifyIntegrity(data, "", ""); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("expected mismatch without digests, got %v", err)
+	}
+}
+
+func TestTarballFilesStripsPackagePrefix(t *testing.T) {
+	data := buildTarball(t, map[string]string{
+		"package/package.json": `{"name":"pkg","version":"1.0.0"}`,
+		"package/lib/index.js": "export {}",
+	})
+
+	files, manifest, err := TarballFiles(data)
+	if err != nil {
+		t.Fatalf("tarball files: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %#v", files)
+	}
+	want := sha256.Sum256([]byte("export {}"))
+	if files["lib/index.js"] != hex.EncodeToString(want[:]) {
+		t.Fatalf("unexpected digest for lib/index.js: %q", files["lib/index.js"])
+	}
+	if string(manifest) != `{"name":"pkg","version":"1.0.0"}` {
+		t.Fatalf("unexpected manifest %q", manifest)
+	}
+}
+
+func buildTarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func sriSHA512(data []byte) string {
+	sum := sha512.Sum512(data)
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,51 @@
+package opencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// SameExceptPlugin reports whether two config contents are equivalent once
+// the given plugin's entries are ignored. Comments and formatting are not
+// compared.
+func SameExceptPlugin(a []byte, b []byte, pluginName string) (bool, error) {
+	left, err := withoutPlugin(a, pluginName)
+	if err != nil {
+		return false, err
+	}
+	right, err := withoutPlugin(b, pluginName)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(left, right), nil
+}
+
+func withoutPlugin(data []byte, pluginName string) (map[string]any, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(sanitizeJSONC(data), &raw); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	for _, key := range []string{"plugin", "plugins"} {
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		list, err := coerceStringSlice(value)
+		if err != nil {
+			continue
+		}
+		kept := []string{}
+		for _, spec := range list {
+			if specName(spec) != pluginName {
+				kept = append(kept, spec)
+			}
+		}
+		if len(kept) == 0 {
+			delete(raw, key)
+			continue
+		}
+		raw[key] = kept
+	}
+	return raw, nil
+}
@@ -0,0 +1,31 @@
+package opencode
+
+import "testing"
+
+func TestSameExceptPlugin(t *testing.T) {
+	snapshot := []byte("{\n  // pinned\n  \"plugin\": [\"alpha@1.0.0\", \"beta@1.0.0\"],\n  \"theme\": \"dark\"\n}\n")
+
+	rewritten := []byte(`{"plugin": ["alpha@2.0.0", "beta@1.0.0"], "theme": "dark"}`)
+	same, err := SameExceptPlugin(snapshot, rewritten, "alpha")
+	if err != nil || !same {
+		t.Fatalf("expected only alpha to differ, got %v %v", same, err)
+	}
+
+	removed := []byte(`{"plugin": ["beta@1.0.0"], "theme": "dark"}`)
+	same, err = SameExceptPlugin(snapshot, removed, "alpha")
+	if err != nil || !same {
+		t.Fatalf("expected removed alpha entry to be ignored, got %v %v", same, err)
+	}
+
+	edited := []byte(`{"plugin": ["alpha@2.0.0", "beta@1.0.0"], "theme": "light"}`)
+	same, err = SameExceptPlugin(snapshot, edited, "alpha")
+	if err != nil || same {
+		t.Fatalf("expected theme change to be detected, got %v %v", same, err)
+	}
+
+	otherPlugin := []byte(`{"plugin": ["alpha@1.0.0", "beta@2.0.0"], "theme": "dark"}`)
+	same, err = SameExceptPlugin(snapshot, otherPlugin, "alpha")
+	if err != nil || same {
+		t.Fatalf("expected beta change to be detected, got %v %v", same, err)
+	}
+}
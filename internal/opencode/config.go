@@ -8,13 +8,46 @@ const (
 	SourceLocal     Source = "local"
 	SourceCustomDir Source = "custom-dir"
 	SourceCustom    Source = "custom"
+	SourceInline    Source = "inline"
+	// SourceDependency marks a dependency declared in a config-dir
+	// package.json rather than a plugin.
+	SourceDependency Source = "dependency"
 )
 
+// configSources lists the config sources in OpenCode precedence order,
+// highest first.
+var configSources = []Source{SourceInline, SourceCustom, SourceCustomDir, SourceProject, SourceGlobal}
+
+// ConfigSources returns the config sources in OpenCode precedence order,
+// highest first.
+func ConfigSources() []Source {
+	return append([]Source{}, configSources...)
+}
+
+// Precedence ranks a source by OpenCode precedence, 0 being the highest.
+// Local plugins and dependencies rank below every config source.
+func Precedence(source Source) int {
+	for i, candidate := range configSources {
+		if candidate == source {
+			return i
+		}
+	}
+	return len(configSources)
+}
+
 type Config struct {
 	Plugins    []string `json:"plugin"`
 	PluginsAlt []string `json:"plugins"`
 }
 
+// PluginSpec is one declared plugin. When the config entry uses {env:} or
+// {file:} substitution, DeclaredSpec holds the expanded value and Template
+// holds the entry as written. For a local plugin, LocalPath is the file
+// OpenCode loads; LocalDir and Version are set when it lives in a folder. For
+// a dependency, ConfigPath is the package.json that declares it. A plugin
+// linked to a local checkout with a file:// entry is named after the
+// checkout's package.json and has LinkPath and Version set. An entry whose
+// substitution fails is named after its Template and has TemplateErr set.
 type PluginSpec struct {
 	Name         string
 	DeclaredSpec string
@@ -22,8 +55,54 @@ type PluginSpec struct {
 	Source       Source
 	ConfigPath   string
 	LocalPath    string
+	LocalDir     string
+	Version      string
+	LinkPath     string
+	Template     string
+	TemplateErr  error
 }
 
+// DiscoveryResult holds the declared plugins. Disabled holds the plugins
+// patchline disabled in the configs that were read, and Unexpanded the entries
+// whose substitution failed; neither are in Plugins.
 type DiscoveryResult struct {
-	Plugins []PluginSpec
+	Plugins       []PluginSpec
+	Disabled      []PluginSpec
+	Unexpanded    []PluginSpec
+	ProjectConfig string
+	GlobalConfig  string
+	Variants      []ConfigVariants
+}
+
+// ConfigVariants records a location holding more than one config file.
+// Patchline reads Used and ignores the others.
+type ConfigVariants struct {
+	Used    string
+	Ignored []string
+}
+
+func (r *DiscoveryResult) addVariants(used string, ignored []string) {
+	if used == "" || len(ignored) == 0 {
+		return
+	}
+	r.Variants = append(r.Variants, ConfigVariants{Used: used, Ignored: ignored})
+}
+
+func (r *DiscoveryResult) addPlugins(plugins []PluginSpec) {
+	for _, plugin := range plugins {
+		if plugin.TemplateErr != nil {
+			r.Unexpanded = append(r.Unexpanded, plugin)
+			continue
+		}
+		r.Plugins = append(r.Plugins, plugin)
+	}
+}
+
+func (r *DiscoveryResult) addDisabled(configPath string, source Source) error {
+	specs, err := loadDisabledSpecs(configPath, source)
+	if err != nil {
+		return err
+	}
+	r.Disabled = append(r.Disabled, specs...)
+	return nil
 }
@@ -0,0 +1,200 @@
+package opencode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+	"github.com/AksharP5/Patchline/internal/npm"
+)
+
+// dependencyManifestName is the file OpenCode installs local plugin
+// dependencies from in a config directory.
+const dependencyManifestName = "package.json"
+
+type dependencyManifest struct {
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// isDependencyManifest reports whether path is a config-dir package.json
+// rather than an OpenCode config file.
+func isDependencyManifest(path string) bool {
+	return filepath.Base(path) == dependencyManifestName
+}
+
+// dependencyManifestPaths returns the package.json files OpenCode installs
+// from: the global config directory, the project's .opencode directory and
+// OPENCODE_CONFIG_DIR. Only existing files are returned.
+func dependencyManifestPaths(projectRoot string, globalConfigPath string, customConfigDir string) []string {
+	dirs := []string{}
+	if globalConfigPath != "" {
+		dirs = append(dirs, filepath.Dir(globalConfigPath))
+	} else {
+		for _, dir := range globalConfigDirs() {
+			if fileExists(filepath.Join(dir, dependencyManifestName)) {
+				dirs = append(dirs, dir)
+				break
+			}
+		}
+	}
+	if projectRoot != "" {
+		dirs = append(dirs, filepath.Join(projectRoot, ".opencode"))
+	}
+	if customConfigDir != "" {
+		dirs = append(dirs, customConfigDir)
+	}
+
+	paths := []string{}
+	for _, dir := range uniqueStrings(dirs) {
+		path := filepath.Join(dir, dependencyManifestName)
+		if fileExists(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// loadDependencySpecs returns the dependencies declared in a package.json,
+// sorted by name. A dependency is pinned when its range names one version.
+func loadDependencySpecs(path string) ([]PluginSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest dependencyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(manifest.Dependencies))
+	for name := range manifest.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	specs := make([]PluginSpec, 0, len(names))
+	for _, name := range names {
+		version := manifest.Dependencies[name]
+		spec := PluginSpec{
+			Name:         name,
+			DeclaredSpec: name + "@" + version,
+			Source:       SourceDependency,
+			ConfigPath:   path,
+		}
+		if npm.IsExactVersion(version) {
+			spec.Pinned = version
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// DependencyDir returns the directory a dependency is installed to, next to
+// the package.json that declares it.
+func DependencyDir(spec PluginSpec) string {
+	return filepath.Join(filepath.Dir(spec.ConfigPath), "node_modules", filepath.FromSlash(spec.Name))
+}
+
+// InstalledDependency returns the installed version of a dependency. It
+// reports false when the dependency is not installed; the version is empty
+// when its package.json cannot be read.
+func InstalledDependency(spec PluginSpec) (string, bool) {
+	dir := DependencyDir(spec)
+	if !dirExists(dir) {
+		return "", false
+	}
+	var manifest localManifest
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil || json.Unmarshal(data, &manifest) != nil {
+		return "", true
+	}
+	return manifest.Version, true
+}
+
+// updateDependency sets the version of a dependency in a package.json. Only
+// the version string is replaced so the rest of the file keeps its layout.
+func updateDependency(path string, name string, newSpec string) error {
+	specName, version := parseSpec(newSpec)
+	if specName != name || version == "" {
+		return fmt.Errorf("invalid dependency spec %q for %s", newSpec, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	out, err := setDependencyVersion(data, name, version)
+	if err != nil {
+		return fmt.Errorf("update %s: %w", path, err)
+	}
+	if err := atomicfile.Write(path, out, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+func setDependencyVersion(data []byte, name string, version string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key != "dependencies" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			dependency, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if dependency != name {
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			value, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := value.(string); !ok {
+				return nil, fmt.Errorf("dependency %s is not a version string", name)
+			}
+			end := int(dec.InputOffset())
+			start := bytes.LastIndexByte(data[:end-1], '"')
+			out := append([]byte{}, data[:start]...)
+			out = append(out, strconv.Quote(version)...)
+			return append(out, data[end:]...), nil
+		}
+		break
+	}
+	return nil, ErrPluginNotFound
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %q, found %v", delim, token)
+	}
+	return nil
+}
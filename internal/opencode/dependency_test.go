@@ -0,0 +1,77 @@
+package opencode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverReadsConfigDirDependencies(t *testing.T) {
+	root := t.TempDir()
+	xdg := filepath.Join(root, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	t.Setenv("OPENCODE_CONFIG_DIR", "")
+	writeLocalFile(t, filepath.Join(xdg, "opencode", "package.json"), `{"dependencies": {"@opencode-ai/plugin": "0.5.1"}}`)
+
+	project := filepath.Join(root, "project")
+	projectManifest := filepath.Join(project, ".opencode", "package.json")
+	writeLocalFile(t, projectManifest, `{"dependencies": {"zod": "^3.22.0", "@opencode-ai/plugin": "0.6.0"}}`)
+	writeLocalFile(t, filepath.Join(project, ".opencode", "node_modules", "@opencode-ai", "plugin", "package.json"), `{"name": "@opencode-ai/plugin", "version": "0.5.9"}`)
+
+	result, err := Discover(project, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(result.Plugins) != 3 {
+		t.Fatalf("expected 3 dependencies, got %+v", result.Plugins)
+	}
+	for _, spec := range result.Plugins {
+		if spec.Source != SourceDependency {
+			t.Fatalf("expected dependency source, got %+v", spec)
+		}
+	}
+
+	sdk := result.Plugins[1]
+	if sdk.Name != "@opencode-ai/plugin" || sdk.Pinned != "0.6.0" || sdk.ConfigPath != projectManifest {
+		t.Fatalf("unexpected project sdk spec: %+v", sdk)
+	}
+	if version, ok := InstalledDependency(sdk); !ok || version != "0.5.9" {
+		t.Fatalf("expected installed 0.5.9, got %q %v", version, ok)
+	}
+
+	zod := result.Plugins[2]
+	if zod.DeclaredSpec != "zod@^3.22.0" || zod.Pinned != "" {
+		t.Fatalf("expected unpinned range, got %+v", zod)
+	}
+	if _, ok := InstalledDependency(zod); ok {
+		t.Fatalf("expected zod to be missing")
+	}
+}
+
+func TestUpdatePluginSpecKeepsPackageJSONLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.json")
+	content := "{\n  \"name\": \"plugins\",\n  \"dependencies\": {\n    \"zod\": \"^3.22.0\",\n    \"@opencode-ai/plugin\": \"0.5.1\"\n  },\n  \"private\": true\n}\n"
+	writeLocalFile(t, path, content)
+
+	if err := UpdatePluginSpec(path, "@opencode-ai/plugin", "@opencode-ai/plugin@0.6.0"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := "{\n  \"name\": \"plugins\",\n  \"dependencies\": {\n    \"zod\": \"^3.22.0\",\n    \"@opencode-ai/plugin\": \"0.6.0\"\n  },\n  \"private\": true\n}\n"
+	if string(data) != want {
+		t.Fatalf("unexpected content:\n%s", data)
+	}
+
+	spec, err := FindPluginSpec(path, "@opencode-ai/plugin")
+	if err != nil || spec.Pinned != "0.6.0" {
+		t.Fatalf("expected pinned 0.6.0, got %+v %v", spec, err)
+	}
+
+	if err := UpdatePluginSpec(path, "missing", "missing@1.0.0"); !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
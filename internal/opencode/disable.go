@@ -0,0 +1,291 @@
+package opencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+)
+
+// DisabledFileName is the sidecar next to a config file that keeps the
+// plugin entries patchline disabled in it.
+const DisabledFileName = "patchline.disabled.json"
+
+// DisabledPlugin is a plugin entry removed from a config by DisablePlugin.
+// Index is the entry's position in the list with every disabled entry of the
+// same config and key put back, so entries can be enabled in any order.
+type DisabledPlugin struct {
+	Config string `json:"config"`
+	Key    string `json:"key"`
+	Index  int    `json:"index"`
+	Name   string `json:"name"`
+	Entry  string `json:"entry"`
+}
+
+type disabledFile struct {
+	Plugins []DisabledPlugin `json:"plugins"`
+}
+
+// DisabledPath returns the sidecar that records disabled plugins for a config
+// file, or "" for OPENCODE_CONFIG_CONTENT.
+func DisabledPath(configPath string) string {
+	if configPath == "" || IsInlineConfig(configPath) {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), DisabledFileName)
+}
+
+// LoadDisabled returns the plugin entries disabled in a config file.
+func LoadDisabled(configPath string) ([]DisabledPlugin, error) {
+	all, err := readDisabled(DisabledPath(configPath))
+	if err != nil {
+		return nil, err
+	}
+	config := filepath.Base(configPath)
+	out := []DisabledPlugin{}
+	for _, plugin := range all {
+		if plugin.Config == config {
+			out = append(out, plugin)
+		}
+	}
+	return out, nil
+}
+
+// DisablePlugin removes every entry for a plugin from a config file and
+// records them in the sidecar so EnablePlugin can put them back. The sidecar
+// is written first, so the entries are never lost: if the config cannot be
+// written, the sidecar is put back.
+func DisablePlugin(configPath string, pluginName string) ([]DisabledPlugin, error) {
+	if IsInlineConfig(configPath) {
+		return nil, fmt.Errorf("%w: %s cannot be written", ErrInlineConfig, inlineConfigEnv)
+	}
+	raw, err := readRawConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	sidecar := DisabledPath(configPath)
+	all, err := readDisabled(sidecar)
+	if err != nil {
+		return nil, err
+	}
+
+	config := filepath.Base(configPath)
+	disabled := []DisabledPlugin{}
+	for _, key := range []string{"plugin", "plugins"} {
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		list, err := coerceStringSlice(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s list: %w", key, err)
+		}
+		positions := fullPositions(len(list), disabledIndexes(all, config, key))
+		kept := []string{}
+		for i, entry := range list {
+			name := entryName(entry, configPath)
+			if name != pluginName {
+				kept = append(kept, entry)
+				continue
+			}
+			disabled = append(disabled, DisabledPlugin{Config: config, Key: key, Index: positions[i], Name: name, Entry: entry})
+		}
+		raw[key] = kept
+	}
+	if len(disabled) == 0 {
+		return nil, ErrPluginNotFound
+	}
+
+	if err := writeDisabled(sidecar, append(all, disabled...)); err != nil {
+		return nil, err
+	}
+	if err := writeRawConfig(configPath, raw); err != nil {
+		_ = writeDisabled(sidecar, all)
+		return nil, err
+	}
+	return disabled, nil
+}
+
+// EnablePlugin puts the disabled entries of a plugin back into a config file
+// at their original positions and drops them from the sidecar. The config is
+// written first and put back if the sidecar cannot be written. Entries still
+// in the list, left by a disable that did not finish, are not added twice.
+func EnablePlugin(configPath string, pluginName string) ([]DisabledPlugin, error) {
+	sidecar := DisabledPath(configPath)
+	all, err := readDisabled(sidecar)
+	if err != nil {
+		return nil, err
+	}
+	config := filepath.Base(configPath)
+	enabled := []DisabledPlugin{}
+	remaining := []DisabledPlugin{}
+	for _, plugin := range all {
+		if plugin.Config == config && plugin.Name == pluginName {
+			enabled = append(enabled, plugin)
+			continue
+		}
+		remaining = append(remaining, plugin)
+	}
+	if len(enabled) == 0 {
+		return nil, ErrPluginNotFound
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].Index < enabled[j].Index
+	})
+
+	raw := map[string]any{}
+	original, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		raw, err = parseRawConfig(configPath, original)
+		if err != nil {
+			return nil, err
+		}
+	case os.IsNotExist(err):
+		original = nil
+	default:
+		return nil, fmt.Errorf("read %s: %w", configPath, err)
+	}
+	for i, plugin := range enabled {
+		list := []string{}
+		if value, ok := raw[plugin.Key]; ok {
+			list, err = coerceStringSlice(value)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s list: %w", plugin.Key, err)
+			}
+		}
+		if slices.Contains(list, plugin.Entry) {
+			continue
+		}
+		// Entries disabled before this one and still disabled are missing
+		// from the list, so the position shifts left by their count.
+		pending := append(append([]DisabledPlugin{}, remaining...), enabled[i+1:]...)
+		position := plugin.Index
+		for _, index := range disabledIndexes(pending, config, plugin.Key) {
+			if index < plugin.Index {
+				position--
+			}
+		}
+		if position > len(list) {
+			position = len(list)
+		}
+		if position < 0 {
+			position = 0
+		}
+		list = append(list[:position], append([]string{plugin.Entry}, list[position:]...)...)
+		raw[plugin.Key] = list
+	}
+
+	if err := writeRawConfig(configPath, raw); err != nil {
+		return nil, err
+	}
+	if err := writeDisabled(sidecar, remaining); err != nil {
+		if original != nil {
+			_ = atomicfile.Write(configPath, original, 0o600)
+		} else {
+			_ = os.Remove(configPath)
+		}
+		return nil, err
+	}
+	return enabled, nil
+}
+
+// loadDisabledSpecs returns the disabled plugins of a config file as specs.
+func loadDisabledSpecs(configPath string, source Source) ([]PluginSpec, error) {
+	disabled, err := LoadDisabled(configPath)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]PluginSpec, 0, len(disabled))
+	for _, plugin := range disabled {
+		spec := plugin.Entry
+		if expanded, err := expandSpec(plugin.Entry, configPath); err == nil {
+			spec = expanded
+		}
+		_, pinned := parseSpec(spec)
+		item := PluginSpec{
+			Name:         plugin.Name,
+			DeclaredSpec: spec,
+			Pinned:       pinned,
+			Source:       source,
+			ConfigPath:   configPath,
+		}
+		if isTemplated(plugin.Entry) {
+			item.Template = plugin.Entry
+		}
+		specs = append(specs, item)
+	}
+	return specs, nil
+}
+
+// disabledIndexes returns the sorted positions of the entries disabled in one
+// config list.
+func disabledIndexes(plugins []DisabledPlugin, config string, key string) []int {
+	indexes := []int{}
+	for _, plugin := range plugins {
+		if plugin.Config == config && plugin.Key == key {
+			indexes = append(indexes, plugin.Index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// fullPositions maps each active entry of a list to its position once the
+// disabled entries at the given positions are put back.
+func fullPositions(active int, disabled []int) []int {
+	positions := make([]int, active)
+	position := 0
+	next := 0
+	for i := 0; i < active; i++ {
+		for next < len(disabled) && disabled[next] == position {
+			position++
+			next++
+		}
+		positions[i] = position
+		position++
+	}
+	return positions
+}
+
+func readDisabled(path string) ([]DisabledPlugin, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var file disabledFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return file.Plugins, nil
+}
+
+// writeDisabled saves the sidecar through a temporary file, so it is never
+// left half written, and removes it once nothing is disabled.
+func writeDisabled(path string, plugins []DisabledPlugin) error {
+	if len(plugins) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		return nil
+	}
+	out, err := json.MarshalIndent(disabledFile{Plugins: plugins}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	out = append(out, '\n')
+	if err := atomicfile.Write(path, out, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package opencode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readPluginList(t *testing.T, path string) []string {
+	t.Helper()
+	raw, err := readRawConfig(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	list, err := coerceStringSlice(raw["plugin"])
+	if err != nil {
+		t.Fatalf("plugin list: %v", err)
+	}
+	return list
+}
+
+func TestDisableAndEnableRestoreOriginalPositions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "opencode.json")
+	writeLocalFile(t, path, `{"plugin": ["alpha@1.0.0", "beta@{env:BETA_VERSION}", "gamma@3.0.0", "delta"]}`)
+	t.Setenv("BETA_VERSION", "2.0.0")
+
+	for _, name := range []string{"beta", "alpha", "gamma"} {
+		if _, err := DisablePlugin(path, name); err != nil {
+			t.Fatalf("disable %s: %v", name, err)
+		}
+	}
+	if got := readPluginList(t, path); !reflect.DeepEqual(got, []string{"delta"}) {
+		t.Fatalf("expected only delta active, got %v", got)
+	}
+
+	specs, err := loadDisabledSpecs(path, SourceProject)
+	if err != nil {
+		t.Fatalf("load disabled: %v", err)
+	}
+	if len(specs) != 3 || specs[0].Name != "beta" || specs[0].DeclaredSpec != "beta@2.0.0" || specs[0].Template != "beta@{env:BETA_VERSION}" {
+		t.Fatalf("unexpected disabled specs: %+v", specs)
+	}
+
+	for _, name := range []string{"gamma", "beta"} {
+		if _, err := EnablePlugin(path, name); err != nil {
+			t.Fatalf("enable %s: %v", name, err)
+		}
+	}
+	if got := readPluginList(t, path); !reflect.DeepEqual(got, []string{"beta@{env:BETA_VERSION}", "gamma@3.0.0", "delta"}) {
+		t.Fatalf("unexpected list after partial enable: %v", got)
+	}
+
+	if _, err := EnablePlugin(path, "alpha"); err != nil {
+		t.Fatalf("enable alpha: %v", err)
+	}
+	want := []string{"alpha@1.0.0", "beta@{env:BETA_VERSION}", "gamma@3.0.0", "delta"}
+	if got := readPluginList(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected original order %v, got %v", want, got)
+	}
+	if _, err := os.Stat(DisabledPath(path)); !os.IsNotExist(err) {
+		t.Fatalf("expected sidecar to be removed, got %v", err)
+	}
+
+	if _, err := EnablePlugin(path, "alpha"); !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+func TestDisabledSidecarIsPerConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "opencode.json")
+	second := filepath.Join(dir, "opencode.jsonc")
+	writeLocalFile(t, first, `{"plugin": ["alpha@1.0.0"]}`)
+	writeLocalFile(t, second, `{"plugin": ["alpha@2.0.0"]}`)
+
+	if _, err := DisablePlugin(first, "alpha"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	disabled, err := LoadDisabled(second)
+	if err != nil || len(disabled) != 0 {
+		t.Fatalf("expected nothing disabled in second config, got %v %v", disabled, err)
+	}
+	if _, err := EnablePlugin(second, "alpha"); !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+func TestDisableKeepsSidecarUnchangedWhenConfigWriteFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "opencode.jsonc")
+	config := "{\"plugin\": [\n  // pinned\n  \"alpha@1.0.0\",\n  \"beta@2.0.0\"\n]}"
+	writeLocalFile(t, path, config)
+
+	if _, err := DisablePlugin(path, "alpha"); !errors.Is(err, ErrCommentedList) {
+		t.Fatalf("expected ErrCommentedList, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != config {
+		t.Fatalf("expected config untouched, got %s", data)
+	}
+	if _, err := os.Stat(DisabledPath(path)); !os.IsNotExist(err) {
+		t.Fatalf("expected no sidecar after a failed disable, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files, got %v", entries)
+	}
+}
+
+func TestEnableDoesNotDuplicateEntriesStillInTheList(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "opencode.json")
+	writeLocalFile(t, path, `{"plugin": ["alpha@1.0.0", "beta@2.0.0"]}`)
+	// A disable interrupted after writing the sidecar leaves alpha in both.
+	writeLocalFile(t, DisabledPath(path), `{"plugins": [{"config": "opencode.json", "key": "plugin", "index": 0, "name": "alpha", "entry": "alpha@1.0.0"}]}`)
+
+	if _, err := EnablePlugin(path, "alpha"); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if got := readPluginList(t, path); !reflect.DeepEqual(got, []string{"alpha@1.0.0", "beta@2.0.0"}) {
+		t.Fatalf("expected alpha once, got %v", got)
+	}
+	if _, err := os.Stat(DisabledPath(path)); !os.IsNotExist(err) {
+		t.Fatalf("expected sidecar to be removed, got %v", err)
+	}
+}
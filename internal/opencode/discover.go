@@ -9,40 +9,77 @@ import (
 	"strings"
 )
 
-var localPluginExtensions = map[string]bool{
-	".js":  true,
-	".cjs": true,
-	".mjs": true,
-	".ts":  true,
-	".cts": true,
-	".mts": true,
+// configFileNames are the config file names OpenCode reads from a config
+// directory, highest precedence first.
+var configFileNames = []string{"opencode.jsonc", "opencode.json"}
+
+// projectConfigNames are the project config locations OpenCode reads from a
+// directory, highest precedence first. The first one found is used.
+var projectConfigNames = []string{
+	"opencode.jsonc",
+	"opencode.json",
+	".opencode.json",
+	filepath.Join(".opencode", "opencode.jsonc"),
+	filepath.Join(".opencode", "opencode.json"),
+}
+
+// ProjectConfigCandidates returns every project config path OpenCode may read
+// in dir, highest precedence first.
+func ProjectConfigCandidates(dir string) []string {
+	paths := make([]string, 0, len(projectConfigNames))
+	for _, name := range projectConfigNames {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths
+}
+
+// InlineConfigPath stands in for the config path of plugins declared in
+// OPENCODE_CONFIG_CONTENT.
+const InlineConfigPath = "$OPENCODE_CONFIG_CONTENT"
+
+const inlineConfigEnv = "OPENCODE_CONFIG_CONTENT"
+
+// IsInlineConfig reports whether path refers to OPENCODE_CONFIG_CONTENT
+// rather than a file.
+func IsInlineConfig(path string) bool {
+	return path == InlineConfigPath
 }
 
 func Discover(projectRoot string, globalConfigPath string, localDirs []string) (DiscoveryResult, error) {
 	result := DiscoveryResult{}
 
-	globalPath, err := resolveGlobalConfig(globalConfigPath)
+	globalPath, ignored, err := resolveGlobalConfig(globalConfigPath)
 	if err != nil && !errors.Is(err, ErrConfigNotFound) {
 		return result, err
 	}
+	result.addVariants(globalPath, ignored)
 	if globalPath != "" {
+		result.GlobalConfig = globalPath
 		plugins, err := loadPluginSpecs(globalPath, SourceGlobal)
 		if err != nil {
 			return result, err
 		}
-		result.Plugins = append(result.Plugins, plugins...)
+		result.addPlugins(plugins)
+		if err := result.addDisabled(globalPath, SourceGlobal); err != nil {
+			return result, err
+		}
 	}
 
-	projectPath, err := findProjectConfig(projectRoot)
+	projectPath, ignored, err := findProjectConfig(projectRoot)
 	if err != nil && !errors.Is(err, ErrConfigNotFound) {
 		return result, err
 	}
+	result.addVariants(projectPath, ignored)
 	if projectPath != "" {
+		result.ProjectConfig = projectPath
 		plugins, err := loadPluginSpecs(projectPath, SourceProject)
 		if err != nil {
 			return result, err
 		}
-		result.Plugins = append(result.Plugins, plugins...)
+		result.addPlugins(plugins)
+		if err := result.addDisabled(projectPath, SourceProject); err != nil {
+			return result, err
+		}
 	}
 
 	customConfigDir, err := resolveCustomConfigDir()
@@ -50,13 +87,17 @@ func Discover(projectRoot string, globalConfigPath string, localDirs []string) (
 		return result, err
 	}
 	if customConfigDir != "" {
-		customConfigPath := filepath.Join(customConfigDir, "opencode.json")
-		if fileExists(customConfigPath) {
+		customConfigPath, ignored := findVariant(customConfigDir, configFileNames)
+		result.addVariants(customConfigPath, ignored)
+		if customConfigPath != "" {
 			plugins, err := loadPluginSpecs(customConfigPath, SourceCustomDir)
 			if err != nil {
 				return result, err
 			}
-			result.Plugins = append(result.Plugins, plugins...)
+			result.addPlugins(plugins)
+			if err := result.addDisabled(customConfigPath, SourceCustomDir); err != nil {
+				return result, err
+			}
 		}
 	}
 
@@ -69,22 +110,62 @@ func Discover(projectRoot string, globalConfigPath string, localDirs []string) (
 		if err != nil {
 			return result, err
 		}
-		result.Plugins = append(result.Plugins, plugins...)
+		result.addPlugins(plugins)
+		if err := result.addDisabled(customConfigPath, SourceCustom); err != nil {
+			return result, err
+		}
+	}
+
+	if strings.TrimSpace(os.Getenv(inlineConfigEnv)) != "" {
+		plugins, err := loadPluginSpecs(InlineConfigPath, SourceInline)
+		if err != nil {
+			return result, err
+		}
+		result.addPlugins(plugins)
 	}
 
 	localCandidates := append([]string{}, localDirs...)
 	localCandidates = append(localCandidates, defaultLocalPluginDirs(projectRoot, customConfigDir)...)
 	result.Plugins = append(result.Plugins, discoverLocalPlugins(localCandidates)...)
 
+	for _, path := range dependencyManifestPaths(projectRoot, globalPath, customConfigDir) {
+		plugins, err := loadDependencySpecs(path)
+		if err != nil {
+			return result, err
+		}
+		result.Plugins = append(result.Plugins, plugins...)
+	}
+
 	return result, nil
 }
 
 func loadPluginSpecs(path string, source Source) ([]PluginSpec, error) {
-	data, err := os.ReadFile(path)
+	if isDependencyManifest(path) {
+		return loadDependencySpecs(path)
+	}
+	data, err := readConfig(path)
 	if err != nil {
 		return nil, err
 	}
+	return parsePluginSpecs(path, data, source)
+}
+
+// readConfig returns the content of a config file, or of
+// OPENCODE_CONFIG_CONTENT for InlineConfigPath.
+func readConfig(path string) ([]byte, error) {
+	if IsInlineConfig(path) {
+		return []byte(os.Getenv(inlineConfigEnv)), nil
+	}
+	return os.ReadFile(path)
+}
 
+// ParsePluginSpecs returns the plugin specs declared in config content,
+// including entries whose substitution fails.
+func ParsePluginSpecs(path string, data []byte) ([]PluginSpec, error) {
+	return parsePluginSpecs(path, data, "")
+}
+
+func parsePluginSpecs(path string, data []byte, source Source) ([]PluginSpec, error) {
 	var cfg Config
 	if err := json.Unmarshal(sanitizeJSONC(data), &cfg); err != nil {
 		return nil, fmt.Errorf("parse %s: %w", path, err)
@@ -94,8 +175,21 @@ func loadPluginSpecs(path string, source Source) ([]PluginSpec, error) {
 	declared = append(declared, cfg.PluginsAlt...)
 
 	plugins := make([]PluginSpec, 0, len(declared))
-	for _, spec := range declared {
-		spec = strings.TrimSpace(spec)
+	for _, entry := range declared {
+		spec, err := expandSpec(entry, path)
+		if err != nil {
+			// One bad substitution must not hide the rest of the config.
+			template := strings.TrimSpace(entry)
+			plugins = append(plugins, PluginSpec{
+				Name:         template,
+				DeclaredSpec: template,
+				Source:       source,
+				ConfigPath:   path,
+				Template:     template,
+				TemplateErr:  err,
+			})
+			continue
+		}
 		if spec == "" {
 			continue
 		}
@@ -103,19 +197,66 @@ func loadPluginSpecs(path string, source Source) ([]PluginSpec, error) {
 		if name == "" {
 			continue
 		}
-		plugins = append(plugins, PluginSpec{
+		plugin := PluginSpec{
 			Name:         name,
 			DeclaredSpec: spec,
 			Pinned:       pinned,
 			Source:       source,
 			ConfigPath:   path,
-		})
+		}
+		if path, ok := linkPath(spec); ok {
+			plugin.LinkPath = path
+			if linked, version, err := LinkedPackage(path); err == nil {
+				if linked != "" {
+					plugin.Name = linked
+				}
+				plugin.Version = version
+			}
+		}
+		if isTemplated(entry) {
+			plugin.Template = strings.TrimSpace(entry)
+		}
+		plugins = append(plugins, plugin)
 	}
 
 	return plugins, nil
 }
 
+// FindPluginSpec returns the declared spec for a plugin in one config file.
+func FindPluginSpec(path string, pluginName string) (PluginSpec, error) {
+	specs, err := loadPluginSpecs(path, "")
+	if err != nil {
+		return PluginSpec{}, err
+	}
+	for _, spec := range specs {
+		if spec.Name == pluginName {
+			return spec, nil
+		}
+	}
+	return PluginSpec{}, ErrPluginNotFound
+}
+
+// expandSpec returns a plugin entry with substitutions expanded relative to
+// the config file at path.
+func expandSpec(entry string, path string) (string, error) {
+	entry = strings.TrimSpace(entry)
+	if !isTemplated(entry) {
+		return entry, nil
+	}
+	spec, err := expandSubstitutions(entry, filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(spec), nil
+}
+
+// parseSpec splits a plugin entry into name and version. It does not read
+// the checkout behind a file:// entry; see specName.
 func parseSpec(spec string) (string, string) {
+	if _, ok := linkPath(spec); ok {
+		return spec, ""
+	}
+
 	at := strings.LastIndex(spec, "@")
 	if at <= 0 {
 		return spec, ""
@@ -129,72 +270,106 @@ func parseSpec(spec string) (string, string) {
 	return name, version
 }
 
-func findProjectConfig(projectRoot string) (string, error) {
+func findProjectConfig(projectRoot string) (string, []string, error) {
 	root := projectRoot
 	if root == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		root = cwd
 	}
 
 	info, err := os.Stat(root)
 	if err == nil && !info.IsDir() {
-		if filepath.Base(root) == "opencode.json" {
-			return root, nil
+		if isConfigFileName(filepath.Base(root)) {
+			return root, nil, nil
 		}
-		return "", fmt.Errorf("project path is not a directory: %s", root)
+		return "", nil, fmt.Errorf("project path is not a directory: %s", root)
 	}
 
 	root = filepath.Clean(root)
 	for {
-		candidate := filepath.Join(root, "opencode.json")
-		if fileExists(candidate) {
-			return candidate, nil
+		if path, ignored := findVariant(root, projectConfigNames); path != "" {
+			return path, ignored, nil
 		}
 		parent := filepath.Dir(root)
 		if parent == root {
-			return "", ErrConfigNotFound
+			return "", nil, ErrConfigNotFound
 		}
 		root = parent
 	}
 }
 
-func resolveGlobalConfig(override string) (string, error) {
+// findVariant returns the highest precedence config in dir and any other
+// variants that exist beside it.
+func findVariant(dir string, names []string) (string, []string) {
+	found := []string{}
+	for _, name := range names {
+		candidate := filepath.Join(dir, name)
+		if fileExists(candidate) {
+			found = append(found, candidate)
+		}
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], found[1:]
+}
+
+func isConfigFileName(name string) bool {
+	for _, candidate := range projectConfigNames {
+		if name == filepath.Base(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveGlobalConfig(override string) (string, []string, error) {
 	if override != "" {
 		if fileExists(override) {
-			return override, nil
+			return override, nil, nil
 		}
-		return "", fmt.Errorf("%w: %s", ErrConfigNotFound, override)
+		return "", nil, fmt.Errorf("%w: %s", ErrConfigNotFound, override)
 	}
 
-	for _, candidate := range globalConfigCandidates() {
-		if fileExists(candidate) {
-			return candidate, nil
+	for _, dir := range globalConfigDirs() {
+		if path, ignored := findVariant(dir, configFileNames); path != "" {
+			return path, ignored, nil
 		}
 	}
-	return "", ErrConfigNotFound
+	return "", nil, ErrConfigNotFound
+}
+
+// DefaultGlobalConfigPath returns where a new global config should be
+// created when none exists yet.
+func DefaultGlobalConfigPath() string {
+	dirs := globalConfigDirs()
+	if len(dirs) == 0 {
+		return ""
+	}
+	return filepath.Join(dirs[0], "opencode.json")
 }
 
-func globalConfigCandidates() []string {
+func globalConfigDirs() []string {
 	paths := []string{}
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome != "" {
-		paths = append(paths, filepath.Join(configHome, "opencode", "opencode.json"))
+		paths = append(paths, filepath.Join(configHome, "opencode"))
 	} else {
 		if appData := os.Getenv("APPDATA"); appData != "" {
-			paths = append(paths, filepath.Join(appData, "opencode", "opencode.json"))
+			paths = append(paths, filepath.Join(appData, "opencode"))
 		}
 		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
-			paths = append(paths, filepath.Join(localAppData, "opencode", "opencode.json"))
+			paths = append(paths, filepath.Join(localAppData, "opencode"))
 		}
 
 		home, err := os.UserHomeDir()
 		if err == nil && home != "" {
-			paths = append(paths, filepath.Join(home, ".config", "opencode", "opencode.json"))
-			paths = append(paths, filepath.Join(home, "AppData", "Roaming", "opencode", "opencode.json"))
-			paths = append(paths, filepath.Join(home, "AppData", "Local", "opencode", "opencode.json"))
+			paths = append(paths, filepath.Join(home, ".config", "opencode"))
+			paths = append(paths, filepath.Join(home, "AppData", "Roaming", "opencode"))
+			paths = append(paths, filepath.Join(home, "AppData", "Local", "opencode"))
 		}
 	}
 
@@ -202,68 +377,41 @@ func globalConfigCandidates() []string {
 }
 
 func defaultLocalPluginDirs(projectRoot string, customConfigDir string) []string {
-	paths := []string{}
+	bases := []string{}
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome != "" {
-		paths = append(paths, filepath.Join(configHome, "opencode", "plugin"))
+		bases = append(bases, filepath.Join(configHome, "opencode"))
 	} else {
 		if appData := os.Getenv("APPDATA"); appData != "" {
-			paths = append(paths, filepath.Join(appData, "opencode", "plugin"))
+			bases = append(bases, filepath.Join(appData, "opencode"))
 		}
 		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
-			paths = append(paths, filepath.Join(localAppData, "opencode", "plugin"))
+			bases = append(bases, filepath.Join(localAppData, "opencode"))
 		}
 
 		home, err := os.UserHomeDir()
 		if err == nil && home != "" {
-			paths = append(paths, filepath.Join(home, ".config", "opencode", "plugin"))
-			paths = append(paths, filepath.Join(home, "AppData", "Roaming", "opencode", "plugin"))
-			paths = append(paths, filepath.Join(home, "AppData", "Local", "opencode", "plugin"))
+			bases = append(bases, filepath.Join(home, ".config", "opencode"))
+			bases = append(bases, filepath.Join(home, "AppData", "Roaming", "opencode"))
+			bases = append(bases, filepath.Join(home, "AppData", "Local", "opencode"))
 		}
 	}
 
 	if projectRoot != "" {
-		paths = append(paths, filepath.Join(projectRoot, ".opencode", "plugin"))
+		bases = append(bases, filepath.Join(projectRoot, ".opencode"))
 	}
 
 	if customConfigDir != "" {
-		paths = append(paths, filepath.Join(customConfigDir, "plugin"))
+		bases = append(bases, customConfigDir)
 	}
 
-	return uniqueStrings(paths)
-}
-
-func discoverLocalPlugins(dirs []string) []PluginSpec {
-	plugins := []PluginSpec{}
-	for _, dir := range uniqueStrings(dirs) {
-		if !dirExists(dir) {
-			continue
-		}
-		entries, err := os.ReadDir(dir)
-		if err != nil {
-			continue
-		}
-
-		for _, entry := range entries {
-			if entry.IsDir() {
-				continue
-			}
-			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			if !localPluginExtensions[ext] {
-				continue
-			}
-			name := strings.TrimSuffix(entry.Name(), ext)
-			path := filepath.Join(dir, entry.Name())
-			plugins = append(plugins, PluginSpec{
-				Name:         name,
-				DeclaredSpec: path,
-				Source:       SourceLocal,
-				LocalPath:    path,
-			})
+	paths := []string{}
+	for _, base := range bases {
+		for _, name := range localPluginDirNames {
+			paths = append(paths, filepath.Join(base, name))
 		}
 	}
-
-	return plugins
+	return uniqueStrings(paths)
 }
 
 func resolveCustomConfigFile() (string, error) {
@@ -19,7 +19,7 @@ func TestFindProjectConfigWalksParents(t *testing.T) {
 		t.Fatalf("write config: %v", err)
 	}
 
-	found, err := findProjectConfig(child)
+	found, _, err := findProjectConfig(child)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -35,7 +35,7 @@ func TestFindProjectConfigExplicitFile(t *testing.T) {
 		t.Fatalf("write config: %v", err)
 	}
 
-	found, err := findProjectConfig(configPath)
+	found, _, err := findProjectConfig(configPath)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -131,12 +131,39 @@ func TestParseSpecScopedPackage(t *testing.T) {
 	}
 }
 
-func TestGlobalConfigCandidatesIncludeXDG(t *testing.T) {
+func TestLinkedEntriesAreNamedAfterTheirCheckout(t *testing.T) {
+	root := t.TempDir()
+	checkout := filepath.Join(root, "checkout")
+	if err := os.MkdirAll(checkout, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(checkout, "package.json"), []byte(`{"name":"alpha","version":"1.3.0-dev"}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	spec := LinkSpec(checkout)
+	if name, pinned := parseSpec(spec); name != spec || pinned != "" {
+		t.Fatalf("expected parseSpec to leave link entries alone, got %q@%q", name, pinned)
+	}
+
+	configPath := filepath.Join(root, "opencode.json")
+	specs, err := ParsePluginSpecs(configPath, []byte(`{"plugin": ["`+spec+`"]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Name != "alpha" || specs[0].Version != "1.3.0-dev" || specs[0].LinkPath != checkout {
+		t.Fatalf("unexpected specs: %#v", specs)
+	}
+	if specName(spec) != "alpha" {
+		t.Fatalf("expected specName to read the checkout, got %q", specName(spec))
+	}
+}
+
+func TestGlobalConfigDirsIncludeXDG(t *testing.T) {
 	root := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", root)
 
-	candidates := globalConfigCandidates()
-	want := filepath.Join(root, "opencode", "opencode.json")
+	candidates := globalConfigDirs()
+	want := filepath.Join(root, "opencode")
 	found := false
 	for _, candidate := range candidates {
 		if candidate == want {
@@ -244,7 +271,7 @@ func TestDiscoverUsesCustomConfigDir(t *testing.T) {
 	}
 }
 
-func TestGlobalConfigCandidatesIncludeWindowsEnv(t *testing.T) {
+func TestGlobalConfigDirsIncludeWindowsEnv(t *testing.T) {
 	if runtime.GOOS != "windows" {
 		t.Skip("windows only")
 	}
@@ -256,9 +283,9 @@ func TestGlobalConfigCandidatesIncludeWindowsEnv(t *testing.T) {
 	t.Setenv("LOCALAPPDATA", localAppData)
 	t.Setenv("XDG_CONFIG_HOME", "")
 
-	candidates := globalConfigCandidates()
-	wantApp := filepath.Join(appData, "opencode", "opencode.json")
-	wantLocal := filepath.Join(localAppData, "opencode", "opencode.json")
+	candidates := globalConfigDirs()
+	wantApp := filepath.Join(appData, "opencode")
+	wantLocal := filepath.Join(localAppData, "opencode")
 
 	foundApp := false
 	foundLocal := false
@@ -311,3 +338,115 @@ func TestDefaultLocalPluginDirsIncludeWindowsEnv(t *testing.T) {
 		t.Fatalf("expected plugin dir %s, got %#v", wantLocal, dirs)
 	}
 }
+
+func TestFindProjectConfigVariantPrecedence(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"opencode.json", ".opencode.json", filepath.Join(".opencode", "opencode.json")} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(`{}`), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	found, ignored, err := findProjectConfig(root)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if found != filepath.Join(root, "opencode.json") {
+		t.Fatalf("expected opencode.json to win, got %s", found)
+	}
+	if len(ignored) != 2 {
+		t.Fatalf("expected two ignored variants, got %#v", ignored)
+	}
+
+	jsonc := filepath.Join(root, "opencode.jsonc")
+	if err := os.WriteFile(jsonc, []byte(`{}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	found, _, err = findProjectConfig(root)
+	if err != nil || found != jsonc {
+		t.Fatalf("expected opencode.jsonc to win, got %s %v", found, err)
+	}
+}
+
+func TestDiscoverReadsConfigDirectoryVariants(t *testing.T) {
+	root := t.TempDir()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("OPENCODE_CONFIG", "")
+	t.Setenv("OPENCODE_CONFIG_DIR", "")
+
+	projectConfig := filepath.Join(root, ".opencode", "opencode.jsonc")
+	if err := os.MkdirAll(filepath.Dir(projectConfig), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(projectConfig, []byte("{\n  // project\n  \"plugin\": [\"alpha@1.0.0\"],\n}\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	globalDir := filepath.Join(configHome, "opencode")
+	if err := os.MkdirAll(globalDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, content := range map[string]string{"opencode.jsonc": `{"plugin": ["beta@1.0.0"]}`, "opencode.json": `{"plugin": ["gamma@1.0.0"]}`} {
+		if err := os.WriteFile(filepath.Join(globalDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	result, err := Discover(root, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if result.ProjectConfig != projectConfig {
+		t.Fatalf("expected project config %s, got %s", projectConfig, result.ProjectConfig)
+	}
+	if result.GlobalConfig != filepath.Join(globalDir, "opencode.jsonc") {
+		t.Fatalf("expected global opencode.jsonc, got %s", result.GlobalConfig)
+	}
+	names := map[string]bool{}
+	for _, plugin := range result.Plugins {
+		names[plugin.Name] = true
+	}
+	if !names["alpha"] || !names["beta"] || names["gamma"] {
+		t.Fatalf("unexpected plugins %#v", result.Plugins)
+	}
+	if len(result.Variants) != 1 || result.Variants[0].Ignored[0] != filepath.Join(globalDir, "opencode.json") {
+		t.Fatalf("expected ignored global variant, got %#v", result.Variants)
+	}
+}
+
+func TestDiscoverUsesInlineConfigContent(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	t.Setenv("OPENCODE_CONFIG_CONTENT", `{"plugin": ["foo@3.0.0"]}`)
+
+	result, err := Discover(root, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(result.Plugins) != 1 {
+		t.Fatalf("expected one plugin, got %+v", result.Plugins)
+	}
+	plugin := result.Plugins[0]
+	if plugin.Source != SourceInline || plugin.Pinned != "3.0.0" || !IsInlineConfig(plugin.ConfigPath) {
+		t.Fatalf("unexpected inline plugin: %+v", plugin)
+	}
+}
+
+func TestPrecedenceFollowsConfigSources(t *testing.T) {
+	sources := ConfigSources()
+	if sources[0] != SourceInline || sources[len(sources)-1] != SourceGlobal {
+		t.Fatalf("unexpected precedence order: %v", sources)
+	}
+	for i, source := range sources {
+		if Precedence(source) != i {
+			t.Fatalf("expected %s at rank %d, got %d", source, i, Precedence(source))
+		}
+	}
+	if Precedence(SourceLocal) <= Precedence(SourceGlobal) || Precedence(SourceDependency) <= Precedence(SourceGlobal) {
+		t.Fatalf("expected local plugins and dependencies below every config source")
+	}
+}
@@ -9,4 +9,15 @@ var (
 	ErrConfigNotFound = errors.New("config not found")
 	// ErrPluginNotFound indicates the plugin entry was not found in config.
 	ErrPluginNotFound = errors.New("plugin not found")
+	// ErrBadFileReference indicates a {file:} substitution could not be read.
+	ErrBadFileReference = errors.New("bad file reference")
+	// ErrTemplatedSpec indicates the plugin entry uses {env:} or {file:}
+	// substitution and would be replaced by a literal spec.
+	ErrTemplatedSpec = errors.New("plugin entry is templated")
+	// ErrInlineConfig indicates a write to config content that came from
+	// OPENCODE_CONFIG_CONTENT rather than a file.
+	ErrInlineConfig = errors.New("config is inline")
+	// ErrCommentedList indicates a plugin list holds comments that rewriting
+	// it would drop.
+	ErrCommentedList = errors.New("plugin list contains comments")
 )
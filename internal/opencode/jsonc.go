@@ -133,3 +133,87 @@ func removeTrailingCommas(input []byte) []byte {
 
 	return out.Bytes()
 }
+
+// maskJSONC replaces comments and trailing commas with spaces, keeping line
+// breaks, so the result parses as JSON and its offsets match the input.
+func maskJSONC(input []byte) []byte {
+	return maskTrailingCommas(maskComments(input))
+}
+
+func maskComments(input []byte) []byte {
+	out := append([]byte{}, input...)
+	inString := false
+	escaped := false
+	for i := 0; i < len(out); i++ {
+		ch := out[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		if ch == '"' {
+			inString = true
+			continue
+		}
+		if ch != '/' || i+1 >= len(out) || (out[i+1] != '/' && out[i+1] != '*') {
+			continue
+		}
+		block := out[i+1] == '*'
+		out[i], out[i+1] = ' ', ' '
+		for i += 2; i < len(out); i++ {
+			if !block && out[i] == '\n' {
+				break
+			}
+			if block && out[i] == '*' && i+1 < len(out) && out[i+1] == '/' {
+				out[i], out[i+1] = ' ', ' '
+				i++
+				break
+			}
+			if out[i] != '\n' && out[i] != '\r' {
+				out[i] = ' '
+			}
+		}
+	}
+	return out
+}
+
+func maskTrailingCommas(input []byte) []byte {
+	out := append([]byte{}, input...)
+	inString := false
+	escaped := false
+	for i := 0; i < len(out); i++ {
+		ch := out[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		if ch == '"' {
+			inString = true
+			continue
+		}
+		if ch != ',' {
+			continue
+		}
+		j := i + 1
+		for j < len(out) && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+			j++
+		}
+		if j < len(out) && (out[j] == '}' || out[j] == ']') {
+			out[i] = ' '
+		}
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package opencode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// linkPrefix starts a plugin entry that loads a local checkout instead of an
+// npm package.
+const linkPrefix = "file://"
+
+// LinkSpec returns the plugin entry that loads the checkout in dir.
+func LinkSpec(dir string) string {
+	path := filepath.ToSlash(dir)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return linkPrefix + path
+}
+
+// linkPath returns the local path of a file:// plugin entry.
+func linkPath(spec string) (string, bool) {
+	if !strings.HasPrefix(spec, linkPrefix) {
+		return "", false
+	}
+	path := strings.TrimPrefix(spec, linkPrefix)
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		// file:///C:/dir on Windows.
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), true
+}
+
+// specName returns the plugin name of an expanded entry. A file:// entry is
+// named after the package.json of the checkout it links to.
+func specName(spec string) string {
+	if path, ok := linkPath(spec); ok {
+		if name, _, err := LinkedPackage(path); err == nil && name != "" {
+			return name
+		}
+	}
+	name, _ := parseSpec(spec)
+	return name
+}
+
+// LinkedPackage returns the package name and version declared by the
+// package.json in a linked checkout.
+func LinkedPackage(dir string) (string, string, error) {
+	path := filepath.Join(dir, "package.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	var manifest localManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", "", fmt.Errorf("parse %s: %w", path, err)
+	}
+	return manifest.Name, manifest.Version, nil
+}
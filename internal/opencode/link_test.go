@@ -0,0 +1,27 @@
+package opencode
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePluginSpecsNamesLinkedCheckouts(t *testing.T) {
+	checkout := filepath.Join(t.TempDir(), "alpha")
+	writeLocalFile(t, filepath.Join(checkout, "package.json"), `{"name": "@acme/alpha", "version": "2.0.0-dev"}`)
+	missing := filepath.Join(t.TempDir(), "gone")
+
+	data := []byte(`{"plugin": ["` + LinkSpec(checkout) + `", "` + LinkSpec(missing) + `"]}`)
+	specs, err := ParsePluginSpecs("opencode.json", data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %+v", specs)
+	}
+	if specs[0].Name != "@acme/alpha" || specs[0].LinkPath != checkout || specs[0].Version != "2.0.0-dev" || specs[0].Pinned != "" {
+		t.Fatalf("unexpected linked spec: %+v", specs[0])
+	}
+	if specs[1].Name != LinkSpec(missing) || specs[1].LinkPath != missing {
+		t.Fatalf("expected a missing checkout to keep its entry as name, got %+v", specs[1])
+	}
+}
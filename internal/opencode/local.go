@@ -0,0 +1,193 @@
+package opencode
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localPluginDirNames are the plugin folder spellings OpenCode accepts in a
+// config directory.
+var localPluginDirNames = []string{"plugin", "plugins"}
+
+// localPluginExtensions lists the loadable extensions, in the order index
+// files are tried for directory plugins.
+var localPluginExtensions = []string{".ts", ".js", ".mts", ".mjs", ".cts", ".cjs"}
+
+// exportConditions are the package.json export conditions tried for a
+// directory plugin, highest preference first.
+var exportConditions = []string{"bun", "import", "default", "node", "require"}
+
+type localManifest struct {
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Main    string          `json:"main"`
+	Exports json.RawMessage `json:"exports"`
+}
+
+func discoverLocalPlugins(dirs []string) []PluginSpec {
+	plugins := []PluginSpec{}
+	seen := map[string]struct{}{}
+	for _, dir := range uniqueStrings(dirs) {
+		if !dirExists(dir) {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules" {
+				continue
+			}
+			plugin, ok := localPlugin(filepath.Join(dir, entry.Name()))
+			if !ok {
+				continue
+			}
+			key := plugin.LocalPath
+			if real, err := filepath.EvalSymlinks(key); err == nil {
+				key = real
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	return plugins
+}
+
+// localPlugin describes the plugin at path, which is either a single file or
+// a folder. Symlinks are followed; a broken link is skipped.
+func localPlugin(path string) (PluginSpec, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return PluginSpec{}, false
+	}
+	if info.IsDir() {
+		return localDirPlugin(path)
+	}
+	ext := filepath.Ext(path)
+	if !isLocalPluginExtension(ext) {
+		return PluginSpec{}, false
+	}
+	return PluginSpec{
+		Name:         strings.TrimSuffix(filepath.Base(path), ext),
+		DeclaredSpec: path,
+		Source:       SourceLocal,
+		LocalPath:    path,
+	}, true
+}
+
+// localDirPlugin resolves the entry file of a folder plugin from its
+// package.json exports or main, falling back to an index file. The entry
+// must stay inside the folder once symlinks are resolved.
+func localDirPlugin(dir string) (PluginSpec, bool) {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return PluginSpec{}, false
+	}
+
+	manifest := localManifest{}
+	if data, err := os.ReadFile(filepath.Join(realDir, "package.json")); err == nil {
+		_ = json.Unmarshal(data, &manifest)
+	}
+
+	candidates := []string{}
+	if len(manifest.Exports) > 0 {
+		var exports any
+		if err := json.Unmarshal(manifest.Exports, &exports); err == nil {
+			if entry := exportsEntry(exports); entry != "" {
+				candidates = append(candidates, entry)
+			}
+		}
+	}
+	if manifest.Main != "" {
+		candidates = append(candidates, manifest.Main)
+	}
+	candidates = append(candidates, "index")
+
+	for _, candidate := range candidates {
+		rel, ok := resolveEntry(realDir, candidate)
+		if !ok {
+			continue
+		}
+		path := filepath.Join(dir, rel)
+		return PluginSpec{
+			Name:         filepath.Base(dir),
+			DeclaredSpec: path,
+			Source:       SourceLocal,
+			LocalPath:    path,
+			LocalDir:     dir,
+			Version:      manifest.Version,
+		}, true
+	}
+	return PluginSpec{}, false
+}
+
+// resolveEntry finds the file an entry refers to, trying the loadable
+// extensions when it has none. It returns the path relative to dir.
+func resolveEntry(dir string, entry string) (string, bool) {
+	entry = filepath.Clean(filepath.FromSlash(entry))
+	if filepath.IsAbs(entry) {
+		return "", false
+	}
+	names := []string{}
+	if isLocalPluginExtension(filepath.Ext(entry)) {
+		names = append(names, entry)
+	} else {
+		for _, ext := range localPluginExtensions {
+			names = append(names, entry+ext)
+		}
+		for _, ext := range localPluginExtensions {
+			names = append(names, filepath.Join(entry, "index"+ext))
+		}
+	}
+
+	for _, name := range names {
+		real, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+		if err != nil || !fileExists(real) {
+			continue
+		}
+		rel, err := filepath.Rel(dir, real)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return name, true
+	}
+	return "", false
+}
+
+// exportsEntry returns the root entry of a package.json exports field.
+func exportsEntry(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case map[string]any:
+		if root, ok := typed["."]; ok {
+			return exportsEntry(root)
+		}
+		for _, condition := range exportConditions {
+			if nested, ok := typed[condition]; ok {
+				if entry := exportsEntry(nested); entry != "" {
+					return entry
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isLocalPluginExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	for _, candidate := range localPluginExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package opencode
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeLocalFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestDiscoverLocalPluginsResolvesFolders(t *testing.T) {
+	root := t.TempDir()
+	writeLocalFile(t, filepath.Join(root, "indexed", "index.ts"), "export default {}")
+	writeLocalFile(t, filepath.Join(root, "main", "package.json"), `{"name":"main-plugin","version":"1.4.0","main":"lib/entry"}`)
+	writeLocalFile(t, filepath.Join(root, "main", "lib", "entry.js"), "module.exports = {}")
+	writeLocalFile(t, filepath.Join(root, "exported", "package.json"), `{"version":"2.0.0","main":"ignored.js","exports":{".":{"import":"./dist/mod.mjs","require":"./dist/mod.cjs"}}}`)
+	writeLocalFile(t, filepath.Join(root, "exported", "dist", "mod.mjs"), "export default {}")
+	writeLocalFile(t, filepath.Join(root, "empty", "README.md"), "no entry")
+	writeLocalFile(t, filepath.Join(root, "node_modules", "dep", "index.js"), "")
+
+	plugins := discoverLocalPlugins([]string{root})
+	byName := map[string]PluginSpec{}
+	for _, plugin := range plugins {
+		byName[plugin.Name] = plugin
+	}
+	if len(plugins) != 3 {
+		t.Fatalf("expected three folder plugins, got %+v", plugins)
+	}
+	if got := byName["indexed"]; got.LocalPath != filepath.Join(root, "indexed", "index.ts") || got.LocalDir != filepath.Join(root, "indexed") {
+		t.Fatalf("unexpected index plugin: %+v", got)
+	}
+	if got := byName["main"]; got.LocalPath != filepath.Join(root, "main", "lib", "entry.js") || got.Version != "1.4.0" {
+		t.Fatalf("unexpected main plugin: %+v", got)
+	}
+	if got := byName["exported"]; got.LocalPath != filepath.Join(root, "exported", "dist", "mod.mjs") || got.Version != "2.0.0" {
+		t.Fatalf("unexpected exports plugin: %+v", got)
+	}
+}
+
+func TestDiscoverLocalPluginsFollowsSymlinksSafely(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need extra privileges on windows")
+	}
+	root := t.TempDir()
+	pluginDir := filepath.Join(root, "plugin")
+	writeLocalFile(t, filepath.Join(root, "src", "linked", "index.js"), "export default {}")
+	writeLocalFile(t, filepath.Join(root, "secret.js"), "export default {}")
+	writeLocalFile(t, filepath.Join(root, "src", "escape", "package.json"), `{"main":"../../secret.js"}`)
+	writeLocalFile(t, filepath.Join(pluginDir, "direct.js"), "export default {}")
+	for link, target := range map[string]string{
+		"linked": filepath.Join(root, "src", "linked"),
+		"again":  filepath.Join(root, "src", "linked"),
+		"escape": filepath.Join(root, "src", "escape"),
+		"broken": filepath.Join(root, "missing"),
+		"alias":  filepath.Join(pluginDir, "direct.js"),
+	} {
+		if err := os.Symlink(target, filepath.Join(pluginDir, link)); err != nil {
+			t.Fatalf("symlink %s: %v", link, err)
+		}
+	}
+
+	plugins := discoverLocalPlugins([]string{pluginDir})
+	names := map[string]bool{}
+	for _, plugin := range plugins {
+		names[plugin.Name] = true
+	}
+	if len(plugins) != 2 || !names["direct"] || !(names["linked"] || names["again"]) {
+		t.Fatalf("expected direct and one linked plugin, got %+v", plugins)
+	}
+}
+
+func TestDefaultLocalPluginDirsIncludePluralSpelling(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	want := filepath.Join(root, ".opencode", "plugins")
+	for _, dir := range defaultLocalPluginDirs(root, "") {
+		if dir == want {
+			return
+		}
+	}
+	t.Fatalf("expected %s in plugin dirs", want)
+}
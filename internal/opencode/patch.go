@@ -0,0 +1,146 @@
+package opencode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// pluginListKeys are the config keys that hold plugin entries.
+var pluginListKeys = []string{"plugin", "plugins"}
+
+type listSpan struct {
+	start int
+	end   int
+}
+
+type textEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+// patchPluginLists rewrites the plugin lists of a JSON or JSONC config to the
+// values in raw and leaves every other byte alone. A list is only rewritten
+// when its entries changed; ErrCommentedList is returned when that list holds
+// comments, since they cannot be carried over.
+func patchPluginLists(data []byte, raw map[string]any) ([]byte, error) {
+	masked := maskJSONC(data)
+	dec := json.NewDecoder(bytes.NewReader(masked))
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	objectStart := int(dec.InputOffset())
+	firstMember := -1
+	spans := map[string]listSpan{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if firstMember < 0 {
+			firstMember = bytes.LastIndexByte(masked[:dec.InputOffset()-1], '"')
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if key, _ := token.(string); key == "plugin" || key == "plugins" {
+			end := int(dec.InputOffset())
+			spans[key] = listSpan{start: end - len(value), end: end}
+		}
+	}
+
+	edits := []textEdit{}
+	for _, key := range pluginListKeys {
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		list, err := coerceStringSlice(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s list: %w", key, err)
+		}
+		span, ok := spans[key]
+		if !ok {
+			if len(list) == 0 {
+				continue
+			}
+			edits = append(edits, insertMember(data, objectStart, firstMember, key, formatList(nil, list)))
+			continue
+		}
+		var current []string
+		if err := json.Unmarshal(masked[span.start:span.end], &current); err == nil && reflect.DeepEqual(current, list) {
+			continue
+		}
+		original := data[span.start:span.end]
+		if !bytes.Equal(maskComments(original), original) {
+			return nil, fmt.Errorf("%w: edit the %q list by hand", ErrCommentedList, key)
+		}
+		edits = append(edits, textEdit{start: span.start, end: span.end, text: formatList(original, list)})
+	}
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := append([]byte{}, data...)
+	for _, edit := range edits {
+		out = append(out[:edit.start], append([]byte(edit.text), out[edit.end:]...)...)
+	}
+	return out, nil
+}
+
+// formatList renders a plugin list in the layout of the list it replaces: on
+// one line, or one entry per line at the original indentation.
+func formatList(original []byte, list []string) string {
+	quoted := make([]string, len(list))
+	for i, entry := range list {
+		encoded, _ := json.Marshal(entry)
+		quoted[i] = string(encoded)
+	}
+	if len(list) == 0 {
+		return "[]"
+	}
+	if !bytes.ContainsRune(original, '\n') {
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+
+	closeIndent := string(original[bytes.LastIndexByte(original, '\n')+1 : len(original)-1])
+	itemIndent := closeIndent + "  "
+	first := original[bytes.IndexByte(original, '\n')+1:]
+	if trimmed := bytes.TrimLeft(first, " \t"); len(trimmed) > 0 && trimmed[0] != ']' {
+		itemIndent = string(first[:len(first)-len(trimmed)])
+	}
+	var b strings.Builder
+	b.WriteString("[\n")
+	for i, entry := range quoted {
+		b.WriteString(itemIndent + entry)
+		if i < len(quoted)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(closeIndent + "]")
+	return b.String()
+}
+
+// insertMember adds a new key at the top of the config object, matching the
+// indentation of the first existing member.
+func insertMember(data []byte, objectStart int, firstMember int, key string, value string) textEdit {
+	member := fmt.Sprintf("%q: %s", key, value)
+	if firstMember < 0 {
+		if bytes.ContainsRune(data[objectStart:], '\n') {
+			return textEdit{start: objectStart, end: objectStart, text: "\n  " + member}
+		}
+		return textEdit{start: objectStart, end: objectStart, text: member}
+	}
+	lineStart := bytes.LastIndexByte(data[:firstMember], '\n')
+	if lineStart < objectStart {
+		return textEdit{start: objectStart, end: objectStart, text: member + ", "}
+	}
+	indent := string(data[lineStart+1 : firstMember])
+	return textEdit{start: objectStart, end: objectStart, text: "\n" + indent + member + ","}
+}
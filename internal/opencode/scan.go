@@ -0,0 +1,70 @@
+package opencode
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AksharP5/Patchline/internal/ignore"
+)
+
+// skippedDirs are never searched for project configs.
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	".git":         true,
+	".opencode":    true,
+}
+
+// FindProjects walks down from root and returns the config of every project
+// below it, one per directory, in path order. Directories ignored by
+// .gitignore files, node_modules and .git are skipped. The .opencode
+// directory is checked as part of its parent.
+func FindProjects(root string) ([]string, error) {
+	root = filepath.Clean(root)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("scan root is not a directory: %s", root)
+	}
+
+	var matcher ignore.Matcher
+	configs := []string{}
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path != root && os.IsPermission(err) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+		if rel != "" && (skippedDirs[entry.Name()] || matcher.Ignored(rel, true)) {
+			return fs.SkipDir
+		}
+		if err := matcher.Load(path, rel); err != nil {
+			return err
+		}
+		if config, _ := findVariant(path, projectConfigNames); config != "" {
+			configs = append(configs, config)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(configs)
+	return configs, nil
+}
@@ -0,0 +1,47 @@
+package opencode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProjects(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"opencode.json":                             `{}`,
+		".gitignore":                                "generated/\n",
+		"packages/web/opencode.jsonc":               `{}`,
+		"packages/api/.opencode/opencode.json":      `{}`,
+		"packages/api/node_modules/x/opencode.json": `{}`,
+		"generated/opencode.json":                   `{}`,
+		"packages/docs/README.md":                   "docs",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	configs, err := FindProjects(root)
+	if err != nil {
+		t.Fatalf("find projects: %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "opencode.json"),
+		filepath.Join(root, "packages", "api", ".opencode", "opencode.json"),
+		filepath.Join(root, "packages", "web", "opencode.jsonc"),
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, configs)
+	}
+	for i := range want {
+		if configs[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, configs)
+		}
+	}
+}
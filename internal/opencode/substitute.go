@@ -0,0 +1,57 @@
+package opencode
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// substitutionPattern matches the {env:NAME} and {file:path} references
+// OpenCode expands in config values.
+var substitutionPattern = regexp.MustCompile(`\{(env|file):([^}]+)\}`)
+
+// isTemplated reports whether a config value uses substitution.
+func isTemplated(value string) bool {
+	return substitutionPattern.MatchString(value)
+}
+
+// expandSubstitutions expands {env:NAME} and {file:path} references the way
+// OpenCode does. Unset variables expand to an empty string. File paths are
+// relative to the config directory, may start with ~/, and their content is
+// trimmed. A file that cannot be read is an error.
+func expandSubstitutions(value string, configDir string) (string, error) {
+	var expandErr error
+	expanded := substitutionPattern.ReplaceAllStringFunc(value, func(match string) string {
+		parts := substitutionPattern.FindStringSubmatch(match)
+		kind, ref := parts[1], strings.TrimSpace(parts[2])
+		if kind == "env" {
+			return os.Getenv(ref)
+		}
+
+		path := ref
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				expandErr = fmt.Errorf("%w: %s: %v", ErrBadFileReference, match, err)
+				return match
+			}
+			path = filepath.Join(home, path[2:])
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if expandErr == nil {
+				expandErr = fmt.Errorf("%w: %s: %v", ErrBadFileReference, match, err)
+			}
+			return match
+		}
+		return strings.TrimSpace(string(data))
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
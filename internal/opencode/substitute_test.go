@@ -0,0 +1,101 @@
+package opencode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePluginSpecsExpandsSubstitutions(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("PATCHLINE_TEST_PLUGIN", "alpha@1.2.0")
+	if err := os.WriteFile(filepath.Join(root, "beta.txt"), []byte("beta@2.0.0\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	path := filepath.Join(root, "opencode.json")
+	data := `{"plugin": ["{env:PATCHLINE_TEST_PLUGIN}", "{file:beta.txt}", "{env:PATCHLINE_TEST_UNSET}", "delta@1.0.0"]}`
+
+	specs, err := ParsePluginSpecs(path, []byte(data))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(specs) != 3 {
+		t.Fatalf("expected unset variable entry to be skipped, got %+v", specs)
+	}
+	if specs[0].Name != "alpha" || specs[0].Pinned != "1.2.0" || specs[0].Template != "{env:PATCHLINE_TEST_PLUGIN}" {
+		t.Fatalf("unexpected env spec: %+v", specs[0])
+	}
+	if specs[1].Name != "beta" || specs[1].DeclaredSpec != "beta@2.0.0" || specs[1].Template == "" {
+		t.Fatalf("unexpected file spec: %+v", specs[1])
+	}
+	if specs[2].Template != "" {
+		t.Fatalf("expected literal spec, got %+v", specs[2])
+	}
+}
+
+func TestMissingFileReferenceOnlyAffectsItsEntry(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "xdg"))
+	path := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(path, []byte(`{"plugin": ["{file:missing.txt}", "beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	result, err := Discover(root, "", nil)
+	if err != nil {
+		t.Fatalf("discover: %v", err)
+	}
+	if len(result.Plugins) != 1 || result.Plugins[0].Name != "beta" {
+		t.Fatalf("expected only beta to be declared, got %+v", result.Plugins)
+	}
+	if len(result.Unexpanded) != 1 || result.Unexpanded[0].Template != "{file:missing.txt}" || !errors.Is(result.Unexpanded[0].TemplateErr, ErrBadFileReference) {
+		t.Fatalf("expected the bad file reference to be unexpanded, got %+v", result.Unexpanded)
+	}
+
+	if err := UpdatePluginSpec(path, "beta", "beta@1.2.0"); err != nil {
+		t.Fatalf("update beta: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), `"{file:missing.txt}"`) || !strings.Contains(string(data), `"beta@1.2.0"`) {
+		t.Fatalf("expected only beta to change, got %s", data)
+	}
+}
+
+func TestUpdatePluginSpecRefusesTemplatedEntry(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("PATCHLINE_TEST_PLUGIN", "alpha@1.0.0")
+	path := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(path, []byte(`{"plugin": ["{env:PATCHLINE_TEST_PLUGIN}", "beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := UpdatePluginSpec(path, "alpha", "alpha@1.2.0"); !errors.Is(err, ErrTemplatedSpec) {
+		t.Fatalf("expected templated error, got %v", err)
+	}
+	if err := RemovePluginSpec(path, "alpha"); !errors.Is(err, ErrTemplatedSpec) {
+		t.Fatalf("expected templated error on remove, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), "{env:PATCHLINE_TEST_PLUGIN}") {
+		t.Fatalf("expected template preserved, got %s", data)
+	}
+
+	if err := FlattenPluginSpec(path, "alpha", "alpha@1.2.0"); err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(data), "{env:") || !strings.Contains(string(data), "alpha@1.2.0") {
+		t.Fatalf("expected literal spec, got %s", data)
+	}
+}
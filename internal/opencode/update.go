@@ -4,13 +4,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
 )
 
-// UpdatePluginSpec updates the declared plugin spec in the config file.
+// UpdatePluginSpec updates the declared plugin spec in the config file. An
+// entry that uses {env:} or {file:} substitution is left alone and
+// ErrTemplatedSpec is returned; use FlattenPluginSpec to replace it. For a
+// package.json path the dependency's version is updated instead.
 func UpdatePluginSpec(path string, pluginName string, newSpec string) error {
+	return updatePluginSpec(path, pluginName, newSpec, false)
+}
+
+// FlattenPluginSpec updates the declared plugin spec like UpdatePluginSpec,
+// replacing templated entries with the literal spec.
+func FlattenPluginSpec(path string, pluginName string, newSpec string) error {
+	return updatePluginSpec(path, pluginName, newSpec, true)
+}
+
+func updatePluginSpec(path string, pluginName string, newSpec string, flatten bool) error {
 	if path == "" {
 		return fmt.Errorf("config path is required")
 	}
+	if isDependencyManifest(path) {
+		return updateDependency(path, pluginName, newSpec)
+	}
+	raw, err := readRawConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := updateRaw(raw, path, pluginName, newSpec, flatten); err != nil {
+		return err
+	}
+	return writeRawConfig(path, raw)
+}
+
+// UpdateInlinePluginSpec returns content, an OPENCODE_CONFIG_CONTENT value,
+// with the plugin's spec replaced. The environment is neither read nor
+// changed, so several updates can build on each other's result.
+func UpdateInlinePluginSpec(content string, pluginName string, newSpec string, flatten bool) (string, error) {
+	raw, err := parseRawConfig(InlineConfigPath, []byte(content))
+	if err != nil {
+		return "", err
+	}
+	if err := updateRaw(raw, InlineConfigPath, pluginName, newSpec, flatten); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("encode %s: %w", inlineConfigEnv, err)
+	}
+	return string(out), nil
+}
+
+func updateRaw(raw map[string]any, path string, pluginName string, newSpec string, flatten bool) error {
 	if pluginName == "" {
 		return fmt.Errorf("plugin name is required")
 	}
@@ -18,47 +67,201 @@ func UpdatePluginSpec(path string, pluginName string, newSpec string) error {
 		return fmt.Errorf("new spec is required")
 	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", path, err)
+	updated := false
+	for _, key := range []string{"plugin", "plugins"} {
+		list, ok, err := updateList(raw, key, path, pluginName, newSpec, flatten)
+		if err != nil {
+			return err
+		}
+		if ok {
+			raw[key] = list
+			updated = true
+		}
 	}
 
-	var raw map[string]any
-	if err := json.Unmarshal(sanitizeJSONC(data), &raw); err != nil {
-		return fmt.Errorf("parse %s: %w", path, err)
+	if !updated {
+		return ErrPluginNotFound
 	}
+	return nil
+}
 
-	updated := false
-	if list, ok, err := updateList(raw, "plugin", pluginName, newSpec); err != nil {
-		return err
-	} else if ok {
-		raw["plugin"] = list
-		updated = true
+// AddPluginSpec appends a plugin spec to the config file, creating the file
+// when it does not exist.
+func AddPluginSpec(path string, spec string) error {
+	if path == "" {
+		return fmt.Errorf("config path is required")
+	}
+	if spec == "" {
+		return fmt.Errorf("spec is required")
+	}
+
+	raw := map[string]any{}
+	if fileExists(path) {
+		existing, err := readRawConfig(path)
+		if err != nil {
+			return err
+		}
+		raw = existing
+	}
+
+	key := "plugin"
+	if _, ok := raw["plugin"]; !ok {
+		if _, ok := raw["plugins"]; ok {
+			key = "plugins"
+		}
+	}
+	list := []string{}
+	if value, ok := raw[key]; ok {
+		existing, err := coerceStringSlice(value)
+		if err != nil {
+			return fmt.Errorf("parse %s list: %w", key, err)
+		}
+		list = existing
+	}
+	raw[key] = append(list, spec)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return writeRawConfig(path, raw)
+}
+
+// RemovePluginSpec removes every entry for a plugin from the config file.
+func RemovePluginSpec(path string, pluginName string) error {
+	if path == "" {
+		return fmt.Errorf("config path is required")
+	}
+	if pluginName == "" {
+		return fmt.Errorf("plugin name is required")
 	}
 
-	if list, ok, err := updateList(raw, "plugins", pluginName, newSpec); err != nil {
+	raw, err := readRawConfig(path)
+	if err != nil {
 		return err
-	} else if ok {
-		raw["plugins"] = list
-		updated = true
 	}
 
-	if !updated {
+	removed := false
+	for _, key := range []string{"plugin", "plugins"} {
+		value, ok := raw[key]
+		if !ok {
+			continue
+		}
+		list, err := coerceStringSlice(value)
+		if err != nil {
+			return fmt.Errorf("parse %s list: %w", key, err)
+		}
+		kept := []string{}
+		for _, entry := range list {
+			if entryName(entry, path) == pluginName {
+				if isTemplated(entry) {
+					return fmt.Errorf("%w: %s", ErrTemplatedSpec, entry)
+				}
+				removed = true
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		raw[key] = kept
+	}
+
+	if !removed {
 		return ErrPluginNotFound
 	}
+	return writeRawConfig(path, raw)
+}
+
+// RestorePluginLists sets the plugin lists of the config file to those in
+// snapshot, an earlier copy of the same file. The rest of the file is kept.
+// A missing file is written back as snapshot.
+func RestorePluginLists(path string, snapshot []byte) error {
+	if path == "" {
+		return fmt.Errorf("config path is required")
+	}
+	old, err := parseRawConfig(path, snapshot)
+	if err != nil {
+		return err
+	}
+	if !fileExists(path) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		if err := atomicfile.Write(path, snapshot, 0o600); err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		return nil
+	}
 
-	out, err := json.MarshalIndent(raw, "", "  ")
+	raw, err := readRawConfig(path)
 	if err != nil {
-		return fmt.Errorf("write %s: %w", path, err)
+		return err
 	}
-	out = append(out, '\n')
-	if err := os.WriteFile(path, out, 0o600); err != nil {
+	for _, key := range pluginListKeys {
+		value, ok := old[key]
+		if !ok {
+			if _, ok := raw[key]; ok {
+				raw[key] = []string{}
+			}
+			continue
+		}
+		list, err := coerceStringSlice(value)
+		if err != nil {
+			return fmt.Errorf("parse %s list: %w", key, err)
+		}
+		raw[key] = list
+	}
+	return writeRawConfig(path, raw)
+}
+
+func readRawConfig(path string) (map[string]any, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return parseRawConfig(path, data)
+}
+
+func parseRawConfig(path string, data []byte) (map[string]any, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(sanitizeJSONC(data), &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if raw == nil {
+		raw = map[string]any{}
+	}
+	return raw, nil
+}
+
+// writeRawConfig saves the plugin lists in raw to a config file. An existing
+// file is patched in place so comments and formatting outside the lists are
+// kept; a new file is written from raw.
+func writeRawConfig(path string, raw map[string]any) error {
+	if IsInlineConfig(path) {
+		return fmt.Errorf("%w: %s cannot be written", ErrInlineConfig, inlineConfigEnv)
+	}
+	data, err := os.ReadFile(path)
+	var out []byte
+	switch {
+	case err == nil:
+		out, err = patchPluginLists(data, raw)
+		if err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+	case os.IsNotExist(err):
+		out, err = json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return fmt.Errorf("write %s: %w", path, err)
+		}
+		out = append(out, '\n')
+	default:
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := atomicfile.Write(path, out, 0o600); err != nil {
 		return fmt.Errorf("write %s: %w", path, err)
 	}
 	return nil
 }
 
-func updateList(raw map[string]any, key string, pluginName string, newSpec string) ([]string, bool, error) {
+func updateList(raw map[string]any, key string, path string, pluginName string, newSpec string, flatten bool) ([]string, bool, error) {
 	value, ok := raw[key]
 	if !ok {
 		return nil, false, nil
@@ -69,16 +272,30 @@ func updateList(raw map[string]any, key string, pluginName string, newSpec strin
 	}
 
 	updated := false
-	for i, spec := range list {
-		name, _ := parseSpec(spec)
-		if name == pluginName {
-			list[i] = newSpec
-			updated = true
+	for i, entry := range list {
+		if entryName(entry, path) != pluginName {
+			continue
 		}
+		if isTemplated(entry) && !flatten {
+			return nil, false, fmt.Errorf("%w: %s", ErrTemplatedSpec, entry)
+		}
+		list[i] = newSpec
+		updated = true
 	}
 	return list, updated, nil
 }
 
+// entryName returns the plugin name a raw config entry declares once its
+// substitutions are expanded. An entry that fails to expand is named after
+// the entry itself, as parsePluginSpecs does.
+func entryName(entry string, path string) string {
+	spec, err := expandSpec(entry, path)
+	if err != nil {
+		return strings.TrimSpace(entry)
+	}
+	return specName(spec)
+}
+
 func coerceStringSlice(value any) ([]string, error) {
 	switch typed := value.(type) {
 	case []string:
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -36,6 +37,36 @@ func TestUpdatePluginSpecPluginKey(t *testing.T) {
 	}
 }
 
+func TestUpdatePluginSpecKeepsFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on windows")
+	}
+	root := t.TempDir()
+	path := filepath.Join(root, "opencode.json")
+	if err := os.WriteFile(path, []byte(`{"plugin": ["alpha@1.0.0"]}`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	if err := UpdatePluginSpec(path, "alpha", "alpha@1.2.0"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Fatalf("expected mode 0644 to be kept, got %v", info.Mode().Perm())
+	}
+	matches, err := filepath.Glob(filepath.Join(root, ".patchline-*"))
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("expected no temporary files, got %v %v", matches, err)
+	}
+}
+
 func TestUpdatePluginSpecPluginsKey(t *testing.T) {
 	root := t.TempDir()
 	path := filepath.Join(root, "opencode.json")
@@ -99,3 +130,151 @@ func TestUpdatePluginSpecNotFound(t *testing.T) {
 		t.Fatalf("expected ErrPluginNotFound, got %v", err)
 	}
 }
+
+func TestAddPluginSpecCreatesConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "opencode.json")
+	if err := AddPluginSpec(path, "alpha@1.0.0"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := AddPluginSpec(path, "beta"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	specs, err := loadPluginSpecs(path, SourceProject)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(specs) != 2 || specs[0].DeclaredSpec != "alpha@1.0.0" || specs[1].DeclaredSpec != "beta" {
+		t.Fatalf("unexpected specs: %#v", specs)
+	}
+}
+
+func TestRemovePluginSpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opencode.json")
+	data := `{"plugin": ["alpha@1.0.0", "beta"], "plugins": ["alpha"], "theme": "dark"}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := RemovePluginSpec(path, "alpha"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	updated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.Contains(string(updated), "alpha") || !strings.Contains(string(updated), "theme") {
+		t.Fatalf("expected alpha removed and other keys kept, got %s", string(updated))
+	}
+	if err := RemovePluginSpec(path, "alpha"); !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+func TestUpdateInlinePluginSpec(t *testing.T) {
+	original := `{"plugin": ["alpha@1.0.0", "beta@1.0.0"], "theme": "dark"}`
+	t.Setenv("OPENCODE_CONFIG_CONTENT", original)
+
+	value, err := UpdateInlinePluginSpec(original, "alpha", "alpha@1.2.0", false)
+	if err != nil {
+		t.Fatalf("update inline: %v", err)
+	}
+	if value != `{"plugin":["alpha@1.2.0","beta@1.0.0"],"theme":"dark"}` {
+		t.Fatalf("unexpected value: %s", value)
+	}
+	value, err = UpdateInlinePluginSpec(value, "beta", "beta@2.0.0", false)
+	if err != nil {
+		t.Fatalf("update inline: %v", err)
+	}
+	if value != `{"plugin":["alpha@1.2.0","beta@2.0.0"],"theme":"dark"}` {
+		t.Fatalf("expected the second update to build on the first, got %s", value)
+	}
+	if os.Getenv("OPENCODE_CONFIG_CONTENT") != original {
+		t.Fatalf("expected environment to be left alone")
+	}
+
+	if err := UpdatePluginSpec(InlineConfigPath, "alpha", "alpha@1.2.0"); !errors.Is(err, ErrInlineConfig) {
+		t.Fatalf("expected inline config error, got %v", err)
+	}
+}
+
+func TestWritesKeepJSONCCommentsAndLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opencode.jsonc")
+	original := `{
+  // team plugins, keep sorted
+  "$schema": "https://opencode.ai/config.json",
+  "plugin": [
+    "alpha@1.0.0",
+    "beta@2.0.0",
+  ],
+  /* theme */ "theme": "dark",
+}
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if err := UpdatePluginSpec(path, "alpha", "alpha@1.1.0"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := strings.Replace(original, `    "alpha@1.0.0",
+    "beta@2.0.0",
+`, `    "alpha@1.1.0",
+    "beta@2.0.0"
+`, 1)
+	if got := readFile(t, path); got != want {
+		t.Fatalf("expected only the list to change, got:\n%s", got)
+	}
+
+	if _, err := DisablePlugin(path, "beta"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if _, err := EnablePlugin(path, "beta"); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if got := readFile(t, path); got != want {
+		t.Fatalf("expected disable and enable to round-trip, got:\n%s", got)
+	}
+}
+
+func TestWritesRefuseCommentsInsideThePluginList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opencode.jsonc")
+	original := `{
+  "plugin": [
+    // pinned until the 2.x migration
+    "alpha@1.0.0"
+  ]
+}
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := UpdatePluginSpec(path, "alpha", "alpha@2.0.0"); !errors.Is(err, ErrCommentedList) {
+		t.Fatalf("expected ErrCommentedList, got %v", err)
+	}
+	if got := readFile(t, path); got != original {
+		t.Fatalf("expected the file to be left alone, got:\n%s", got)
+	}
+}
+
+func TestAddPluginSpecInsertsListIntoCommentedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opencode.jsonc")
+	original := "{\n  // theme only\n  \"theme\": \"dark\"\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := AddPluginSpec(path, "alpha@1.0.0"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	want := "{\n  \"plugin\": [\"alpha@1.0.0\"],\n  // theme only\n  \"theme\": \"dark\"\n}\n"
+	if got := readFile(t, path); got != want {
+		t.Fatalf("unexpected config:\n%s", got)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,10 @@
+package policy
+
+import "errors"
+
+var (
+	// ErrPolicyNotFound indicates an explicitly requested policy file does not exist.
+	ErrPolicyNotFound = errors.New("policy not found")
+	// ErrInvalidPolicy indicates a policy file could not be parsed.
+	ErrInvalidPolicy = errors.New("invalid policy")
+)
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ProjectPath returns the project policy path for the given project directory.
+func ProjectPath(projectDir string) string {
+	return filepath.Join(projectDir, ".patchline", "policy.json")
+}
+
+// GlobalCandidates returns default global policy paths in lookup order.
+func GlobalCandidates() []string {
+	paths := []string{}
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "patchline", "policy.json"))
+	} else {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			paths = append(paths, filepath.Join(appData, "patchline", "policy.json"))
+		}
+		home, err := os.UserHomeDir()
+		if err == nil && home != "" {
+			paths = append(paths, filepath.Join(home, ".config", "patchline", "policy.json"))
+		}
+	}
+	return uniqueStrings(paths)
+}
+
+// ResolveGlobal returns the first existing global policy path, if any.
+func ResolveGlobal() string {
+	for _, candidate := range GlobalCandidates() {
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		out = append(out, value)
+	}
+	return out
+}
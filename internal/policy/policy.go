@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/npm"
+)
+
+// Rule names reported in violations.
+const (
+	RuleDenied     = "denied"
+	RuleNotAllowed = "not-allowed"
+	RuleRequired   = "required"
+	RuleConstraint = "constraint"
+)
+
+// File is the on-disk policy format. Package patterns are exact names,
+// globs such as "@scope/*" or "opencode-*", or a bare "@scope".
+type File struct {
+	Allow       []string          `json:"allow"`
+	Deny        []string          `json:"deny"`
+	Require     []string          `json:"require"`
+	Constraints map[string]string `json:"constraints"`
+}
+
+// Policy is the merged result of every loaded policy file.
+type Policy struct {
+	Allow       []string
+	Deny        []string
+	Require     []string
+	Constraints []Constraint
+	Sources     []string
+}
+
+// Constraint restricts the versions of packages matching Pattern.
+type Constraint struct {
+	Pattern string
+	Range   npm.Range
+	Source  string
+}
+
+// Violation describes one way a plugin breaks the policy.
+type Violation struct {
+	Plugin  string `json:"plugin"`
+	Version string `json:"version,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Plugin is a plugin to evaluate. Version may be empty when unknown.
+type Plugin struct {
+	Name    string
+	Version string
+}
+
+// Load reads and merges policy files in order. Missing files are skipped;
+// lists are unioned and every matching constraint must hold.
+func Load(paths ...string) (Policy, error) {
+	merged := Policy{}
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Policy{}, fmt.Errorf("read %s: %w", p, err)
+		}
+		file, err := parse(data)
+		if err != nil {
+			return Policy{}, fmt.Errorf("%w: %s: %v", ErrInvalidPolicy, p, err)
+		}
+		if err := merged.add(file, p); err != nil {
+			return Policy{}, fmt.Errorf("%w: %s: %v", ErrInvalidPolicy, p, err)
+		}
+	}
+	return merged, nil
+}
+
+func parse(data []byte) (File, error) {
+	var file File
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return File{}, err
+	}
+	return file, nil
+}
+
+func (p *Policy) add(file File, source string) error {
+	p.Allow = appendUnique(p.Allow, file.Allow...)
+	p.Deny = appendUnique(p.Deny, file.Deny...)
+	p.Require = appendUnique(p.Require, file.Require...)
+
+	patterns := make([]string, 0, len(file.Constraints))
+	for pattern := range file.Constraints {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		r, err := npm.ParseRange(file.Constraints[pattern])
+		if err != nil {
+			return err
+		}
+		p.Constraints = append(p.Constraints, Constraint{Pattern: pattern, Range: r, Source: source})
+	}
+	p.Sources = append(p.Sources, source)
+	return nil
+}
+
+// Empty reports whether no policy file was loaded.
+func (p Policy) Empty() bool {
+	return len(p.Sources) == 0
+}
+
+// CheckPackage evaluates the deny, allow and version rules for one package.
+// Version constraints are skipped when version is empty.
+func (p Policy) CheckPackage(name string, version string) []Violation {
+	violations := []Violation{}
+	if pattern, ok := matchAny(p.Deny, name); ok {
+		violations = append(violations, Violation{
+			Plugin:  name,
+			Version: version,
+			Rule:    RuleDenied,
+			Message: fmt.Sprintf("%s is blocked by %q", name, pattern),
+		})
+	} else if len(p.Allow) > 0 {
+		if _, ok := matchAny(p.Allow, name); !ok {
+			violations = append(violations, Violation{
+				Plugin:  name,
+				Version: version,
+				Rule:    RuleNotAllowed,
+				Message: fmt.Sprintf("%s is not on the allow list", name),
+			})
+		}
+	}
+
+	if version == "" {
+		return violations
+	}
+	for _, constraint := range p.Constraints {
+		if !Match(constraint.Pattern, name) {
+			continue
+		}
+		if constraint.Range.Contains(version) {
+			continue
+		}
+		violations = append(violations, Violation{
+			Plugin:  name,
+			Version: version,
+			Rule:    RuleConstraint,
+			Message: fmt.Sprintf("%s@%s does not satisfy %q", name, version, constraint.Range.String()),
+		})
+	}
+	return violations
+}
+
+// Evaluate checks every plugin and reports required plugins that are absent.
+func (p Policy) Evaluate(plugins []Plugin) []Violation {
+	violations := []Violation{}
+	present := map[string]struct{}{}
+	for _, plugin := range plugins {
+		present[plugin.Name] = struct{}{}
+		violations = append(violations, p.CheckPackage(plugin.Name, plugin.Version)...)
+	}
+	for _, required := range p.Require {
+		if _, ok := present[required]; ok {
+			continue
+		}
+		violations = append(violations, Violation{
+			Plugin:  required,
+			Rule:    RuleRequired,
+			Message: fmt.Sprintf("%s is required but not declared", required),
+		})
+	}
+	return violations
+}
+
+// Match reports whether a package name matches a policy pattern.
+func Match(pattern string, name string) bool {
+	if pattern == name || pattern == "*" {
+		return true
+	}
+	if strings.HasPrefix(pattern, "@") && !strings.Contains(pattern, "/") {
+		return strings.HasPrefix(name, pattern+"/")
+	}
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func matchAny(patterns []string, name string) (string, bool) {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func appendUnique(values []string, extra ...string) []string {
+	for _, value := range extra {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		found := false
+		for _, existing := range values {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePolicy(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+}
+
+func TestLoadMergesFiles(t *testing.T) {
+	root := t.TempDir()
+	global := filepath.Join(root, "global.json")
+	project := ProjectPath(root)
+	writePolicy(t, global, `{"deny":["bad-plugin"],"constraints":{"alpha":"<3"}}`)
+	writePolicy(t, project, `{"allow":["@acme/*","alpha"],"require":["@acme/audit"],"constraints":{"alpha":">=1.2.0"}}`)
+
+	pol, err := Load(global, filepath.Join(root, "missing.json"), project)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(pol.Sources) != 2 {
+		t.Fatalf("expected two sources, got %v", pol.Sources)
+	}
+	if len(pol.Deny) != 1 || len(pol.Allow) != 2 || len(pol.Require) != 1 {
+		t.Fatalf("unexpected merged lists: %+v", pol)
+	}
+	if len(pol.Constraints) != 2 {
+		t.Fatalf("expected both constraints, got %d", len(pol.Constraints))
+	}
+}
+
+func TestLoadRejectsInvalidPolicy(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "policy.json")
+
+	writePolicy(t, path, `{"blocked":["x"]}`)
+	if _, err := Load(path); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy for unknown field, got %v", err)
+	}
+
+	writePolicy(t, path, `{"constraints":{"alpha":"not a range"}}`)
+	if _, err := Load(path); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("expected ErrInvalidPolicy for bad range, got %v", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"alpha", "alpha", true},
+		{"alpha", "alpha-beta", false},
+		{"@acme", "@acme/tool", true},
+		{"@acme", "@acmecorp/tool", false},
+		{"@acme/*", "@acme/tool", true},
+		{"opencode-*", "opencode-wakatime", true},
+		{"opencode-*", "@scope/opencode-x", false},
+		{"*", "@scope/anything", true},
+	}
+	for _, tc := range cases {
+		if got := Match(tc.pattern, tc.name); got != tc.want {
+			t.Fatalf("Match(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "policy.json")
+	writePolicy(t, path, `{"allow":["@acme/*","alpha"],"deny":["alpha-legacy"],"constraints":{"alpha":"<3"}}`)
+	pol, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if violations := pol.CheckPackage("alpha", "2.5.0"); len(violations) != 0 {
+		t.Fatalf("expected no violations, got %+v", violations)
+	}
+	if violations := pol.CheckPackage("alpha", "3.0.0"); len(violations) != 1 || violations[0].Rule != RuleConstraint {
+		t.Fatalf("expected constraint violation, got %+v", violations)
+	}
+	if violations := pol.CheckPackage("alpha", ""); len(violations) != 0 {
+		t.Fatalf("expected unknown version to skip constraints, got %+v", violations)
+	}
+	if violations := pol.CheckPackage("beta", "1.0.0"); len(violations) != 1 || violations[0].Rule != RuleNotAllowed {
+		t.Fatalf("expected not-allowed violation, got %+v", violations)
+	}
+	if violations := pol.CheckPackage("alpha-legacy", "1.0.0"); len(violations) != 1 || violations[0].Rule != RuleDenied {
+		t.Fatalf("expected denied violation, got %+v", violations)
+	}
+}
+
+func TestEvaluateReportsMissingRequired(t *testing.T) {
+	pol := Policy{Require: []string{"@acme/audit"}, Sources: []string{"test"}}
+
+	violations := pol.Evaluate([]Plugin{{Name: "alpha", Version: "1.0.0"}})
+	if len(violations) != 1 || violations[0].Rule != RuleRequired || violations[0].Plugin != "@acme/audit" {
+		t.Fatalf("expected required violation, got %+v", violations)
+	}
+
+	violations = pol.Evaluate([]Plugin{{Name: "@acme/audit", Version: "1.0.0"}})
+	if len(violations) != 0 {
+		t.Fatalf("expected no violations, got %+v", violations)
+	}
+}
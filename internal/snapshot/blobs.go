@@ -0,0 +1,193 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+)
+
+const blobsDirName = "blobs"
+
+// HashBytes returns the content address used for config backups.
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256-" + hex.EncodeToString(sum[:])
+}
+
+// PutBlob stores a byte-exact copy of data and returns its hash. Identical
+// content is stored once.
+func (s Store) PutBlob(data []byte) (string, error) {
+	if s.Directory == "" {
+		return "", fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	var hash string
+	err := s.withLock(func() error {
+		var putErr error
+		hash, putErr = s.putBlob(data)
+		return putErr
+	})
+	return hash, err
+}
+
+// putBlob writes a blob. The caller holds the lock, so a concurrent prune
+// cannot collect it before an entry refers to it.
+func (s Store) putBlob(data []byte) (string, error) {
+	hash := HashBytes(data)
+	path := s.blobPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("create blob dir: %w", err)
+	}
+	if err := atomicfile.Write(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("write blob: %w", err)
+	}
+	return hash, nil
+}
+
+// Blob returns the stored content for hash and checks it was not altered.
+func (s Store) Blob(hash string) ([]byte, error) {
+	if s.Directory == "" {
+		return nil, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if !validHash(hash) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, hash)
+	}
+	data, err := os.ReadFile(s.blobPath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, hash)
+		}
+		return nil, fmt.Errorf("read blob: %w", err)
+	}
+	if HashBytes(data) != hash {
+		return nil, fmt.Errorf("%w: %s", ErrBlobCorrupt, hash)
+	}
+	return data, nil
+}
+
+// captureConfig stores a backup of the entry's config file and records its
+// hash. Entries whose config file is missing are left unchanged. The caller
+// holds the lock.
+func (s Store) captureConfig(entry Entry) (Entry, error) {
+	if entry.ConfigHash != "" || entry.ConfigPath == "" {
+		return entry, nil
+	}
+	data, err := os.ReadFile(entry.ConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entry, nil
+		}
+		return entry, fmt.Errorf("read %s: %w", entry.ConfigPath, err)
+	}
+	hash, err := s.putBlob(data)
+	if err != nil {
+		return entry, err
+	}
+	entry.ConfigHash = hash
+	return entry, nil
+}
+
+// captureLocal stores a copy of the entry's local plugin file and records its
+// hash. Entries whose file is missing are left unchanged. The caller holds the
+// lock.
+func (s Store) captureLocal(entry Entry) (Entry, error) {
+	if entry.LocalHash != "" || entry.LocalPath == "" {
+		return entry, nil
+	}
+	data, err := os.ReadFile(entry.LocalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entry, nil
+		}
+		return entry, fmt.Errorf("read %s: %w", entry.LocalPath, err)
+	}
+	hash, err := s.putBlob(data)
+	if err != nil {
+		return entry, err
+	}
+	entry.LocalHash = hash
+	return entry, nil
+}
+
+// blobHashes returns the blobs an entry refers to.
+func (e Entry) blobHashes() []string {
+	hashes := []string{}
+	for _, hash := range []string{e.ConfigHash, e.LocalHash} {
+		if hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// collectGarbage removes blobs no longer referenced by any entry or set. The
+// caller holds the lock.
+func (s Store) collectGarbage() (int, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(s.Directory, blobsDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read blob dir: %w", err)
+	}
+
+	referenced := map[string]struct{}{}
+	files, err := s.pluginFiles()
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range files {
+		entries, err := s.loadEntries(path, true)
+		if err != nil {
+			return 0, fmt.Errorf("read snapshot %s: %w", filepath.Base(path), err)
+		}
+		for _, entry := range entries {
+			for _, hash := range entry.blobHashes() {
+				referenced[hash] = struct{}{}
+			}
+		}
+	}
+	sets, err := s.ListSets()
+	if err != nil {
+		return 0, err
+	}
+	for _, set := range sets {
+		for _, entry := range set.Entries {
+			for _, hash := range entry.blobHashes() {
+				referenced[hash] = struct{}{}
+			}
+		}
+	}
+
+	removed := 0
+	for _, dirEntry := range dirEntries {
+		if _, ok := referenced[dirEntry.Name()]; ok || !validHash(dirEntry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.Directory, blobsDirName, dirEntry.Name())); err != nil {
+			return removed, fmt.Errorf("remove blob: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (s Store) blobPath(hash string) string {
+	return filepath.Join(s.Directory, blobsDirName, hash)
+}
+
+func validHash(hash string) bool {
+	digest, ok := strings.CutPrefix(hash, "sha256-")
+	if !ok || len(digest) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
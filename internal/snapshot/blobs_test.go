@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStorePutBlobDeduplicates(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	first, err := store.PutBlob([]byte("// comment\n{}\n"))
+	if err != nil {
+		t.Fatalf("put blob: %v", err)
+	}
+	second, err := store.PutBlob([]byte("// comment\n{}\n"))
+	if err != nil {
+		t.Fatalf("put blob: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected identical hashes, got %s and %s", first, second)
+	}
+	data, err := store.Blob(first)
+	if err != nil {
+		t.Fatalf("blob: %v", err)
+	}
+	if string(data) != "// comment\n{}\n" {
+		t.Fatalf("unexpected blob content %q", string(data))
+	}
+
+	if err := os.WriteFile(store.blobPath(first), []byte("tampered"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := store.Blob(first); !errors.Is(err, ErrBlobCorrupt) {
+		t.Fatalf("expected ErrBlobCorrupt, got %v", err)
+	}
+	if _, err := store.Blob("sha256-missing"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+}
+
+func TestStoreSaveCapturesConfigBackup(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.jsonc")
+	content := "{\n  // keep me\n  \"plugin\": [\"alpha@1.0.0\"]\n}\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	store := Store{Directory: filepath.Join(root, "snapshots")}
+	saved, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entry, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if saved.ID == "" || saved.ID != entry.ID || saved.ConfigHash != entry.ConfigHash {
+		t.Fatalf("expected Save to return the stored entry, got %#v and %#v", saved, entry)
+	}
+	if entry.ConfigHash != HashBytes([]byte(content)) {
+		t.Fatalf("expected config hash, got %q", entry.ConfigHash)
+	}
+	data, err := store.Blob(entry.ConfigHash)
+	if err != nil {
+		t.Fatalf("blob: %v", err)
+	}
+	if string(data) != content {
+		t.Fatalf("expected byte-exact backup, got %q", string(data))
+	}
+}
+
+func TestStorePruneRemovesUnreferencedBlobs(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	store := Store{Directory: filepath.Join(root, "snapshots")}
+	hashes := []string{}
+	for i, spec := range []string{"alpha@1.0.0", "alpha@2.0.0"} {
+		content := `{"plugin": ["` + spec + `"]}`
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		_, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: spec, ConfigPath: configPath, Timestamp: time.Now().Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		hashes = append(hashes, HashBytes([]byte(content)))
+	}
+
+	if _, err := store.Prune(Retention{KeepLast: 1}, time.Now(), false); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if _, err := store.Blob(hashes[0]); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expected pruned blob to be removed, got %v", err)
+	}
+	if _, err := store.Blob(hashes[1]); err != nil {
+		t.Fatalf("expected kept blob, got %v", err)
+	}
+}
+
+func TestStoreSaveRemovesBlobsDroppedByRetention(t *testing.T) {
+	root := t.TempDir()
+	configPath := filepath.Join(root, "opencode.json")
+	store := Store{Directory: filepath.Join(root, "snapshots"), Retention: Retention{KeepLast: 1}}
+	hashes := []string{}
+	for i, spec := range []string{"alpha@1.0.0", "alpha@2.0.0"} {
+		content := `{"plugin": ["` + spec + `"]}`
+		if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		_, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: spec, ConfigPath: configPath, Timestamp: time.Now().Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		hashes = append(hashes, HashBytes([]byte(content)))
+	}
+
+	if _, err := store.Blob(hashes[0]); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expected blob of the dropped entry to be removed, got %v", err)
+	}
+	if _, err := store.Blob(hashes[1]); err != nil {
+		t.Fatalf("expected kept blob, got %v", err)
+	}
+}
+
+func TestStoreSaveCapturesLocalPluginFile(t *testing.T) {
+	root := t.TempDir()
+	pluginPath := filepath.Join(root, "plugin", "tool.ts")
+	if err := os.MkdirAll(filepath.Dir(pluginPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	content := "export default {}\n"
+	if err := os.WriteFile(pluginPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+
+	store := Store{Directory: filepath.Join(root, "snapshots")}
+	if _, err := store.Save(Entry{PluginName: "tool", PreviousSpec: pluginPath, Source: "local", LocalPath: pluginPath}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entry, err := store.Latest("tool")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if entry.LocalHash != HashBytes([]byte(content)) || entry.ConfigHash != "" {
+		t.Fatalf("unexpected hashes: %+v", entry)
+	}
+
+	if _, err := store.Prune(Retention{KeepLast: 1}, time.Now(), false); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	data, err := store.Blob(entry.LocalHash)
+	if err != nil {
+		t.Fatalf("expected local copy to survive pruning, got %v", err)
+	}
+	if string(data) != content {
+		t.Fatalf("unexpected local copy %q", string(data))
+	}
+}
@@ -7,6 +7,20 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 	// ErrSnapshotNotFound indicates no snapshot exists for a plugin.
 	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrAmbiguousSnapshot indicates a snapshot reference matches several entries.
+	ErrAmbiguousSnapshot = errors.New("ambiguous snapshot reference")
+	// ErrSetExists indicates a named snapshot already exists.
+	ErrSetExists = errors.New("snapshot name already exists")
+	// ErrInvalidRetention indicates the retention config could not be parsed.
+	ErrInvalidRetention = errors.New("invalid retention config")
+	// ErrBlobNotFound indicates a config backup is missing from the store.
+	ErrBlobNotFound = errors.New("config backup not found")
+	// ErrBlobCorrupt indicates a config backup no longer matches its hash.
+	ErrBlobCorrupt = errors.New("config backup is corrupt")
+	// ErrLocked indicates another process held the store lock for too long.
+	ErrLocked = errors.New("snapshot store is locked")
+	// ErrCorruptSnapshot indicates a snapshot file could not be parsed.
+	ErrCorruptSnapshot = errors.New("snapshot file is corrupt")
 	// ErrInvalidSnapshotDir indicates the snapshot directory is invalid.
 	ErrInvalidSnapshotDir = errors.New("invalid snapshot directory")
 )
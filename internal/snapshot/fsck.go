@@ -0,0 +1,219 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+)
+
+// Problem is one inconsistency found by Fsck.
+type Problem struct {
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Repaired bool   `json:"repaired,omitempty"`
+}
+
+// FsckResult summarizes a store check.
+type FsckResult struct {
+	Files    int       `json:"files"`
+	Entries  int       `json:"entries"`
+	Problems []Problem `json:"problems"`
+}
+
+// Unrepaired returns the number of problems left in the store.
+func (r FsckResult) Unrepaired() int {
+	count := 0
+	for _, problem := range r.Problems {
+		if !problem.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// Fsck validates every history file, named snapshot and config backup in the
+// store. With repair set it holds the lock, salvages corrupt history files and
+// removes leftover temporary files.
+func (s Store) Fsck(repair bool) (FsckResult, error) {
+	result := FsckResult{Problems: []Problem{}}
+	if s.Directory == "" {
+		return result, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if _, err := os.Stat(s.Directory); err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	if !repair {
+		return result, s.fsck(&result, false)
+	}
+	return result, s.withLock(func() error {
+		return s.fsck(&result, true)
+	})
+}
+
+func (s Store) fsck(result *FsckResult, repair bool) error {
+	add := func(path string, repaired bool, format string, args ...any) {
+		result.Problems = append(result.Problems, Problem{Path: path, Message: fmt.Sprintf(format, args...), Repaired: repaired})
+	}
+
+	files, err := s.pluginFiles()
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		result.Files++
+		entries, err := readEntries(path)
+		if errors.Is(err, ErrCorruptSnapshot) {
+			if !repair {
+				add(path, false, "corrupt history; %d entr(ies) can be salvaged", len(entries))
+				continue
+			}
+			salvaged, aside, err := s.recoverEntries(path)
+			if err != nil {
+				return err
+			}
+			add(path, true, "corrupt history; kept %d entr(ies), moved original to %s", len(salvaged), filepath.Base(aside))
+			entries = salvaged
+		} else if err != nil {
+			return fmt.Errorf("read snapshot %s: %w", filepath.Base(path), err)
+		}
+		result.Entries += len(entries)
+		s.checkEntries(path, entries, add)
+	}
+
+	if err := s.checkSets(result, add); err != nil {
+		return err
+	}
+	if err := s.checkBlobs(add); err != nil {
+		return err
+	}
+	return s.checkTempFiles(repair, add)
+}
+
+func (s Store) checkEntries(path string, entries []Entry, add func(string, bool, string, ...any)) {
+	seen := map[string]struct{}{}
+	for i, entry := range entries {
+		switch {
+		case entry.PluginName == "":
+			add(path, false, "entry %d has no plugin name", i)
+			continue
+		case s.entryPath(entry.PluginName) != path:
+			add(path, false, "entry %d belongs to %s", i, entry.PluginName)
+		}
+		if entry.Timestamp.IsZero() {
+			add(path, false, "entry %d has no timestamp", i)
+		}
+		id := entry.ID
+		if id == "" {
+			id = entryID(entry)
+		}
+		if _, ok := seen[id]; ok {
+			add(path, false, "duplicate entry id %s", id)
+		}
+		seen[id] = struct{}{}
+		for _, hash := range entry.blobHashes() {
+			if _, err := s.Blob(hash); err != nil {
+				add(path, false, "entry %s: %v", id, err)
+			}
+		}
+	}
+}
+
+func (s Store) checkSets(result *FsckResult, add func(string, bool, string, ...any)) error {
+	dir := filepath.Join(s.Directory, setsDirName)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read snapshot dir: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		result.Files++
+		set, err := readSet(path)
+		if err != nil {
+			add(path, false, "corrupt named snapshot: %v", err)
+			continue
+		}
+		if s.setPath(set.Name) != path {
+			add(path, false, "named snapshot is called %q", set.Name)
+		}
+		for _, entry := range set.Entries {
+			for _, hash := range entry.blobHashes() {
+				if _, err := s.Blob(hash); err != nil {
+					add(path, false, "%s: %v", entry.PluginName, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s Store) checkBlobs(add func(string, bool, string, ...any)) error {
+	dir := filepath.Join(s.Directory, blobsDirName)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read blob dir: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if !validHash(name) {
+			add(path, false, "unexpected file in blob store")
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read blob: %w", err)
+		}
+		if HashBytes(data) != name {
+			add(path, false, "config backup does not match its hash")
+		}
+	}
+	return nil
+}
+
+// checkTempFiles reports files left behind by interrupted writes.
+func (s Store) checkTempFiles(repair bool, add func(string, bool, string, ...any)) error {
+	paths := []string{}
+	for _, dir := range []string{s.Directory, filepath.Join(s.Directory, setsDirName), filepath.Join(s.Directory, blobsDirName)} {
+		// .snapshot-* is the prefix older versions used for their temporary files.
+		for _, pattern := range []string{atomicfile.TempPattern, ".snapshot-*"} {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return err
+			}
+			paths = append(paths, matches...)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if !repair {
+			add(path, false, "leftover temporary file")
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		add(path, true, "removed leftover temporary file")
+	}
+	return nil
+}
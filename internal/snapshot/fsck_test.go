@@ -0,0 +1,71 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFsckReportsAndRepairs(t *testing.T) {
+	dir := t.TempDir()
+	store := Store{Directory: dir}
+	configPath := filepath.Join(t.TempDir(), "opencode.json")
+	if err := os.WriteFile(configPath, []byte(`{"plugin":["beta@1.0.0"]}`), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := store.Save(Entry{PluginName: "beta", PreviousSpec: "beta@1.0.0", ConfigPath: configPath, Timestamp: time.Now()}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	clean, err := store.Fsck(false)
+	if err != nil {
+		t.Fatalf("fsck: %v", err)
+	}
+	if len(clean.Problems) != 0 || clean.Entries != 1 {
+		t.Fatalf("expected clean store, got %#v", clean)
+	}
+
+	corrupt := `[{"timestamp":"2026-03-01T12:00:00Z","pluginName":"alpha","previousSpec":"alpha@1.0.0"}, {`
+	if err := os.WriteFile(filepath.Join(dir, "alpha.json"), []byte(corrupt), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".snapshot-123"), []byte("partial"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	entry, _ := store.Latest("beta")
+	if err := os.WriteFile(store.blobPath(entry.ConfigHash), []byte("edited"), 0o600); err != nil {
+		t.Fatalf("write blob: %v", err)
+	}
+
+	result, err := store.Fsck(false)
+	if err != nil {
+		t.Fatalf("fsck: %v", err)
+	}
+	if len(result.Problems) != 4 || result.Unrepaired() != 4 {
+		t.Fatalf("expected four problems, got %#v", result.Problems)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "alpha.json")); err != nil {
+		t.Fatalf("expected check without repair to leave files, got %v", err)
+	}
+
+	repaired, err := store.Fsck(true)
+	if err != nil {
+		t.Fatalf("fsck repair: %v", err)
+	}
+	if repaired.Unrepaired() != 2 {
+		t.Fatalf("expected only blob problems left, got %#v", repaired.Problems)
+	}
+	for _, problem := range repaired.Problems {
+		if !problem.Repaired && !strings.Contains(problem.Message, "hash") && !strings.Contains(problem.Message, "corrupt") {
+			t.Fatalf("unexpected unrepaired problem %#v", problem)
+		}
+	}
+	history, err := store.History("alpha")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("expected salvaged entry, got %#v %v", history, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".snapshot-123")); !os.IsNotExist(err) {
+		t.Fatalf("expected temp file removed, got %v", err)
+	}
+}
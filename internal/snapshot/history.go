@@ -0,0 +1,163 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the formats accepted by Find for point-in-time lookups.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// History returns every snapshot entry for a plugin, oldest first. Entries
+// written before ids existed are given a stable derived id.
+func (s Store) History(pluginName string) ([]Entry, error) {
+	if s.Directory == "" {
+		return nil, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if pluginName == "" {
+		return nil, fmt.Errorf("plugin name is required")
+	}
+
+	entries, err := s.loadEntries(s.entryPath(pluginName), false)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, ErrSnapshotNotFound
+	}
+	for i := range entries {
+		if entries[i].ID == "" {
+			entries[i].ID = entryID(entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// Find returns the entry referenced by an id (or unique id prefix), a
+// timestamp, or a version. A timestamp selects the newest entry taken at or
+// before that time; a version selects the newest entry whose previous spec or
+// installed version matches it.
+func (s Store) Find(pluginName string, ref string) (Entry, error) {
+	entries, err := s.History(pluginName)
+	if err != nil {
+		return Entry{}, err
+	}
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return entries[len(entries)-1], nil
+	}
+
+	for _, entry := range entries {
+		if entry.ID == ref {
+			return entry, nil
+		}
+	}
+
+	if at, ok := parseTimestamp(ref); ok {
+		for i := len(entries) - 1; i >= 0; i-- {
+			if !entries[i].Timestamp.Truncate(time.Second).After(at) {
+				return entries[i], nil
+			}
+		}
+		return Entry{}, fmt.Errorf("%w: no entry at or before %s", ErrSnapshotNotFound, ref)
+	}
+
+	if isHex(ref) {
+		matches := []Entry{}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.ID, ref) {
+				matches = append(matches, entry)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+		if len(matches) > 1 {
+			return Entry{}, fmt.Errorf("%w: %s matches %d entries", ErrAmbiguousSnapshot, ref, len(matches))
+		}
+	}
+
+	version := strings.TrimPrefix(ref, "v")
+	for i := len(entries) - 1; i >= 0; i-- {
+		if specVersion(entries[i].PreviousSpec) == version || entries[i].PreviousInstalled == version {
+			return entries[i], nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, ref)
+}
+
+func entryID(entry Entry) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		entry.PluginName,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano),
+		entry.PreviousSpec,
+		entry.Reason,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+func parseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isHex(value string) bool {
+	if len(value) < 4 {
+		return false
+	}
+	for _, r := range value {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+func specVersion(spec string) string {
+	at := strings.LastIndex(spec, "@")
+	if at <= 0 {
+		return ""
+	}
+	return spec[at+1:]
+}
+
+// Entries returns the entries of every plugin history in the store.
+func (s Store) Entries() ([]Entry, error) {
+	files, err := s.pluginFiles()
+	if err != nil {
+		return nil, err
+	}
+	all := []Entry{}
+	for _, path := range files {
+		entries, err := s.loadEntries(path, false)
+		if err != nil {
+			return nil, fmt.Errorf("read snapshot %s: %w", filepath.Base(path), err)
+		}
+		for _, entry := range entries {
+			if entry.ID == "" {
+				entry.ID = entryID(entry)
+			}
+			all = append(all, entry)
+		}
+	}
+	return all, nil
+}
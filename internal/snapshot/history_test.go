@@ -0,0 +1,116 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func saveHistory(t *testing.T, store Store) []Entry {
+	t.Helper()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	specs := []string{"alpha@1.0.0", "alpha@1.1.0", "alpha@2.0.0"}
+	for i, spec := range specs {
+		_, err := store.Save(Entry{
+			PluginName:        "alpha",
+			PreviousSpec:      spec,
+			PreviousInstalled: spec[len("alpha@"):],
+			Reason:            "upgrade",
+			Timestamp:         base.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	return entries
+}
+
+func TestStoreHistoryAssignsIDs(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	entries := saveHistory(t, store)
+	if len(entries) != 3 {
+		t.Fatalf("expected three entries, got %d", len(entries))
+	}
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		if len(entry.ID) != 8 {
+			t.Fatalf("expected 8 character id, got %q", entry.ID)
+		}
+		seen[entry.ID] = struct{}{}
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected unique ids, got %#v", entries)
+	}
+	if entries[0].PreviousSpec != "alpha@1.0.0" {
+		t.Fatalf("expected oldest first, got %s", entries[0].PreviousSpec)
+	}
+}
+
+func TestStoreHistoryDerivesLegacyIDs(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `[{"timestamp":"2026-03-01T12:00:00Z","pluginName":"alpha","previousSpec":"alpha@1.0.0","reason":"upgrade"}]`
+	if err := os.WriteFile(filepath.Join(dir, "alpha.json"), []byte(legacy), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	store := Store{Directory: dir}
+
+	first, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	second, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if first[0].ID == "" || first[0].ID != second[0].ID {
+		t.Fatalf("expected stable derived id, got %q and %q", first[0].ID, second[0].ID)
+	}
+}
+
+func TestStoreFind(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	entries := saveHistory(t, store)
+
+	cases := []struct {
+		ref  string
+		want string
+	}{
+		{"", "alpha@2.0.0"},
+		{entries[0].ID, "alpha@1.0.0"},
+		{entries[1].ID[:6], "alpha@1.1.0"},
+		{"1.1.0", "alpha@1.1.0"},
+		{"v1.0.0", "alpha@1.0.0"},
+		{"2026-03-01T13:30:00Z", "alpha@1.1.0"},
+		{"2026-03-01T14:00:00Z", "alpha@2.0.0"},
+	}
+	for _, tc := range cases {
+		got, err := store.Find("alpha", tc.ref)
+		if err != nil {
+			t.Fatalf("find %q: %v", tc.ref, err)
+		}
+		if got.PreviousSpec != tc.want {
+			t.Fatalf("find %q: expected %s, got %s", tc.ref, tc.want, got.PreviousSpec)
+		}
+	}
+}
+
+func TestStoreFindMissing(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	saveHistory(t, store)
+
+	if _, err := store.Find("alpha", "9.9.9"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound for unknown version, got %v", err)
+	}
+	if _, err := store.Find("alpha", "2020-01-01T00:00:00Z"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound before first entry, got %v", err)
+	}
+	if _, err := store.Find("beta", ""); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound for unknown plugin, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	lockFileName      = ".lock"
+	breakLockFileName = ".lock.break"
+)
+
+// Lock timing. A lock older than staleLockAge is assumed to belong to a
+// process that died and is taken over.
+var (
+	lockTimeout  = 10 * time.Second
+	lockRetry    = 20 * time.Millisecond
+	staleLockAge = 2 * time.Minute
+)
+
+// lockSeq keeps the tokens of locks taken by one process unique.
+var lockSeq atomic.Int64
+
+// lock serializes writers to the store with an exclusive lock file. The
+// returned function releases it.
+func (s Store) lock() (func(), error) {
+	if s.Directory == "" {
+		return nil, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if err := os.MkdirAll(s.Directory, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot dir: %w", err)
+	}
+
+	path := filepath.Join(s.Directory, lockFileName)
+	token := fmt.Sprintf("%d %d %d", os.Getpid(), time.Now().UnixNano(), lockSeq.Add(1))
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, _ = file.WriteString(token + "\n")
+			_ = file.Close()
+			return func() { releaseLock(path, token) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock snapshot dir: %w", err)
+		}
+		if isStale(path) && breakStaleLock(s.Directory, path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// breakStaleLock removes a stale lock and reports whether it did. Only the
+// holder of the break lock may remove it, and it checks again that the lock
+// is stale: another process may have broken it and taken a fresh lock since
+// it was first seen.
+func breakStaleLock(dir string, path string) bool {
+	breakPath := filepath.Join(dir, breakLockFileName)
+	file, err := os.OpenFile(breakPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		// A break lock is only held for a moment, so an old one was left
+		// by a process that died while breaking.
+		if isStale(breakPath) {
+			_ = os.Remove(breakPath)
+		}
+		return false
+	}
+	_ = file.Close()
+	defer os.Remove(breakPath)
+
+	if !isStale(path) {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// releaseLock removes the lock file if it still holds token, so a process
+// whose lock was broken as stale does not release the lock of the next one.
+func releaseLock(path string, token string) {
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) != token {
+		return
+	}
+	_ = os.Remove(path)
+}
+
+func isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > staleLockAge
+}
+
+// withLock runs fn while holding the store lock.
+func (s Store) withLock(fn func() error) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
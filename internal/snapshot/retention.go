@@ -0,0 +1,250 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionFileName is the retention config stored in the snapshot directory.
+// It starts with a dot so it cannot collide with the history file of a plugin
+// named "retention".
+const RetentionFileName = ".retention.json"
+
+// legacyRetentionFileName is the retention config older versions kept beside
+// the snapshot directory.
+const legacyRetentionFileName = "retention.json"
+
+// DefaultRetention applies when no retention config exists.
+var DefaultRetention = Retention{KeepLast: 50}
+
+// Retention decides which per-plugin entries survive pruning. An entry is kept
+// when it is one of the last KeepLast entries, newer than MaxAge, or part of a
+// named snapshot. The newest entry, and the newest link entry of each config,
+// are always kept. Zero values disable a rule; when both are zero nothing is
+// pruned.
+type Retention struct {
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+type retentionFile struct {
+	KeepLast int    `json:"keepLast"`
+	MaxAge   string `json:"maxAge"`
+}
+
+// PruneResult reports the entries removed per plugin.
+type PruneResult struct {
+	Removed map[string][]Entry
+}
+
+// Total returns the number of removed entries.
+func (r PruneResult) Total() int {
+	total := 0
+	for _, entries := range r.Removed {
+		total += len(entries)
+	}
+	return total
+}
+
+// Open returns a store for dir using the retention config in it, or
+// DefaultRetention when none exists. A config left beside the directory by
+// older versions is still read.
+func Open(dir string) (Store, error) {
+	store := Store{Directory: dir, Retention: DefaultRetention}
+	if dir == "" {
+		return store, nil
+	}
+	retention, err := LoadRetention(RetentionPath(dir))
+	if os.IsNotExist(err) {
+		retention, err = LoadRetention(filepath.Join(filepath.Dir(filepath.Clean(dir)), legacyRetentionFileName))
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return Store{}, err
+	}
+	store.Retention = retention
+	return store, nil
+}
+
+// RetentionPath returns the retention config path for a snapshot directory.
+func RetentionPath(dir string) string {
+	return filepath.Join(dir, RetentionFileName)
+}
+
+// LoadRetention reads a retention config file.
+func LoadRetention(path string) (Retention, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Retention{}, err
+	}
+	var file retentionFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return Retention{}, fmt.Errorf("%w: %s: %v", ErrInvalidRetention, path, err)
+	}
+	if file.KeepLast < 0 {
+		return Retention{}, fmt.Errorf("%w: %s: keepLast must not be negative", ErrInvalidRetention, path)
+	}
+	retention := Retention{KeepLast: file.KeepLast}
+	if file.MaxAge != "" {
+		age, err := ParseAge(file.MaxAge)
+		if err != nil {
+			return Retention{}, fmt.Errorf("%w: %s: %v", ErrInvalidRetention, path, err)
+		}
+		retention.MaxAge = age
+	}
+	return retention, nil
+}
+
+// ParseAge parses a duration, also accepting whole days such as "30d".
+func ParseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// Enabled reports whether the retention prunes anything.
+func (r Retention) Enabled() bool {
+	return r.KeepLast > 0 || r.MaxAge > 0
+}
+
+// Apply splits entries, sorted oldest first, into kept and removed entries.
+func (r Retention) Apply(entries []Entry, now time.Time) ([]Entry, []Entry) {
+	if !r.Enabled() || len(entries) == 0 {
+		return entries, nil
+	}
+	links := newestLinks(entries)
+	kept := []Entry{}
+	removed := []Entry{}
+	for i, entry := range entries {
+		fromEnd := len(entries) - i
+		switch {
+		case fromEnd == 1,
+			entry.Set != "",
+			links[i],
+			r.KeepLast > 0 && fromEnd <= r.KeepLast,
+			r.MaxAge > 0 && now.Sub(entry.Timestamp) < r.MaxAge:
+			kept = append(kept, entry)
+		default:
+			removed = append(removed, entry)
+		}
+	}
+	return kept, removed
+}
+
+// newestLinks marks the newest link entry of each config. unlink reads it to
+// put back the spec a plugin had before it was linked.
+func newestLinks(entries []Entry) map[int]bool {
+	seen := map[string]bool{}
+	marked := map[int]bool{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Reason != "link" {
+			continue
+		}
+		key := entries[i].ConfigRoot + "\x00" + entries[i].ConfigPath
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		marked[i] = true
+	}
+	return marked
+}
+
+// Prune applies retention to every plugin history in the store. Named
+// snapshot sets are never removed. With dryRun set nothing is written.
+func (s Store) Prune(retention Retention, now time.Time, dryRun bool) (PruneResult, error) {
+	result := PruneResult{Removed: map[string][]Entry{}}
+	if s.Directory == "" {
+		return result, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if dryRun {
+		return result, s.prune(retention, now, dryRun, result)
+	}
+	return result, s.withLock(func() error {
+		return s.prune(retention, now, dryRun, result)
+	})
+}
+
+func (s Store) prune(retention Retention, now time.Time, dryRun bool, result PruneResult) error {
+	files, err := s.pluginFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		var entries []Entry
+		if dryRun {
+			entries, err = readEntries(path)
+			if errors.Is(err, ErrCorruptSnapshot) {
+				err = nil
+			}
+		} else {
+			entries, err = s.loadEntries(path, true)
+		}
+		if err != nil {
+			return fmt.Errorf("read snapshot %s: %w", filepath.Base(path), err)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		})
+		kept, removed := retention.Apply(entries, now)
+		if len(removed) == 0 {
+			continue
+		}
+		pluginName := removed[0].PluginName
+		result.Removed[pluginName] = removed
+		if dryRun {
+			continue
+		}
+		if err := writeEntries(path, kept); err != nil {
+			return fmt.Errorf("write snapshot: %w", err)
+		}
+	}
+	if dryRun || result.Total() == 0 {
+		return nil
+	}
+	_, err = s.collectGarbage()
+	return err
+}
+
+// pluginFiles returns the per-plugin history files in the store.
+func (s Store) pluginFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.Directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+	files := []string{}
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		files = append(files, filepath.Join(s.Directory, name))
+	}
+	return files, nil
+}
@@ -0,0 +1,195 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func retentionEntries(now time.Time) []Entry {
+	return []Entry{
+		{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Timestamp: now.Add(-40 * 24 * time.Hour), Set: "release"},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.1.0", Timestamp: now.Add(-30 * 24 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.2.0", Timestamp: now.Add(-20 * 24 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.3.0", Timestamp: now.Add(-2 * 24 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.4.0", Timestamp: now.Add(-time.Hour)},
+	}
+}
+
+func TestRetentionApply(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name      string
+		retention Retention
+		kept      int
+	}{
+		{"disabled", Retention{}, 5},
+		{"keep last two plus named", Retention{KeepLast: 2}, 3},
+		{"max age plus named", Retention{MaxAge: 7 * 24 * time.Hour}, 3},
+		{"union of rules", Retention{KeepLast: 3, MaxAge: 24 * time.Hour}, 4},
+		{"newest always kept", Retention{MaxAge: time.Minute}, 2},
+	}
+	for _, tc := range cases {
+		kept, removed := tc.retention.Apply(retentionEntries(now), now)
+		if len(kept) != tc.kept || len(kept)+len(removed) != 5 {
+			t.Fatalf("%s: expected %d kept, got %d kept and %d removed", tc.name, tc.kept, len(kept), len(removed))
+		}
+		if kept[0].Set != "release" {
+			t.Fatalf("%s: expected named entry to be kept, got %#v", tc.name, kept[0])
+		}
+	}
+}
+
+func TestRetentionKeepsNewestLinkEntryPerConfig(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{PluginName: "alpha", PreviousSpec: "alpha@0.9.0", Reason: "link", ConfigPath: "opencode.json", Timestamp: now.Add(-5 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Reason: "link", ConfigPath: "opencode.json", Timestamp: now.Add(-4 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Reason: "link", ConfigPath: "other/opencode.json", Timestamp: now.Add(-3 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.1.0", Reason: "upgrade", ConfigPath: "opencode.json", Timestamp: now.Add(-2 * time.Hour)},
+		{PluginName: "alpha", PreviousSpec: "alpha@1.2.0", Reason: "upgrade", ConfigPath: "opencode.json", Timestamp: now.Add(-time.Hour)},
+	}
+	kept, removed := Retention{KeepLast: 1}.Apply(entries, now)
+	if len(kept) != 3 || len(removed) != 2 {
+		t.Fatalf("expected three kept and two removed, got %#v", kept)
+	}
+	if kept[0].Timestamp != entries[1].Timestamp || kept[1].Timestamp != entries[2].Timestamp {
+		t.Fatalf("expected the newest link entry of each config to be kept, got %#v", kept)
+	}
+}
+
+func TestPluginNamedRetentionKeepsConfigAndHistoryApart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(RetentionPath(dir), []byte(`{"keepLast": 5}`), 0o600); err != nil {
+		t.Fatalf("write retention: %v", err)
+	}
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := store.Save(Entry{PluginName: "retention", PreviousSpec: "retention@1.0.0"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("expected the retention config to survive, got %v", err)
+	}
+	if store.Retention.KeepLast != 5 {
+		t.Fatalf("unexpected retention: %#v", store.Retention)
+	}
+	entries, err := store.Entries()
+	if err != nil || len(entries) != 1 || entries[0].PluginName != "retention" {
+		t.Fatalf("expected the plugin's history to be listed, got %v %v", entries, err)
+	}
+}
+
+func TestStoreSaveAppliesRetention(t *testing.T) {
+	store := Store{Directory: t.TempDir(), Retention: Retention{KeepLast: 2}}
+	for i := 0; i < 4; i++ {
+		_, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Timestamp: time.Now().Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries after retention, got %d", len(entries))
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	now := time.Now()
+	store := Store{Directory: t.TempDir()}
+	for _, entry := range retentionEntries(now) {
+		if _, err := store.Save(entry); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	if err := store.SaveSet(Set{Name: "release"}); err != nil {
+		t.Fatalf("save set: %v", err)
+	}
+
+	result, err := store.Prune(Retention{KeepLast: 1}, now, true)
+	if err != nil {
+		t.Fatalf("prune dry run: %v", err)
+	}
+	if result.Total() != 3 {
+		t.Fatalf("expected three entries to prune, got %d", result.Total())
+	}
+	if entries, _ := store.History("alpha"); len(entries) != 5 {
+		t.Fatalf("expected dry run to keep all entries, got %d", len(entries))
+	}
+
+	if _, err := store.Prune(Retention{KeepLast: 1}, now, false); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 2 || entries[1].PreviousSpec != "alpha@1.4.0" {
+		t.Fatalf("expected named and newest entries, got %#v", entries)
+	}
+	if _, err := store.LoadSet("release"); err != nil {
+		t.Fatalf("expected named set to survive prune: %v", err)
+	}
+}
+
+func TestOpenLoadsRetention(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "snapshots")
+
+	store, err := Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if store.Retention != DefaultRetention {
+		t.Fatalf("expected default retention, got %#v", store.Retention)
+	}
+
+	legacy := filepath.Join(root, legacyRetentionFileName)
+	if err := os.WriteFile(legacy, []byte(`{"keepLast": 7}`), 0o600); err != nil {
+		t.Fatalf("write retention: %v", err)
+	}
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if store.Retention.KeepLast != 7 {
+		t.Fatalf("expected the legacy config beside the directory, got %#v", store.Retention)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(RetentionPath(dir), []byte(`{"keepLast": 5, "maxAge": "30d"}`), 0o600); err != nil {
+		t.Fatalf("write retention: %v", err)
+	}
+	store, err = Open(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if store.Retention.KeepLast != 5 || store.Retention.MaxAge != 30*24*time.Hour {
+		t.Fatalf("unexpected retention: %#v", store.Retention)
+	}
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entries, err := store.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected the retention config not to be read as history, got %v %v", entries, err)
+	}
+
+	if err := os.WriteFile(RetentionPath(dir), []byte(`{"maxAge": "soon"}`), 0o600); err != nil {
+		t.Fatalf("write retention: %v", err)
+	}
+	if _, err := Open(dir); !errors.Is(err, ErrInvalidRetention) {
+		t.Fatalf("expected ErrInvalidRetention, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Root names recorded in Entry.ConfigRoot.
+const (
+	RootProject   = "project"
+	RootGlobal    = "global"
+	RootConfigDir = "configDir"
+)
+
+// Roots are the directories config paths are recorded against, so entries
+// still resolve after a project moves or on a machine with a different home
+// directory. Remaps are applied when entries are resolved.
+type Roots struct {
+	Project   string
+	Global    string
+	ConfigDir string
+	Remaps    []Remap
+}
+
+// Remap rewrites config paths that start with Old to start with New.
+type Remap struct {
+	Old string
+	New string
+}
+
+// ParseRemap parses an old=new remap.
+func ParseRemap(value string) (Remap, error) {
+	oldPath, newPath, ok := strings.Cut(value, "=")
+	oldPath = strings.TrimSpace(oldPath)
+	newPath = strings.TrimSpace(newPath)
+	if !ok || oldPath == "" || newPath == "" {
+		return Remap{}, fmt.Errorf("invalid remap %q: expected old=new", value)
+	}
+	return Remap{Old: filepath.Clean(oldPath), New: filepath.Clean(newPath)}, nil
+}
+
+// Resolve returns the config path of an entry on this machine. A remap that
+// matches the recorded absolute path wins, then the recorded path itself while
+// it still exists. Only a missing path is rebuilt from its root.
+func (r Roots) Resolve(entry Entry) string {
+	if entry.ConfigPath != "" {
+		if remapped, ok := r.remap(entry.ConfigPath); ok {
+			return remapped
+		}
+		if fileExists(entry.ConfigPath) {
+			return entry.ConfigPath
+		}
+	}
+	root := r.dir(entry.ConfigRoot)
+	if root == "" || entry.ConfigRel == "" {
+		return entry.ConfigPath
+	}
+	resolved := filepath.Join(root, filepath.FromSlash(entry.ConfigRel))
+	if remapped, ok := r.remap(resolved); ok {
+		return remapped
+	}
+	return resolved
+}
+
+// relativize records the entry's config path against the most specific root
+// that contains it. Paths outside every root stay absolute only.
+func (r Roots) relativize(entry Entry) Entry {
+	// A path like $OPENCODE_CONFIG_CONTENT names config held in the
+	// environment, which has no location to record.
+	if entry.ConfigPath == "" || entry.ConfigRoot != "" || strings.HasPrefix(entry.ConfigPath, "$") {
+		return entry
+	}
+	path, err := filepath.Abs(entry.ConfigPath)
+	if err != nil {
+		return entry
+	}
+	bestLen := -1
+	for _, name := range []string{RootConfigDir, RootProject, RootGlobal} {
+		root := r.dir(name)
+		if root == "" {
+			continue
+		}
+		root, err := filepath.Abs(root)
+		if err != nil || len(root) <= bestLen {
+			continue
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		bestLen = len(root)
+		entry.ConfigRoot = name
+		entry.ConfigRel = filepath.ToSlash(rel)
+	}
+	return entry
+}
+
+func (r Roots) dir(name string) string {
+	switch name {
+	case RootProject:
+		return r.Project
+	case RootGlobal:
+		return r.Global
+	case RootConfigDir:
+		return r.ConfigDir
+	}
+	return ""
+}
+
+func (r Roots) remap(path string) (string, bool) {
+	path = filepath.Clean(path)
+	for _, remap := range r.Remaps {
+		if path == remap.Old {
+			return remap.New, true
+		}
+		prefix := remap.Old + string(filepath.Separator)
+		if strings.HasPrefix(path, prefix) {
+			return filepath.Join(remap.New, strings.TrimPrefix(path, prefix)), true
+		}
+	}
+	return "", false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
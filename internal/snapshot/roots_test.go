@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRootsRelativizePicksMostSpecificRoot(t *testing.T) {
+	home := t.TempDir()
+	roots := Roots{
+		Project: home,
+		Global:  filepath.Join(home, ".config", "opencode"),
+	}
+
+	entry := roots.relativize(Entry{ConfigPath: filepath.Join(home, ".config", "opencode", "opencode.json")})
+	if entry.ConfigRoot != RootGlobal || entry.ConfigRel != "opencode.json" {
+		t.Fatalf("expected global-relative path, got %s %s", entry.ConfigRoot, entry.ConfigRel)
+	}
+
+	entry = roots.relativize(Entry{ConfigPath: filepath.Join(home, "app", "opencode.json")})
+	if entry.ConfigRoot != RootProject || entry.ConfigRel != "app/opencode.json" {
+		t.Fatalf("expected project-relative path, got %s %s", entry.ConfigRoot, entry.ConfigRel)
+	}
+
+	outside := filepath.Join(t.TempDir(), "opencode.json")
+	entry = roots.relativize(Entry{ConfigPath: outside})
+	if entry.ConfigRoot != "" || entry.ConfigRel != "" {
+		t.Fatalf("expected path outside roots to stay absolute, got %s %s", entry.ConfigRoot, entry.ConfigRel)
+	}
+}
+
+func TestRootsResolve(t *testing.T) {
+	moved := t.TempDir()
+	config := filepath.Join(moved, "opencode.json")
+	if err := os.WriteFile(config, []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	entry := Entry{ConfigPath: "/old/machine/project/opencode.json", ConfigRoot: RootProject, ConfigRel: "opencode.json"}
+
+	if got := (Roots{Project: moved}).Resolve(entry); got != config {
+		t.Fatalf("expected %s, got %s", config, got)
+	}
+	if got := (Roots{}).Resolve(entry); got != entry.ConfigPath {
+		t.Fatalf("expected recorded path without roots, got %s", got)
+	}
+
+	remap, err := ParseRemap("/old/machine=/new/machine")
+	if err != nil {
+		t.Fatalf("parse remap: %v", err)
+	}
+	got := Roots{Project: moved, Remaps: []Remap{remap}}.Resolve(entry)
+	if got != filepath.Join("/new/machine", "project", "opencode.json") {
+		t.Fatalf("expected remap to win, got %s", got)
+	}
+}
+
+func TestRootsResolvePrefersExistingRecordedPath(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	for _, dir := range []string{first, second} {
+		if err := os.WriteFile(filepath.Join(dir, "opencode.json"), []byte(`{}`), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	recorded := filepath.Join(first, "opencode.json")
+	entry := Entry{ConfigPath: recorded, ConfigRoot: RootProject, ConfigRel: "opencode.json"}
+
+	if got := (Roots{Project: second}).Resolve(entry); got != recorded {
+		t.Fatalf("expected the recorded path %s while it exists, got %s", recorded, got)
+	}
+
+	remap, err := ParseRemap(first + "=" + second)
+	if err != nil {
+		t.Fatalf("parse remap: %v", err)
+	}
+	want := filepath.Join(second, "opencode.json")
+	if got := (Roots{Project: second, Remaps: []Remap{remap}}).Resolve(entry); got != want {
+		t.Fatalf("expected remap to %s, got %s", want, got)
+	}
+}
+
+func TestParseRemapRejectsInvalid(t *testing.T) {
+	for _, value := range []string{"", "old", "=new", "old="} {
+		if _, err := ParseRemap(value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestStoreSaveRecordsPortablePath(t *testing.T) {
+	project := t.TempDir()
+	store := Store{Directory: t.TempDir(), Roots: Roots{Project: project}}
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", ConfigPath: filepath.Join(project, "opencode.json")}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entry, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if entry.ConfigRoot != RootProject || entry.ConfigRel != "opencode.json" {
+		t.Fatalf("expected portable path, got %#v", entry)
+	}
+}
@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
+)
+
+const setsDirName = "sets"
+
+// Set is a named snapshot of every plugin captured at the same moment.
+type Set struct {
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// SaveSet writes a named set in a single file and records each member in the
+// plugin's history. Existing names are not overwritten.
+func (s Store) SaveSet(set Set) error {
+	if s.Directory == "" {
+		return fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if err := validateSetName(set.Name); err != nil {
+		return err
+	}
+	if set.Timestamp.IsZero() {
+		set.Timestamp = time.Now().UTC()
+	}
+
+	for i := range set.Entries {
+		set.Entries[i].Timestamp = set.Timestamp
+		set.Entries[i].Set = set.Name
+		if set.Entries[i].ID == "" {
+			set.Entries[i].ID = entryID(set.Entries[i])
+		}
+		set.Entries[i] = s.Roots.relativize(set.Entries[i])
+	}
+	return s.withLock(func() error {
+		for i := range set.Entries {
+			entry, err := s.capture(set.Entries[i])
+			if err != nil {
+				return err
+			}
+			set.Entries[i] = entry
+		}
+		return s.saveSet(set)
+	})
+}
+
+// saveSet writes the set file and its history entries. The caller holds the
+// lock.
+func (s Store) saveSet(set Set) error {
+	dir := filepath.Join(s.Directory, setsDirName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+	path := s.setPath(set.Name)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%w: %s", ErrSetExists, set.Name)
+	}
+
+	data, err := json.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	data = append(data, '\n')
+	if err := atomicfile.Write(path, data, 0o600); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	for _, entry := range set.Entries {
+		if err := s.save(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadSet reads a named set.
+func (s Store) LoadSet(name string) (Set, error) {
+	if s.Directory == "" {
+		return Set{}, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if err := validateSetName(name); err != nil {
+		return Set{}, err
+	}
+	set, err := readSet(s.setPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Set{}, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+		}
+		return Set{}, fmt.Errorf("read snapshot: %w", err)
+	}
+	return set, nil
+}
+
+// ListSets returns every named set, oldest first.
+func (s Store) ListSets() ([]Set, error) {
+	if s.Directory == "" {
+		return nil, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	dirEntries, err := os.ReadDir(filepath.Join(s.Directory, setsDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read snapshot dir: %w", err)
+	}
+
+	sets := []Set{}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ".json") || strings.HasPrefix(dirEntry.Name(), ".") {
+			continue
+		}
+		set, err := readSet(filepath.Join(s.Directory, setsDirName, dirEntry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read snapshot %s: %w", dirEntry.Name(), err)
+		}
+		sets = append(sets, set)
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].Timestamp.Before(sets[j].Timestamp)
+	})
+	return sets, nil
+}
+
+func (s Store) setPath(name string) string {
+	return filepath.Join(s.Directory, setsDirName, url.PathEscape(name)+".json")
+}
+
+func readSet(path string) (Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Set{}, err
+	}
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return Set{}, err
+	}
+	return set, nil
+}
+
+func validateSetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("snapshot name is required")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	return nil
+}
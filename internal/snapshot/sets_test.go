@@ -0,0 +1,64 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveSetAndList(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	set := Set{
+		Name: "pre-upgrade",
+		Entries: []Entry{
+			{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Reason: "snapshot"},
+			{PluginName: "@scope/beta", PreviousSpec: "@scope/beta@2.0.0", Reason: "snapshot"},
+		},
+	}
+	if err := store.SaveSet(set); err != nil {
+		t.Fatalf("save set: %v", err)
+	}
+	if err := store.SaveSet(Set{Name: "later", Timestamp: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("save second set: %v", err)
+	}
+
+	loaded, err := store.LoadSet("pre-upgrade")
+	if err != nil {
+		t.Fatalf("load set: %v", err)
+	}
+	if len(loaded.Entries) != 2 || loaded.Entries[0].Set != "pre-upgrade" || loaded.Entries[0].ID == "" {
+		t.Fatalf("unexpected set entries: %#v", loaded.Entries)
+	}
+
+	sets, err := store.ListSets()
+	if err != nil {
+		t.Fatalf("list sets: %v", err)
+	}
+	if len(sets) != 2 || sets[0].Name != "pre-upgrade" || sets[1].Name != "later" {
+		t.Fatalf("unexpected sets: %#v", sets)
+	}
+
+	history, err := store.History("@scope/beta")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(history) != 1 || history[0].Set != "pre-upgrade" {
+		t.Fatalf("expected set member in plugin history, got %#v", history)
+	}
+}
+
+func TestStoreSaveSetRejectsDuplicateAndInvalidNames(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	if err := store.SaveSet(Set{Name: "friday"}); err != nil {
+		t.Fatalf("save set: %v", err)
+	}
+	if err := store.SaveSet(Set{Name: "friday"}); !errors.Is(err, ErrSetExists) {
+		t.Fatalf("expected ErrSetExists, got %v", err)
+	}
+	if err := store.SaveSet(Set{Name: "../escape"}); err == nil {
+		t.Fatalf("expected invalid name error")
+	}
+	if _, err := store.LoadSet("missing"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound, got %v", err)
+	}
+}
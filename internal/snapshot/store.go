@@ -1,45 +1,92 @@
 package snapshot
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"time"
+
+	"github.com/AksharP5/Patchline/internal/atomicfile"
 )
 
 type Entry struct {
+	ID                string    `json:"id,omitempty"`
 	Timestamp         time.Time `json:"timestamp"`
 	PluginName        string    `json:"pluginName"`
 	PreviousSpec      string    `json:"previousSpec"`
+	Template          string    `json:"template,omitempty"`
 	PreviousInstalled string    `json:"previousInstalled"`
 	Source            string    `json:"source"`
 	Reason            string    `json:"reason"`
 	ConfigPath        string    `json:"configPath"`
+	ConfigRoot        string    `json:"configRoot,omitempty"`
+	ConfigRel         string    `json:"configRel,omitempty"`
+	ConfigHash        string    `json:"configHash,omitempty"`
+	LocalPath         string    `json:"localPath,omitempty"`
+	LocalHash         string    `json:"localHash,omitempty"`
+	RestoredFrom      string    `json:"restoredFrom,omitempty"`
+	Set               string    `json:"set,omitempty"`
 }
 
+// corruptSuffix marks history files that were moved aside because they could
+// not be parsed.
+const corruptSuffix = ".corrupt-"
+
 type Store struct {
 	Directory string
+	Retention Retention
+	Roots     Roots
 }
 
-func (s Store) Save(entry Entry) error {
+// Save appends an entry to its plugin history and returns it as stored, with
+// its ID, timestamp and backups filled in.
+func (s Store) Save(entry Entry) (Entry, error) {
 	if s.Directory == "" {
-		return fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+		return Entry{}, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
 	}
 	if entry.PluginName == "" {
-		return fmt.Errorf("plugin name is required")
+		return Entry{}, fmt.Errorf("plugin name is required")
 	}
 	if entry.Timestamp.IsZero() {
 		entry.Timestamp = time.Now().UTC()
 	}
-	if err := os.MkdirAll(s.Directory, 0o755); err != nil {
-		return fmt.Errorf("create snapshot dir: %w", err)
+	if entry.ID == "" {
+		entry.ID = entryID(entry)
 	}
+	entry = s.Roots.relativize(entry)
+	err := s.withLock(func() error {
+		captured, err := s.capture(entry)
+		if err != nil {
+			return err
+		}
+		entry = captured
+		return s.save(entry)
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
 
+// capture stores the config and local plugin backups of an entry. The caller
+// holds the lock.
+func (s Store) capture(entry Entry) (Entry, error) {
+	entry, err := s.captureConfig(entry)
+	if err != nil {
+		return entry, err
+	}
+	return s.captureLocal(entry)
+}
+
+// save appends an entry to the plugin history. The caller holds the lock.
+func (s Store) save(entry Entry) error {
 	path := s.entryPath(entry.PluginName)
-	entries, err := readEntries(path)
+	entries, err := s.loadEntries(path, true)
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("read snapshot: %w", err)
 	}
@@ -49,18 +96,68 @@ func (s Store) Save(entry Entry) error {
 			return entries[i].Timestamp.Before(entries[j].Timestamp)
 		})
 	}
+	entries, removed := s.Retention.Apply(entries, time.Now())
 
-	data, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal snapshot: %w", err)
-	}
-	data = append(data, '\n')
-	if err := writeFileAtomic(path, data); err != nil {
+	if err := writeEntries(path, entries); err != nil {
 		return fmt.Errorf("write snapshot: %w", err)
 	}
+	if len(removed) > 0 {
+		if _, err := s.collectGarbage(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Remove deletes the entries with the given ids from the plugin histories
+// and returns how many were removed. Entries of named snapshots are kept, as
+// in Prune.
+func (s Store) Remove(ids []string) (int, error) {
+	if s.Directory == "" {
+		return 0, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+	removed := 0
+	err := s.withLock(func() error {
+		files, err := s.pluginFiles()
+		if err != nil {
+			return err
+		}
+		for _, path := range files {
+			entries, err := s.loadEntries(path, true)
+			if err != nil {
+				return fmt.Errorf("read snapshot %s: %w", filepath.Base(path), err)
+			}
+			kept := []Entry{}
+			for _, entry := range entries {
+				if _, ok := wanted[entry.ID]; ok && entry.Set == "" {
+					continue
+				}
+				kept = append(kept, entry)
+			}
+			if len(kept) == len(entries) {
+				continue
+			}
+			if err := writeEntries(path, kept); err != nil {
+				return fmt.Errorf("write snapshot: %w", err)
+			}
+			removed += len(entries) - len(kept)
+		}
+		if removed == 0 {
+			return nil
+		}
+		_, err = s.collectGarbage()
+		return err
+	})
+	return removed, err
+}
+
 func (s Store) Latest(pluginName string) (Entry, error) {
 	if s.Directory == "" {
 		return Entry{}, fmt.Errorf("%w: directory is empty", ErrInvalidSnapshotDir)
@@ -70,7 +167,7 @@ func (s Store) Latest(pluginName string) (Entry, error) {
 	}
 
 	path := s.entryPath(pluginName)
-	entries, err := readEntries(path)
+	entries, err := s.loadEntries(path, false)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return Entry{}, ErrSnapshotNotFound
@@ -95,6 +192,49 @@ func (s Store) entryPath(pluginName string) string {
 	return filepath.Join(s.Directory, name+".json")
 }
 
+// loadEntries reads a plugin history. A corrupt file is moved aside and
+// replaced by the entries that could still be parsed. Pass locked when the
+// caller already holds the store lock.
+func (s Store) loadEntries(path string, locked bool) ([]Entry, error) {
+	entries, err := readEntries(path)
+	if !errors.Is(err, ErrCorruptSnapshot) {
+		return entries, err
+	}
+	if locked {
+		entries, _, err = s.recoverEntries(path)
+		return entries, err
+	}
+	err = s.withLock(func() error {
+		var recoverErr error
+		entries, _, recoverErr = s.recoverEntries(path)
+		return recoverErr
+	})
+	return entries, err
+}
+
+// recoverEntries moves a corrupt history file aside and rewrites it with the
+// salvaged entries. It returns the salvaged entries and where the damaged file
+// went, or an empty path when the file was not corrupt. The caller holds the
+// lock.
+func (s Store) recoverEntries(path string) ([]Entry, string, error) {
+	entries, err := readEntries(path)
+	if !errors.Is(err, ErrCorruptSnapshot) {
+		return entries, "", err
+	}
+	aside := path + corruptSuffix + time.Now().UTC().Format("20060102T150405.000000000Z")
+	if err := os.Rename(path, aside); err != nil {
+		return nil, "", fmt.Errorf("move corrupt snapshot aside: %w", err)
+	}
+	if len(entries) > 0 {
+		if err := writeEntries(path, entries); err != nil {
+			return nil, aside, fmt.Errorf("write snapshot: %w", err)
+		}
+	}
+	return entries, aside, nil
+}
+
+// readEntries parses a plugin history. When the file is corrupt it returns
+// the entries that could be parsed along with ErrCorruptSnapshot.
 func readEntries(path string) ([]Entry, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -102,35 +242,47 @@ func readEntries(path string) ([]Entry, error) {
 	}
 	var entries []Entry
 	if err := json.Unmarshal(data, &entries); err != nil {
-		return nil, err
+		return salvageEntries(data), fmt.Errorf("%w: %s: %v", ErrCorruptSnapshot, filepath.Base(path), err)
 	}
 	return entries, nil
 }
 
-func writeFileAtomic(path string, data []byte) error {
-	dir := filepath.Dir(path)
-	tmp, err := os.CreateTemp(dir, ".snapshot-*")
-	if err != nil {
-		return err
-	}
-	tmpName := tmp.Name()
-	if _, err := tmp.Write(data); err != nil {
-		_ = tmp.Close()
-		_ = os.Remove(tmpName)
-		return err
+// salvageEntries decodes array elements one at a time and stops at the first
+// one that is not valid JSON. Elements that parse but are not usable entries
+// are skipped.
+func salvageEntries(data []byte) []Entry {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('[') {
+		return nil
 	}
-	if err := tmp.Sync(); err != nil {
-		_ = tmp.Close()
-		_ = os.Remove(tmpName)
-		return err
-	}
-	if err := tmp.Close(); err != nil {
-		_ = os.Remove(tmpName)
-		return err
+	entries := []Entry{}
+	for decoder.More() {
+		offset := decoder.InputOffset()
+		var entry Entry
+		if err := decoder.Decode(&entry); err != nil {
+			if decoder.InputOffset() == offset {
+				break
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				break
+			}
+			continue
+		}
+		if entry.PluginName == "" || entry.Timestamp.IsZero() {
+			continue
+		}
+		entries = append(entries, entry)
 	}
-	if err := os.Rename(tmpName, path); err != nil {
-		_ = os.Remove(tmpName)
+	return entries
+}
+
+func writeEntries(path string, entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
 		return err
 	}
-	return nil
+	data = append(data, '\n')
+	return atomicfile.Write(path, data, 0o600)
 }
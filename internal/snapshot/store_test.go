@@ -2,6 +2,11 @@ package snapshot
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -21,10 +26,10 @@ func TestStoreSaveAndLatest(t *testing.T) {
 		Timestamp:    time.Now(),
 	}
 
-	if err := store.Save(early); err != nil {
+	if _, err := store.Save(early); err != nil {
 		t.Fatalf("save early: %v", err)
 	}
-	if err := store.Save(late); err != nil {
+	if _, err := store.Save(late); err != nil {
 		t.Fatalf("save late: %v", err)
 	}
 
@@ -44,3 +49,269 @@ func TestStoreLatestMissing(t *testing.T) {
 		t.Fatalf("expected ErrSnapshotNotFound, got %v", err)
 	}
 }
+
+func TestStoreConcurrentSavesKeepEveryEntry(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.Save(Entry{
+				PluginName:   "alpha",
+				PreviousSpec: fmt.Sprintf("alpha@1.0.%d", i),
+				Timestamp:    base.Add(time.Duration(i) * time.Minute),
+			})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 20 {
+		t.Fatalf("expected 20 entries, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(store.Directory, lockFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected lock to be released, got %v", err)
+	}
+}
+
+func TestStoreConcurrentSaveAndPruneKeepBlobs(t *testing.T) {
+	root := t.TempDir()
+	store := Store{Directory: filepath.Join(root, "snapshots")}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		configPath := filepath.Join(root, fmt.Sprintf("opencode-%d.json", i))
+		if err := os.WriteFile(configPath, []byte(fmt.Sprintf(`{"plugin": ["alpha@1.0.%d"]}`, i)), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.Save(Entry{
+				PluginName:   "alpha",
+				PreviousSpec: fmt.Sprintf("alpha@1.0.%d", i),
+				ConfigPath:   configPath,
+				Timestamp:    base.Add(time.Duration(i) * time.Minute),
+			})
+			errs <- err
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := store.Prune(Retention{KeepLast: 1}, base, false)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("save or prune: %v", err)
+		}
+	}
+
+	result, err := store.Fsck(false)
+	if err != nil {
+		t.Fatalf("fsck: %v", err)
+	}
+	if result.Unrepaired() != 0 {
+		t.Fatalf("expected every entry to keep its blob, got %+v", result.Problems)
+	}
+}
+
+func TestStoreTakesOverStaleLock(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	lockPath := filepath.Join(store.Directory, lockFileName)
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o600); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func TestStoreConcurrentStaleLockTakeover(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	lockPath := filepath.Join(store.Directory, lockFileName)
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o600); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	var holders atomic.Int32
+	var overlaps atomic.Int32
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- store.withLock(func() error {
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+				return nil
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+	}
+	if overlaps.Load() != 0 {
+		t.Fatalf("expected one holder at a time, got %d overlaps", overlaps.Load())
+	}
+	for _, name := range []string{lockFileName, breakLockFileName} {
+		if _, err := os.Stat(filepath.Join(store.Directory, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be released, got %v", name, err)
+		}
+	}
+}
+
+func TestStoreLateBreakerKeepsFreshLock(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	lockPath := filepath.Join(store.Directory, lockFileName)
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0o600); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	unlock, err := store.lock()
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// A second process that saw the stale lock before it was taken over
+	// breaks it only now.
+	if breakStaleLock(store.Directory, lockPath) {
+		t.Fatalf("expected the fresh lock to be kept")
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("expected the lock to be held: %v", err)
+	}
+
+	// A holder whose lock was broken must not release the next holder's.
+	if err := os.WriteFile(lockPath, []byte("other\n"), 0o600); err != nil {
+		t.Fatalf("write lock: %v", err)
+	}
+	unlock()
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("expected the other holder's lock to stay: %v", err)
+	}
+}
+
+func TestStoreRecoversCorruptHistory(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	path := filepath.Join(store.Directory, "alpha.json")
+	truncated := `[
+  {"timestamp":"2026-03-01T12:00:00Z","pluginName":"alpha","previousSpec":"alpha@1.0.0"},
+  {"timestamp":"2026-03-02T12:00:00Z","pluginName":"alpha","previousSpec":"alpha@1.1.0"},
+  {"timestamp":"2026-03-03T12:00:00Z","pluginName":"al`
+	if err := os.WriteFile(path, []byte(truncated), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	latest, err := store.Latest("alpha")
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	if latest.PreviousSpec != "alpha@1.1.0" {
+		t.Fatalf("expected salvaged latest entry, got %s", latest.PreviousSpec)
+	}
+
+	matches, _ := filepath.Glob(path + corruptSuffix + "*")
+	if len(matches) != 1 {
+		t.Fatalf("expected damaged file moved aside, got %v", matches)
+	}
+	data, _ := os.ReadFile(matches[0])
+	if string(data) != truncated {
+		t.Fatalf("expected original content preserved, got %s", string(data))
+	}
+
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@2.0.0"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+}
+
+func TestStoreSaveRecoversUnreadableHistory(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	path := filepath.Join(store.Directory, "alpha.json")
+	if err := os.WriteFile(path, []byte("\x00\x00garbage"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	entries, err := store.History("alpha")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(entries) != 1 || entries[0].PreviousSpec != "alpha@1.0.0" {
+		t.Fatalf("expected only the new entry, got %#v", entries)
+	}
+}
+
+func TestStoreRemoveKeepsNamedSnapshotEntries(t *testing.T) {
+	store := Store{Directory: t.TempDir()}
+	if _, err := store.Save(Entry{PluginName: "alpha", PreviousSpec: "alpha@1.0.0", Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := store.SaveSet(Set{Name: "before", Entries: []Entry{{PluginName: "alpha", PreviousSpec: "alpha@1.1.0"}}}); err != nil {
+		t.Fatalf("save set: %v", err)
+	}
+	entries, err := store.History("alpha")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected two entries, got %d %v", len(entries), err)
+	}
+
+	removed, err := store.Remove([]string{entries[0].ID, entries[1].ID})
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected one entry removed, got %d", removed)
+	}
+	entries, err = store.History("alpha")
+	if err != nil || len(entries) != 1 || entries[0].Set != "before" {
+		t.Fatalf("expected only the named snapshot entry to remain, got %+v %v", entries, err)
+	}
+}